@@ -0,0 +1,138 @@
+package gocraft
+
+import (
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const (
+	axisX = iota
+	axisY
+	axisZ
+)
+
+// collisionEpsilon keeps boxes from ending up exactly on a block face,
+// which would make the next overlap test ambiguous.
+const collisionEpsilon = 1e-4
+
+// AABB is an axis aligned bounding box in world space.
+type AABB struct {
+	Min rl.Vector3
+	Max rl.Vector3
+}
+
+// NewAABB creates a box of the given width and height standing on feet.
+func NewAABB(feet rl.Vector3, width, height float32) AABB {
+	half := width / 2
+	return AABB{
+		Min: rl.NewVector3(feet.X-half, feet.Y, feet.Z-half),
+		Max: rl.NewVector3(feet.X+half, feet.Y+height, feet.Z+half),
+	}
+}
+
+// blockAABB returns the box of the voxel at x, y, z. Blocks are centered
+// on x and z and stand on y, matching res/models/brick.obj.
+func blockAABB(x, y, z int) AABB {
+	return AABB{
+		Min: rl.NewVector3(float32(x)-0.5, float32(y), float32(z)-0.5),
+		Max: rl.NewVector3(float32(x)+0.5, float32(y)+1, float32(z)+0.5),
+	}
+}
+
+// blockCoord returns the voxel containing the world position.
+func blockCoord(pos rl.Vector3) (int, int, int) {
+	return int(math.Floor(float64(pos.X) + 0.5)),
+		int(math.Floor(float64(pos.Y))),
+		int(math.Floor(float64(pos.Z) + 0.5))
+}
+
+func (a AABB) Offset(d rl.Vector3) AABB {
+	return AABB{
+		Min: rl.Vector3Add(a.Min, d),
+		Max: rl.Vector3Add(a.Max, d),
+	}
+}
+
+// Expand grows the box in the direction of d, covering a sweep along d.
+func (a AABB) Expand(d rl.Vector3) AABB {
+	out := a
+	for axis := axisX; axis <= axisZ; axis++ {
+		v := vecAxis(d, axis)
+		if v < 0 {
+			setVecAxis(&out.Min, axis, vecAxis(out.Min, axis)+v)
+		} else {
+			setVecAxis(&out.Max, axis, vecAxis(out.Max, axis)+v)
+		}
+	}
+	return out
+}
+
+func (a AABB) Intersects(b AABB) bool {
+	return a.Min.X < b.Max.X && a.Max.X > b.Min.X &&
+		a.Min.Y < b.Max.Y && a.Max.Y > b.Min.Y &&
+		a.Min.Z < b.Max.Z && a.Max.Z > b.Min.Z
+}
+
+func (a AABB) Center() rl.Vector3 {
+	return rl.Vector3Scale(rl.Vector3Add(a.Min, a.Max), 0.5)
+}
+
+// overlapsOn reports whether both boxes overlap on every axis but the given one.
+func (a AABB) overlapsOn(b AABB, axis int) bool {
+	for o := axisX; o <= axisZ; o++ {
+		if o == axis {
+			continue
+		}
+		if vecAxis(a.Min, o) >= vecAxis(b.Max, o) || vecAxis(a.Max, o) <= vecAxis(b.Min, o) {
+			return false
+		}
+	}
+	return true
+}
+
+// clipAxis shortens the movement d of a along axis so it does not enter b.
+func (a AABB) clipAxis(b AABB, axis int, d float32) float32 {
+	if !a.overlapsOn(b, axis) {
+		return d
+	}
+	if d > 0 && vecAxis(a.Max, axis) <= vecAxis(b.Min, axis)+collisionEpsilon {
+		if max := vecAxis(b.Min, axis) - vecAxis(a.Max, axis) - collisionEpsilon; max < d {
+			d = float32(math.Max(0, float64(max)))
+		}
+	}
+	if d < 0 && vecAxis(a.Min, axis) >= vecAxis(b.Max, axis)-collisionEpsilon {
+		if min := vecAxis(b.Max, axis) - vecAxis(a.Min, axis) + collisionEpsilon; min > d {
+			d = float32(math.Min(0, float64(min)))
+		}
+	}
+	return d
+}
+
+// blockRange returns the inclusive voxel ranges touched by the box.
+func (a AABB) blockRange() (minX, minY, minZ, maxX, maxY, maxZ int) {
+	minX, minY, minZ = blockCoord(a.Min)
+	maxX, maxY, maxZ = blockCoord(a.Max)
+	return
+}
+
+func vecAxis(v rl.Vector3, axis int) float32 {
+	switch axis {
+	case axisX:
+		return v.X
+	case axisY:
+		return v.Y
+	}
+	return v.Z
+}
+
+func setVecAxis(v *rl.Vector3, axis int, value float32) {
+	switch axis {
+	case axisX:
+		v.X = value
+	case axisY:
+		v.Y = value
+	default:
+		v.Z = value
+	}
+}
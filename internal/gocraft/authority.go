@@ -0,0 +1,197 @@
+package gocraft
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+var ErrInvalidAction = errors.New("invalid action")
+
+const (
+	// maxInputBacklog is how many inputs a client can save up while its
+	// packets are late. Clients get one input per tick, so sending more
+	// than that does not make a player faster.
+	maxInputBacklog = 10
+	// maxQueuedInputs bounds the inputs waiting for budget, older ones are
+	// dropped and the client is corrected by the next snapshot.
+	maxQueuedInputs = 2 * maxInputBacklog
+	// blockReachSlack is added to playerReach when validating block
+	// actions. The server sees the player a bit behind its own prediction
+	// and the reach is measured to the block center.
+	blockReachSlack float32 = 1.5
+)
+
+// queueInput adds an input in sequence order. Inputs older than the
+// last applied one arrived out of order and are dropped.
+func (c *serverClient) queueInput(p *PlayerInputPacket) {
+	if p.Sequence <= c.lastSequence || !validInput(p) {
+		return
+	}
+	i := sort.Search(len(c.inputs), func(i int) bool {
+		return c.inputs[i].Sequence >= p.Sequence
+	})
+	if i < len(c.inputs) && c.inputs[i].Sequence == p.Sequence {
+		return
+	}
+	c.inputs = append(c.inputs, nil)
+	copy(c.inputs[i+1:], c.inputs[i:])
+	c.inputs[i] = p
+
+	if len(c.inputs) > maxQueuedInputs {
+		c.inputs = c.inputs[len(c.inputs)-maxQueuedInputs:]
+	}
+}
+
+func validInput(p *PlayerInputPacket) bool {
+	for _, v := range []float32{p.Input.Forward, p.Input.Strafe, p.Input.Yaw, p.Pitch} {
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// simulate moves the player of c by the inputs it is allowed to use this
+// tick and sends the result back as snapshot.
+func (s *Server) simulate(c *serverClient, dt float32) {
+	if c.inputBudget < maxInputBacklog {
+		c.inputBudget++
+	}
+
+	for c.inputBudget > 0 && len(c.inputs) > 0 {
+		// wait for the ground instead of letting the player fall through
+		if !s.world.Chunks.Loaded(c.player.Position) {
+			break
+		}
+		in := c.inputs[0]
+		c.inputs = c.inputs[1:]
+		c.inputBudget--
+
+		in.Input.Forward = clamp32(in.Input.Forward, -1, 1)
+		in.Input.Strafe = clamp32(in.Input.Strafe, -1, 1)
+		c.player.SetMode(s.allowedMode(in.Mode))
+		s.world.StepPlayer(c.player, in.Input, dt)
+
+		c.lastSequence = in.Sequence
+		c.yaw, c.pitch = in.Input.Yaw, clamp32(in.Pitch, -89, 89)
+	}
+
+	c.send(&PlayerSnapshotPacket{
+		Sequence: c.lastSequence,
+		Position: c.player.Position,
+		Velocity: c.player.Velocity,
+		Mode:     c.player.Mode,
+		OnGround: c.player.OnGround,
+	})
+}
+
+// allowedMode returns the mode a client may use when it asks for mode.
+// Noclip would let players walk through walls and is never allowed.
+func (s *Server) allowedMode(mode PlayerMode) PlayerMode {
+	if mode == PlayerModeFly && s.config.AllowFlight {
+		return PlayerModeFly
+	}
+	return PlayerModeWalk
+}
+
+// applyBlockAction changes the world if the action is valid and tells
+// everyone. Invalid actions are answered with the real state of the block
+// so the client can undo its prediction.
+func (s *Server) applyBlockAction(c *serverClient, p *BlockActionPacket) {
+	x, y, z := int(p.X), int(p.Y), int(p.Z)
+	if !s.world.Chunks.Loaded(rl.NewVector3(float32(x), float32(y), float32(z))) {
+		return
+	}
+	var err error
+	switch {
+	case !s.validBlockAction(c, p):
+		err = ErrInvalidAction
+	case p.Action == BlockActionBreak:
+		err = s.world.BreakBlock(c.player, x, y, z)
+	case p.Action == BlockActionPlace:
+		err = s.world.PlaceBlock(c.player, x, y, z, p.Type)
+	}
+	if err != nil {
+		c.send(s.blockState(x, y, z))
+		// the client already took the block out of its inventory
+		c.player.Inventory.changed = true
+		return
+	}
+	s.broadcast(s.blockState(x, y, z))
+}
+
+func (s *Server) validBlockAction(c *serverClient, p *BlockActionPacket) bool {
+	x, y, z := int(p.X), int(p.Y), int(p.Z)
+	if y < 0 || y >= int(s.world.Chunks.height) {
+		return false
+	}
+
+	center := rl.NewVector3(float32(x), float32(y)+0.5, float32(z))
+	if rl.Vector3Distance(c.player.EyePosition(), center) > playerReach+blockReachSlack {
+		return false
+	}
+
+	solid := s.world.Chunks.IsSolid(x, y, z)
+	switch p.Action {
+	case BlockActionBreak:
+		return solid
+	case BlockActionPlace:
+		if solid || c.player.Inventory.Count(p.Type) == 0 {
+			return false
+		}
+		box := blockAABB(x, y, z)
+		for _, other := range s.clients {
+			if other.player.Mode != PlayerModeNoclip && other.player.Box().Intersects(box) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// applyCraft crafts once with the grid the client sent. The blocks in
+// the grid are taken from the inventory, the client keeps them in its
+// crafting screen until then. On any mismatch the client just gets the
+// real inventory back.
+func (s *Server) applyCraft(c *serverClient, p *CraftPacket) {
+	inv := &c.player.Inventory
+	// the reply replaces whatever the client predicted
+	inv.changed = true
+
+	need := make(map[BlockType]int)
+	for _, slot := range p.Grid.Slots {
+		if !slot.Empty() {
+			need[slot.Type]++
+		}
+	}
+	for typ, n := range need {
+		if inv.Count(typ) < n {
+			return
+		}
+	}
+	result, ok := s.recipes.Craft(&p.Grid)
+	if !ok {
+		return
+	}
+	for typ, n := range need {
+		inv.Take(typ, n)
+	}
+	if left := inv.Add(result.Type, result.Count); left > 0 {
+		x, y, z := blockCoord(c.player.Position)
+		s.world.DropItem(result.Type, left, x, y, z)
+	}
+}
+
+// blockState describes the current block at x, y, z as a change packet.
+func (s *Server) blockState(x, y, z int) *BlockChangePacket {
+	change := &BlockChangePacket{X: int32(x), Y: int32(y), Z: int32(z), Removed: true}
+	if b := s.world.Chunks.BlockAt(x, y, z); b != nil && !b.carved {
+		change.Type = b.blockType
+		change.Removed = false
+	}
+	return change
+}
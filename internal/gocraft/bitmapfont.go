@@ -0,0 +1,93 @@
+package gocraft
+
+import "unicode"
+
+// The SoftwareRenderer has no font files, it draws text with this tiny
+// pixel font. Each glyph is 3x5 pixels, row by row, # is set.
+const (
+	glyphWidth  = 3
+	glyphHeight = 5
+)
+
+var glyphs = map[rune]string{
+	'0':  "####.##.##.####",
+	'1':  ".#.##..#..#.###",
+	'2':  "###..#####..###",
+	'3':  "###..#.##..####",
+	'4':  "#.##.####..#..#",
+	'5':  "####..###..####",
+	'6':  "####..####.####",
+	'7':  "###..#.#..#..#.",
+	'8':  "####.#####.####",
+	'9':  "####.####..####",
+	'A':  ".#.#.#####.##.#",
+	'B':  "##.#.###.#.###.",
+	'C':  ".###..#..#...##",
+	'D':  "##.#.##.##.###.",
+	'E':  "####..##.#..###",
+	'F':  "####..##.#..#..",
+	'G':  ".###..#.##.#.##",
+	'H':  "#.##.####.##.##",
+	'I':  "###.#..#..#.###",
+	'J':  "..#..#..##.#.#.",
+	'K':  "#.##.###.#.##.#",
+	'L':  "#..#..#..#..###",
+	'M':  "#.#######.##.##",
+	'N':  "##.#.##.##.##.#",
+	'O':  ".#.#.##.##.#.#.",
+	'P':  "##.#.###.#..#..",
+	'Q':  ".#.#.##.###..##",
+	'R':  "##.#.###.#.##.#",
+	'S':  ".###...#...###.",
+	'T':  "###.#..#..#..#.",
+	'U':  "#.##.##.##.####",
+	'V':  "#.##.##.##.#.#.",
+	'W':  "#.##.#######.##",
+	'X':  "#.##.#.#.#.##.#",
+	'Y':  "#.##.#.#..#..#.",
+	'Z':  "###..#.#.#..###",
+	' ':  "...............",
+	'.':  ".............#.",
+	',':  "..........#.#..",
+	':':  "....#.....#....",
+	';':  "....#.....#.#..",
+	'-':  "......###......",
+	'+':  "....#.###.#....",
+	'/':  "..#..#.#.#..#..",
+	'(':  ".#.#..#..#...#.",
+	')':  ".#...#..#..#.#.",
+	'[':  "##.#..#..#..##.",
+	']':  ".##..#..#..#.##",
+	'%':  "#.#..#.#.#..#.#",
+	'!':  ".#..#..#.....#.",
+	'?':  "##...#.#.....#.",
+	'>':  "#...#...#.#.#..",
+	'<':  "..#.#.#...#...#",
+	'=':  "...###...###...",
+	'_':  "............###",
+	'\'': ".#..#..........",
+	'"':  "#.##.#.........",
+	'*':  "#.#.#.#.#.#.#.#",
+	'#':  "#.####.####.#.#",
+}
+
+// unknownGlyph is drawn for characters the font does not have.
+const unknownGlyph = "###############"
+
+// glyphFor returns the glyph of ch, lower case letters are drawn upper
+// case.
+func glyphFor(ch rune) string {
+	if g, ok := glyphs[unicode.ToUpper(ch)]; ok {
+		return g
+	}
+	return unknownGlyph
+}
+
+// glyphScale is the size of a font pixel, so the glyphs are about as high
+// as the raylib font of the size.
+func glyphScale(size int32) int32 {
+	if size < 12 {
+		return 1
+	}
+	return size / 7
+}
@@ -1,13 +1,17 @@
 package gocraft
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
 var (
 	ErrBlockManagerNoSpace = errors.New("not enough space to add block")
+	ErrChunkNotLoaded      = errors.New("chunk is not loaded")
+	ErrInvalidBlock        = errors.New("invalid block definition")
 )
 
 type BlockType int
@@ -20,13 +24,116 @@ const (
 	BlockTypeGroud BlockType = 4
 )
 
-var blockMaterials = map[BlockType]rl.Material{}
+// blockNames are the ids of the block types in data files.
+var blockNames = map[BlockType]string{
+	BlockTypeDirt:  "dirt",
+	BlockTypeGras:  "gras",
+	BlockTypeSnow:  "snow",
+	BlockTypeRock:  "rock",
+	BlockTypeGroud: "ground",
+}
+
+func (t BlockType) String() string {
+	if name, ok := blockNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("block(%d)", int(t))
+}
+
+// ParseBlockType returns the block type with the given id.
+func ParseBlockType(name string) (BlockType, bool) {
+	for t, n := range blockNames {
+		if n == name {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// BlockDefinition is how a block type looks, it is read from the
+// resources, see Resources.LoadBlockDefinitions.
+type BlockDefinition struct {
+	Type BlockType
+	// Texture is the path of the texture in the resources.
+	Texture string
+	// Color is used where the block is drawn without its texture.
+	Color rl.Color
+}
+
+type blockDefinitionFile struct {
+	Blocks []struct {
+		ID      string `json:"id"`
+		Texture string `json:"texture"`
+		Color   string `json:"color"`
+	} `json:"blocks"`
+}
+
+func parseBlockDefinitions(data []byte) ([]BlockDefinition, error) {
+	var file blockDefinitionFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	var (
+		defs    []BlockDefinition
+		defined = make(map[BlockType]bool)
+	)
+	for i, b := range file.Blocks {
+		t, ok := ParseBlockType(b.ID)
+		if !ok {
+			return nil, fmt.Errorf("%w: #%d: unknown block %q", ErrInvalidBlock, i, b.ID)
+		}
+		if defined[t] {
+			return nil, fmt.Errorf("%w: %s is defined twice", ErrInvalidBlock, b.ID)
+		}
+		if b.Texture == "" {
+			return nil, fmt.Errorf("%w: %s has no texture", ErrInvalidBlock, b.ID)
+		}
+		color, err := parseColor(b.Color)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %s", ErrInvalidBlock, b.ID, err.Error())
+		}
+		defined[t] = true
+		defs = append(defs, BlockDefinition{Type: t, Texture: b.Texture, Color: color})
+	}
+	for t, name := range blockNames {
+		if !defined[t] {
+			return nil, fmt.Errorf("%w: %s is missing", ErrInvalidBlock, name)
+		}
+	}
+	return defs, nil
+}
+
+// blockMaterials are the textured materials of the blocks, see
+// loadBlocks.
+var blockMaterials = map[BlockType]Material{}
+
+// loadBlocks loads the textures of the block definitions and sets up
+// the block materials and colors. A texture that fails to load gets a
+// placeholder, the error is only logged.
+func loadBlocks(r Renderer, resources *Resources, defs []BlockDefinition) {
+	for _, def := range defs {
+		img, err := resources.LoadTextureImage(def.Texture)
+		if err != nil {
+			rl.TraceLog(rl.LogWarning, "block %s: %s", def.Type, err.Error())
+		}
+		blockMaterials[def.Type] = Material{Texture: r.LoadTexture(img), Color: rl.White}
+		farMaterials[def.Type] = Material{Color: def.Color}
+		blockColors[def.Type] = def.Color
+	}
+}
 
-func addBlockMaterial(t BlockType, fn string, shader rl.Shader) {
-	bm := rl.LoadMaterialDefault()
-	bm.Shader = shader
-	bm.Maps.Texture = rl.LoadTexture(fn)
-	blockMaterials[t] = bm
+// loadBlockColors sets up the block colors without loading textures,
+// for the commands that draw maps without a renderer.
+func loadBlockColors(resources *Resources) error {
+	defs, err := resources.LoadBlockDefinitions()
+	if err != nil {
+		return err
+	}
+	for _, def := range defs {
+		blockColors[def.Type] = def.Color
+	}
+	return nil
 }
 
 type Block struct {
@@ -0,0 +1,174 @@
+package gocraft
+
+import (
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+type CameraMode int
+
+const (
+	CameraModeFirstPerson CameraMode = iota
+	CameraModeThirdPerson
+	CameraModeSpectator
+	cameraModeCount
+)
+
+func (m CameraMode) String() string {
+	switch m {
+	case CameraModeThirdPerson:
+		return "third person"
+	case CameraModeSpectator:
+		return "spectator"
+	}
+	return "first person"
+}
+
+const (
+	cameraMaxPitch         float32 = 89
+	cameraDefaultDistance  float32 = 4
+	cameraSpectatorSpeed   float32 = 13
+	cameraCollisionStep    float32 = 0.1
+	cameraCollisionPadding float32 = 0.2
+)
+
+// Camera turns mouse movement and a followed eye position into a raylib
+// camera. In spectator mode it flies on its own and ignores the eye.
+type Camera struct {
+	Mode        CameraMode
+	FOV         float32
+	Sensitivity float32
+	// Smoothing is the time in seconds the view needs to catch up with
+	// the mouse, 0 disables it.
+	Smoothing float32
+	// Distance of the orbit camera in third person mode.
+	Distance float32
+	// FlySpeed is the spectator speed in blocks per second.
+	FlySpeed float32
+
+	yaw         float32
+	pitch       float32
+	smoothYaw   float32
+	smoothPitch float32
+	position    rl.Vector3
+}
+
+func NewCamera(fov, sensitivity float32) *Camera {
+	return &Camera{
+		FOV:         fov,
+		Sensitivity: sensitivity,
+		Distance:    cameraDefaultDistance,
+		FlySpeed:    cameraSpectatorSpeed,
+	}
+}
+
+// Rotate applies a mouse delta in pixels.
+func (c *Camera) Rotate(dx, dy float32) {
+	c.Turn(dx*c.Sensitivity, -dy*c.Sensitivity)
+}
+
+// Turn changes yaw and pitch by the given degrees.
+func (c *Camera) Turn(yaw, pitch float32) {
+	c.yaw = float32(math.Mod(float64(c.yaw+yaw), 360))
+	c.pitch = clamp32(c.pitch+pitch, -cameraMaxPitch, cameraMaxPitch)
+}
+
+// NextMode cycles through the camera modes. Entering spectator mode
+// starts flying from the current view position.
+func (c *Camera) NextMode() {
+	c.Mode = (c.Mode + 1) % cameraModeCount
+}
+
+// Yaw is the heading in degrees used for movement.
+func (c *Camera) Yaw() float32 {
+	return c.yaw
+}
+
+func (c *Camera) Pitch() float32 {
+	return c.pitch
+}
+
+func (c *Camera) Position() rl.Vector3 {
+	return c.position
+}
+
+// Front is the smoothed view direction.
+func (c *Camera) Front() rl.Vector3 {
+	return rl.Vector3Normalize(rl.NewVector3(
+		cos32(radians(c.smoothYaw))*cos32(radians(c.smoothPitch)),
+		sin32(radians(c.smoothPitch)),
+		sin32(radians(c.smoothYaw))*cos32(radians(c.smoothPitch)),
+	))
+}
+
+func (c *Camera) Right() rl.Vector3 {
+	return rl.Vector3Normalize(rl.Vector3CrossProduct(c.Front(), rl.NewVector3(0, 1, 0)))
+}
+
+// Update moves the camera for this frame. eye is the position the first
+// and third person modes follow, world is used to keep the orbit camera
+// out of terrain and may be nil.
+func (c *Camera) Update(eye rl.Vector3, world VoxelWorld, dt float32) {
+	if c.Smoothing <= 0 {
+		c.smoothYaw, c.smoothPitch = c.yaw, c.pitch
+	} else {
+		blend := 1 - float32(math.Exp(float64(-dt/c.Smoothing)))
+		c.smoothYaw += shortestAngle(c.smoothYaw, c.yaw) * blend
+		c.smoothPitch += (c.pitch - c.smoothPitch) * blend
+	}
+
+	switch c.Mode {
+	case CameraModeFirstPerson:
+		c.position = eye
+	case CameraModeThirdPerson:
+		c.position = c.orbit(eye, world)
+	}
+}
+
+// Fly moves the spectator camera. forward, strafe and up are in the range -1..1.
+func (c *Camera) Fly(forward, strafe, up, dt float32) {
+	move := rl.Vector3Add(
+		rl.Vector3Scale(c.Front(), forward),
+		rl.Vector3Scale(c.Right(), strafe),
+	)
+	move.Y += up
+	if rl.Vector3Length(move) > 1 {
+		move = rl.Vector3Normalize(move)
+	}
+	c.position = rl.Vector3Add(c.position, rl.Vector3Scale(move, c.FlySpeed*dt))
+}
+
+// orbit places the camera behind eye and pulls it closer when terrain is in the way.
+func (c *Camera) orbit(eye rl.Vector3, world VoxelWorld) rl.Vector3 {
+	back := rl.Vector3Negate(c.Front())
+	dist := c.Distance
+	if world != nil {
+		for d := cameraCollisionStep; d <= c.Distance; d += cameraCollisionStep {
+			x, y, z := blockCoord(rl.Vector3Add(eye, rl.Vector3Scale(back, d)))
+			if world.IsSolid(x, y, z) {
+				dist = float32(math.Max(0, float64(d-cameraCollisionPadding)))
+				break
+			}
+		}
+	}
+	return rl.Vector3Add(eye, rl.Vector3Scale(back, dist))
+}
+
+// Camera3D returns the raylib camera for rendering.
+func (c *Camera) Camera3D() rl.Camera3D {
+	target := rl.Vector3Add(c.position, c.Front())
+	return rl.NewCamera3D(c.position, target, rl.NewVector3(0, 1, 0), c.FOV, rl.CameraPerspective)
+}
+
+// shortestAngle returns the signed difference to turn from a to b in degrees.
+func shortestAngle(from, to float32) float32 {
+	d := float32(math.Mod(float64(to-from), 360))
+	switch {
+	case d > 180:
+		d -= 360
+	case d < -180:
+		d += 360
+	}
+	return d
+}
@@ -6,9 +6,20 @@ import (
 
 type Chunk struct {
 	blockList  [][][]*Block
-	instances  map[BlockType][]rl.Matrix
+	sections   []*chunkSection
 	center     rl.Vector2
 	debugColor rl.Color
+
+	// edits are the changes made after generation, they are what gets saved
+	edits map[[3]int]blockEdit
+	dirty bool
+	// moved is set when an entity of the chunk moved since it was saved
+	moved bool
+	// revision counts the changes, so views of the chunk know when to
+	// update
+	revision int
+	// entities standing in the chunk, they are saved with it
+	entities []*Entity
 }
 
 func NewChunk(width, height, lenght int) *Chunk {
@@ -16,11 +27,14 @@ func NewChunk(width, height, lenght int) *Chunk {
 	bm.blockList = make([][][]*Block, width)
 	for i := 0; i < width; i++ {
 		bm.blockList[i] = make([][]*Block, height)
-		for j := 0; j < lenght; j++ {
+		for j := 0; j < height; j++ {
 			bm.blockList[i][j] = make([]*Block, lenght)
 		}
 	}
-	bm.instances = make(map[BlockType][]rl.Matrix)
+	for i := 0; i*chunkSectionHeight < height; i++ {
+		bm.sections = append(bm.sections, &chunkSection{chunk: bm, index: i})
+	}
+	bm.edits = make(map[[3]int]blockEdit)
 	return bm
 }
 
@@ -98,17 +112,26 @@ func (bm *Chunk) checkBlockPosition(x, y, z int) error {
 	return nil
 }
 
-func (c *Chunk) RenderChunk(blockModel rl.Model) {
-	for typ, transforms := range c.instances {
-		rl.DrawMeshInstanced(
-			*blockModel.Meshes,
-			blockMaterials[typ],
-			transforms,
-			len(transforms),
-		)
+// RenderChunk draws all sections of the chunk, see cullSections for
+// drawing only the visible ones.
+func (c *Chunk) RenderChunk(r Renderer, block Mesh, stats *RenderStats) {
+	stats.Chunks++
+	for _, s := range c.sections {
+		s.render(r, block, stats)
 	}
 }
 
+// snowLine is the height above which the top block is snow.
+const snowLine = 38
+
+// surfaceBlock is the type of a block at height y with nothing above it.
+func surfaceBlock(y int) BlockType {
+	if y > snowLine {
+		return BlockTypeSnow
+	}
+	return BlockTypeGras
+}
+
 func (c *Chunk) configureBlock(x, y, z int) {
 	b := c.GetBlock(x, y, z)
 	if b == nil {
@@ -126,24 +149,19 @@ func (c *Chunk) configureBlock(x, y, z int) {
 	}
 
 	if !c.HasBlock(x, y+1, z) {
-		switch {
-		case y > 38:
-			b.blockType = BlockTypeSnow
-		default:
-			b.blockType = BlockTypeGras
-		}
+		b.blockType = surfaceBlock(y)
 	}
 
 	if y == 0 {
 		b.blockType = BlockTypeGroud
 	}
 
-	if b.enabled && !b.carved {
-		c.instances[b.blockType] = append(c.instances[b.blockType], rl.MatrixTranslate(
-			b.position.X,
-			b.position.Y,
-			b.position.Z,
-		))
+}
+
+// buildInstances collects the transforms of all visible blocks.
+func (c *Chunk) buildInstances() {
+	for i := range c.sections {
+		c.buildSection(i)
 	}
 }
 
@@ -155,4 +173,77 @@ func (c *Chunk) Generate() {
 			}
 		}
 	}
+	c.buildInstances()
+}
+
+// setBlock replaces the block at the local position, nil removes it.
+// Visibility of the neighbours is updated and the instances rebuilt.
+func (c *Chunk) setBlock(x, y, z int, block *Block) error {
+	if err := c.placeBlock(x, y, z, block); err != nil {
+		return err
+	}
+
+	edit := blockEdit{Removed: block == nil}
+	if block != nil {
+		edit.Type = block.blockType
+	}
+	c.edits[[3]int{x, y, z}] = edit
+	c.dirty = true
+	c.revision++
+	// placeBlock changes the visibility one block up and down, which
+	// can be in the next section
+	built := map[*chunkSection]bool{}
+	for _, s := range []*chunkSection{c.section(y - 1), c.section(y), c.section(y + 1)} {
+		if s != nil && !built[s] {
+			c.buildSection(s.index)
+			built[s] = true
+		}
+	}
+	return nil
+}
+
+// placeBlock sets the block and updates the visibility around it without
+// rebuilding the instances.
+func (c *Chunk) placeBlock(x, y, z int, block *Block) error {
+	if err := c.checkBlockPosition(x, y, z); err != nil {
+		return err
+	}
+
+	c.blockList[x][y][z] = block
+	for _, n := range [][3]int{
+		{0, 0, 0}, {1, 0, 0}, {-1, 0, 0}, {0, 1, 0}, {0, -1, 0},
+		{0, 0, 1}, {0, 0, -1}, {1, 1, 0}, {-1, -1, 0},
+	} {
+		if b := c.GetBlock(x+n[0], y+n[1], z+n[2]); b != nil {
+			b.enabled = !c.IsSurrounded(x+n[0], y+n[1], z+n[2])
+		}
+	}
+	return nil
+}
+
+// applyEdits replays saved edits on a freshly generated chunk.
+func (c *Chunk) applyEdits(edits map[[3]int]blockEdit) {
+	for pos, edit := range edits {
+		var block *Block
+		if !edit.Removed {
+			block = &Block{
+				position:  c.worldPosition(pos[0], pos[1], pos[2]),
+				blockType: edit.Type,
+				enabled:   true,
+			}
+		}
+		if c.placeBlock(pos[0], pos[1], pos[2], block) == nil {
+			c.edits[pos] = edit
+		}
+	}
+	c.buildInstances()
+}
+
+// worldPosition converts a local block position to world coordinates.
+func (c *Chunk) worldPosition(x, y, z int) rl.Vector3 {
+	return rl.NewVector3(
+		float32(x)-c.center.X-float32(len(c.blockList)/2),
+		float32(y),
+		float32(z)-c.center.Y-float32(len(c.blockList[0][0])/2),
+	)
 }
@@ -0,0 +1,82 @@
+package gocraft
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// encodeBlocks serializes all blocks as one byte per voxel in x, y, z
+// order, 0 is air and everything else is the block type + 1. The result
+// is deflated, terrain has long runs of equal voxels.
+func (c *Chunk) encodeBlocks() ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		row = make([]byte, len(c.blockList[0][0]))
+	)
+	w, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	for x := range c.blockList {
+		for y := range c.blockList[x] {
+			for z, b := range c.blockList[x][y] {
+				row[z] = 0
+				if b != nil && !b.carved {
+					row[z] = byte(b.blockType) + 1
+				}
+			}
+			if _, err := w.Write(row); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeChunk builds a chunk from the output of encodeBlocks.
+func decodeChunk(center rl.Vector2, size, height int, data []byte) (*Chunk, error) {
+	var (
+		chunk = NewChunk(size, height, size)
+		r     = flate.NewReader(bytes.NewReader(data))
+		row   = make([]byte, size)
+	)
+	defer r.Close()
+	chunk.center = center
+
+	for x := 0; x < size; x++ {
+		for y := 0; y < height; y++ {
+			if _, err := io.ReadFull(r, row); err != nil {
+				return nil, fmt.Errorf("chunk %v: %w", center, err)
+			}
+			for z, v := range row {
+				if v == 0 {
+					continue
+				}
+				chunk.blockList[x][y][z] = &Block{
+					position:  chunk.worldPosition(x, y, z),
+					blockType: BlockType(v - 1),
+					enabled:   true,
+				}
+			}
+		}
+	}
+
+	for x := 0; x < size; x++ {
+		for y := 0; y < height; y++ {
+			for z := 0; z < size; z++ {
+				if b := chunk.blockList[x][y][z]; b != nil {
+					b.enabled = !chunk.IsSurrounded(x, y, z)
+				}
+			}
+		}
+	}
+	chunk.buildInstances()
+	return chunk, nil
+}
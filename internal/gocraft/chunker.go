@@ -1,26 +1,74 @@
 package gocraft
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"sort"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 	fastnoise "github.com/tinogoehlert/gocraft/pkg/go-fastnoiselite"
 )
 
-func normalizef(in float32) float32 {
-	out := (in - -1) / (1 - -1) * (0 - 48)
+// normalizef maps noise in -1..1 to whole numbers in 0..scale.
+func normalizef(in, scale float32) float32 {
+	out := (in - -1) / (1 - -1) * (0 - scale)
 	out = float32(math.Abs(float64(out)))
 
 	return float32(math.Round(float64(out)))
 }
 
+// TerrainConfig are the noise parameters of the generated terrain.
+type TerrainConfig struct {
+	Frequency float32 `json:"frequency"`
+	// Octaves of the ridged height noise.
+	Octaves int `json:"octaves"`
+	// Height is the highest possible terrain.
+	Height float32 `json:"height"`
+	// CaveOctaves of the ping pong noise carving the caves.
+	CaveOctaves int `json:"cave_octaves"`
+	// CaveThreshold carves blocks whose cave noise, scaled like the
+	// height, is below it.
+	CaveThreshold float32 `json:"cave_threshold"`
+}
+
+func DefaultTerrain() TerrainConfig {
+	return TerrainConfig{
+		Frequency:     0.01,
+		Octaves:       4,
+		Height:        48,
+		CaveOctaves:   6,
+		CaveThreshold: 10,
+	}
+}
+
+func (t TerrainConfig) Validate(chunkSize float32) error {
+	switch {
+	case t.Frequency <= 0 || t.Frequency > 1:
+		return errors.New("frequency must be in (0, 1]")
+	case t.Octaves < 1 || t.Octaves > 10:
+		return errors.New("octaves must be 1 to 10")
+	case t.CaveOctaves < 1 || t.CaveOctaves > 10:
+		return errors.New("cave_octaves must be 1 to 10")
+	case t.Height < 1 || t.Height > chunkSize:
+		return fmt.Errorf("height must be 1 to the chunk size %.0f", chunkSize)
+	case t.CaveThreshold < 0 || t.CaveThreshold > t.Height:
+		return errors.New("cave_threshold must be 0 to height")
+	}
+	return nil
+}
+
 type ChunkManager struct {
 	terrainNoise *fastnoise.NoiseState
-	chunkMap     map[rl.Vector2]*Chunk
-	width        float32
-	height       float32
-	length       float32
+	terrain      TerrainConfig
+	store        *WorldStore
+	seed         int
+	// remote managers never generate, chunks are inserted as they arrive
+	remote   bool
+	chunkMap map[rl.Vector2]*Chunk
+	width    float32
+	height   float32
+	length   float32
 }
 
 func NewChunkManager(size float32) *ChunkManager {
@@ -34,35 +82,271 @@ func NewChunkManager(size float32) *ChunkManager {
 
 	cm.terrainNoise.SetFractal(fastnoise.FNL_FRACTAL_RIDGED)
 	cm.terrainNoise.SetType(fastnoise.FNL_NOISE_PERLIN)
-	cm.terrainNoise.SetFrequency(0.01)
-	cm.terrainNoise.SetOctaves(4)
+	cm.SetTerrain(DefaultTerrain())
+	cm.SetSeed(fastnoise.DefaultSeed)
 	return cm
 }
 
+// terrainHeight is the number of blocks stacked at world x, z before
+// caves are carved out.
+func terrainHeight(noise *fastnoise.NoiseState, terrain TerrainConfig, x, z float32) float32 {
+	noise.SetFractal(fastnoise.FNL_FRACTAL_RIDGED)
+	noise.SetOctaves(terrain.Octaves)
+	return normalizef(noise.GetNoise2D(x, z), terrain.Height)
+}
+
+// caveNoise is the cave noise at a block scaled like the height, blocks
+// below the CaveThreshold are carved.
+func caveNoise(noise *fastnoise.NoiseState, terrain TerrainConfig, x, y, z float32) float32 {
+	noise.SetFractal(fastnoise.FNL_FRACTAL_PINGPONG)
+	noise.SetOctaves(terrain.CaveOctaves)
+	return normalizef(noise.GetNoise3D(x, y, z), terrain.Height)
+}
+
+// newTerrainNoise returns a noise state of its own for terrainHeight
+// that matches the chunks of the manager.
+func (cm *ChunkManager) newTerrainNoise() *fastnoise.NoiseState {
+	noise := fastnoise.NewDefaultNoise()
+	noise.SetType(fastnoise.FNL_NOISE_PERLIN)
+	noise.SetSeed(cm.seed)
+	noise.SetFrequency(cm.terrain.Frequency)
+	return noise
+}
+
+// SetTerrain changes the terrain of chunks generated from now on.
+func (cm *ChunkManager) SetTerrain(terrain TerrainConfig) {
+	cm.terrain = terrain
+	cm.terrainNoise.SetFrequency(terrain.Frequency)
+}
+
+func (cm *ChunkManager) Terrain() TerrainConfig {
+	return cm.terrain
+}
+
+// SetSeed changes the terrain of chunks generated from now on.
+func (cm *ChunkManager) SetSeed(seed int) {
+	cm.seed = seed
+	cm.terrainNoise.SetSeed(seed)
+}
+
+func (cm *ChunkManager) Seed() int {
+	return cm.seed
+}
+
+func (cm *ChunkManager) Size() float32 {
+	return cm.width
+}
+
+// SetStore makes the manager load edits of newly generated chunks from
+// the store and save them when chunks are unloaded.
+func (cm *ChunkManager) SetStore(store *WorldStore) {
+	cm.store = store
+}
+
+// LoadAround generates all chunks within radius chunks around the world
+// position. The chunk containing pos comes first, remote managers leave
+// out chunks they did not receive yet.
+func (cm *ChunkManager) LoadAround(pos rl.Vector3, radius int) []*Chunk {
+	centers := cm.centersAround(pos, radius)
+	chunks := make([]*Chunk, 0, len(centers))
+	for _, center := range centers {
+		if chunk := cm.GetChunk(center, rl.White); chunk != nil {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks
+}
+
+// centersAround returns the chunk centers within radius chunks around the
+// world position, starting with the chunk containing it.
+func (cm *ChunkManager) centersAround(pos rl.Vector3, radius int) []rl.Vector2 {
+	x, _, z := blockCoord(pos)
+	center := cm.chunkCenter(x, z)
+
+	centers := []rl.Vector2{center}
+	for dx := -radius; dx <= radius; dx++ {
+		for dz := -radius; dz <= radius; dz++ {
+			if dx == 0 && dz == 0 {
+				continue
+			}
+			centers = append(centers, rl.NewVector2(
+				center.X+float32(dx)*cm.width,
+				center.Y+float32(dz)*cm.length,
+			))
+		}
+	}
+	return centers
+}
+
+// Pending counts the chunks within radius around pos that are not
+// loaded yet, e.g. because the server did not send them.
+func (cm *ChunkManager) Pending(pos rl.Vector3, radius int) int {
+	n := 0
+	for _, center := range cm.centersAround(pos, radius) {
+		if _, ok := cm.chunkMap[center]; !ok {
+			n++
+		}
+	}
+	return n
+}
+
+// UnloadExcept drops every chunk not in keep. Modified chunks are saved
+// first when a store is set, without one chunks with edits stay loaded
+// so the edits are not lost.
+func (cm *ChunkManager) UnloadExcept(keep map[rl.Vector2]bool) error {
+	for center, chunk := range cm.chunkMap {
+		if keep[center] || cm.store == nil && len(chunk.edits) > 0 {
+			continue
+		}
+		if err := cm.saveChunk(chunk); err != nil {
+			return err
+		}
+		delete(cm.chunkMap, center)
+	}
+	return nil
+}
+
+// Save writes all modified chunks to the store.
+func (cm *ChunkManager) Save() error {
+	for _, chunk := range cm.chunkMap {
+		if err := cm.saveChunk(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cm *ChunkManager) saveChunk(chunk *Chunk) error {
+	// entities that stand still or only got older are not worth a write
+	if cm.store == nil || !chunk.dirty && !chunk.moved {
+		return nil
+	}
+	if err := cm.store.SaveChunk(chunk.center, chunk.edits, chunk.entities); err != nil {
+		return err
+	}
+	chunk.dirty, chunk.moved = false, false
+	return nil
+}
+
+// Chunks returns all loaded chunks ordered by their centers, so the
+// order does not change between runs.
+func (cm *ChunkManager) Chunks() []*Chunk {
+	chunks := make([]*Chunk, 0, len(cm.chunkMap))
+	for _, chunk := range cm.chunkMap {
+		chunks = append(chunks, chunk)
+	}
+	sort.Slice(chunks, func(i, j int) bool {
+		a, b := chunks[i].center, chunks[j].center
+		return a.X < b.X || a.X == b.X && a.Y < b.Y
+	})
+	return chunks
+}
+
+// NewRemoteChunkManager creates a manager for chunks received from a server.
+func NewRemoteChunkManager(size float32) *ChunkManager {
+	cm := NewChunkManager(size)
+	cm.remote = true
+	return cm
+}
+
+// insertChunk adds or replaces a chunk, e.g. one received from a server.
+func (cm *ChunkManager) insertChunk(chunk *Chunk) {
+	cm.chunkMap[chunk.center] = chunk
+}
+
+// GetChunk returns the chunk with the given center and generates it if
+// needed. Remote managers return nil for chunks that did not arrive yet.
 func (cm *ChunkManager) GetChunk(pos rl.Vector2, color rl.Color) *Chunk {
 	if chunk, ok := cm.chunkMap[pos]; ok {
 		return chunk
 	}
+	if cm.remote {
+		return nil
+	}
 
 	c := cm.generateNewChunk(pos)
 	c.debugColor = color
 	return c
 }
 
-var currentChunk *Chunk
+// chunkCenter returns the center key of the chunk containing world x, z.
+func (cm *ChunkManager) chunkCenter(x, z int) rl.Vector2 {
+	var (
+		cx = math.Floor((float64(x) + float64(cm.width/2)) / float64(cm.width))
+		cz = math.Floor((float64(z) + float64(cm.length/2)) / float64(cm.length))
+	)
+	// centers are stored negated, see GetChunks
+	return rl.NewVector2(float32(-cx)*cm.width, float32(-cz)*cm.length)
+}
 
-func (cm ChunkManager) DebugChunks(pos rl.Vector3, source *Chunk) {
-	rl.DrawText(fmt.Sprintf("position: %v", rl.NewVector2(pos.X, pos.Z)), 10, 40, 16, rl.Yellow)
-	for _, oldChunk := range cm.chunkMap {
-		dist := rl.Vector2Distance(oldChunk.center, rl.NewVector2(-pos.X, -pos.Z))
-		if dist < cm.length/2 {
-			rl.DrawText(fmt.Sprintf("distance: %d", int(dist)), 10, 60, 16, oldChunk.debugColor)
-			rl.DrawText(fmt.Sprintf("current chunk: %v", oldChunk.center), 10, 20, 16, oldChunk.debugColor)
-			break
+// BlockAt returns the block at the given world coordinates. Chunks that
+// have not been generated yet are treated as empty.
+func (cm *ChunkManager) BlockAt(x, y, z int) *Block {
+	chunk, lx, ly, lz, err := cm.localBlock(x, y, z)
+	if err != nil {
+		return nil
+	}
+	return chunk.GetBlock(lx, ly, lz)
+}
+
+// localBlock resolves world coordinates to a generated chunk and the
+// position inside of it.
+func (cm *ChunkManager) localBlock(x, y, z int) (*Chunk, int, int, int, error) {
+	center := cm.chunkCenter(x, z)
+	chunk, ok := cm.chunkMap[center]
+	if !ok {
+		return nil, 0, 0, 0, ErrChunkNotLoaded
+	}
+	return chunk, x + int(center.X+cm.width/2), y, z + int(center.Y+cm.length/2), nil
+}
+
+// SetBlock places a block of the given type at the world coordinates.
+func (cm *ChunkManager) SetBlock(x, y, z int, typ BlockType) error {
+	chunk, lx, ly, lz, err := cm.localBlock(x, y, z)
+	if err != nil {
+		return err
+	}
+	return chunk.setBlock(lx, ly, lz, &Block{
+		position:  rl.NewVector3(float32(x), float32(y), float32(z)),
+		blockType: typ,
+		enabled:   true,
+	})
+}
+
+// RemoveBlock clears the block at the world coordinates.
+func (cm *ChunkManager) RemoveBlock(x, y, z int) error {
+	chunk, lx, ly, lz, err := cm.localBlock(x, y, z)
+	if err != nil {
+		return err
+	}
+	return chunk.setBlock(lx, ly, lz, nil)
+}
+
+// Loaded reports whether the chunk containing pos is loaded.
+func (cm *ChunkManager) Loaded(pos rl.Vector3) bool {
+	x, _, z := blockCoord(pos)
+	_, ok := cm.chunkMap[cm.chunkCenter(x, z)]
+	return ok
+}
+
+// IsSolid reports whether the voxel at x, y, z blocks movement.
+func (cm *ChunkManager) IsSolid(x, y, z int) bool {
+	b := cm.BlockAt(x, y, z)
+	return b != nil && !b.carved
+}
+
+// SurfaceHeight returns the y coordinate above the highest solid block at x, z.
+func (cm *ChunkManager) SurfaceHeight(x, z int) int {
+	for y := int(cm.height) - 1; y >= 0; y-- {
+		if cm.IsSolid(x, y, z) {
+			return y + 1
 		}
 	}
+	return 0
 }
 
+var currentChunk *Chunk
+
 func (cm *ChunkManager) GetChunks(pos rl.Vector3, source *Chunk) []*Chunk {
 	currentChunk = source
 	for _, oldChunk := range cm.chunkMap {
@@ -140,19 +424,15 @@ func (cm *ChunkManager) generateNewChunk(pos rl.Vector2) *Chunk {
 		var w float32
 		for w = 0; w < cm.height; w++ {
 			var h float32
-			cm.terrainNoise.SetFractal(fastnoise.FNL_FRACTAL_RIDGED)
-			cm.terrainNoise.SetOctaves(4)
-			terraXZ := normalizef(cm.terrainNoise.GetNoise2D(w-startX, l-startY))
+			terraXZ := terrainHeight(cm.terrainNoise, cm.terrain, w-startX, l-startY)
 			for h = 0; h < cm.height; h++ {
 				if h < terraXZ || h == 0 {
-					cm.terrainNoise.SetFractal(fastnoise.FNL_FRACTAL_PINGPONG)
-					cm.terrainNoise.SetOctaves(6)
-					terraXYZ := normalizef(cm.terrainNoise.GetNoise3D(w-startX, float32(h), l-startY))
+					terraXYZ := caveNoise(cm.terrainNoise, cm.terrain, w-startX, h, l-startY)
 					b := &Block{
 						blockType: BlockTypeDirt,
 						position:  rl.NewVector3(w-startX, h, l-startY),
 					}
-					if terraXYZ < 10 && h > 0 {
+					if terraXYZ < cm.terrain.CaveThreshold && h > 0 {
 						b.carved = true
 					}
 					chunk.AddBlock(b, w, h, l)
@@ -162,6 +442,22 @@ func (cm *ChunkManager) generateNewChunk(pos rl.Vector2) *Chunk {
 	}
 
 	chunk.Generate()
+
+	var saved *chunkSave
+	if cm.store != nil {
+		var err error
+		if saved, err = cm.store.LoadChunk(pos); err != nil {
+			rl.TraceLog(rl.LogWarning, "chunk %v: %s", pos, err.Error())
+		}
+	}
+	if saved != nil {
+		if len(saved.edits) > 0 {
+			chunk.applyEdits(saved.edits)
+		}
+		chunk.entities = saved.entities
+	} else {
+		cm.populate(chunk)
+	}
 	cm.chunkMap[pos] = chunk
 	return chunk
 }
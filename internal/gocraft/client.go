@@ -0,0 +1,387 @@
+package gocraft
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+var ErrDisconnected = errors.New("disconnected")
+
+// maxPendingInputs bounds the prediction history when the server stops
+// answering, 10 seconds at the default tick rate.
+const maxPendingInputs = 200
+
+// RemotePlayer is another player on the server as seen by a client.
+type RemotePlayer struct {
+	ID         uint32
+	Name       string
+	Position   rl.Vector3
+	Yaw, Pitch float32
+}
+
+// clientItem is a packet received, or a chunk that is decoded in the
+// background. The chunk is sent on the channel once it is done, nil if
+// it could not be decoded.
+type clientItem struct {
+	packet Packet
+	chunk  chan *Chunk
+}
+
+type ChatMessage struct {
+	Sender  string
+	Message string
+}
+
+// Client is the connection of a game to a server. The world it holds
+// only contains the chunks the server sent, packets are applied to it in
+// Poll so the world is never touched by the network goroutines.
+type Client struct {
+	ID       uint32
+	Name     string
+	World    *World
+	Player   *Player
+	TickRate int
+	Others   map[uint32]*RemotePlayer
+	Chat     []ChatMessage
+	// Pitch is sent with the inputs so other players see where we look.
+	Pitch float32
+	// Reserved returns the stacks a screen took out of the inventory, like
+	// the crafting grid. The server still counts them as part of the
+	// inventory, so they are left out of every inventory it sends.
+	Reserved func() []ItemStack
+
+	// inputs the server did not acknowledge yet, they are replayed on top
+	// of every snapshot
+	pending  []*PlayerInputPacket
+	sequence uint32
+	acked    uint32
+
+	conn net.Conn
+	pc   packetLink
+	// incoming holds the packets in the order they arrived, next is the
+	// one Poll waits for
+	incoming chan clientItem
+	next     *clientItem
+	// done is closed once the connection ended
+	done chan struct{}
+
+	writeMu sync.Mutex
+	errMu   sync.Mutex
+	err     error
+}
+
+// Dial connects to the server at addr and logs in with name.
+func Dial(addr, name string) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, handshakeTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn, name)
+}
+
+// NewClient logs in on an established connection.
+func NewClient(conn net.Conn, name string) (*Client, error) {
+	return newClient(conn, newPacketConn(conn), name)
+}
+
+func newClient(conn net.Conn, link packetLink, name string) (*Client, error) {
+	c := &Client{
+		Name:     name,
+		Others:   make(map[uint32]*RemotePlayer),
+		conn:     conn,
+		pc:       link,
+		incoming: make(chan clientItem, clientQueueSize),
+		done:     make(chan struct{}),
+	}
+
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err := c.pc.Write(&HandshakePacket{Version: ProtocolVersion, Name: name}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	p, err := c.pc.Read()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	switch p := p.(type) {
+	case *LoginPacket:
+		c.ID = p.PlayerID
+		c.TickRate = int(p.TickRate)
+		c.World = NewRemoteWorld(float32(p.ChunkSize), int(p.ViewDistance))
+		c.World.Chunks.SetSeed(int(p.Seed))
+		c.World.Chunks.SetTerrain(p.Terrain)
+		c.Player = NewPlayer(p.Position)
+		c.World.Players = append(c.World.Players, c.Player)
+	case *DisconnectPacket:
+		conn.Close()
+		return nil, errors.New(p.Reason)
+	default:
+		conn.Close()
+		return nil, ErrUnknownPacket
+	}
+
+	go c.readLoop()
+	return c, nil
+}
+
+func (c *Client) readLoop() {
+	for {
+		p, err := c.pc.Read()
+		if err != nil {
+			c.fail(err)
+			return
+		}
+
+		item := clientItem{packet: p}
+		switch p := p.(type) {
+		case *KeepAlivePacket:
+			c.send(p)
+		case *ChunkDataPacket:
+			// decoding and building the instances is too slow for the
+			// render thread, the chunk keeps its place in the queue so
+			// later block changes apply to it
+			item = clientItem{chunk: make(chan *Chunk, 1)}
+			go func() {
+				center := rl.NewVector2(float32(p.X), float32(p.Z))
+				chunk, err := decodeChunk(center, int(p.Size), int(p.Height), p.Data)
+				if err != nil {
+					c.fail(err)
+				}
+				item.chunk <- chunk
+			}()
+		case *DisconnectPacket:
+			c.fail(errors.New(p.Reason))
+			return
+		}
+
+		select {
+		case c.incoming <- item:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// fail remembers the first error and closes the connection.
+func (c *Client) fail(err error) {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+	if c.err == nil {
+		c.err = err
+		close(c.done)
+		c.conn.Close()
+	}
+}
+
+// Err is the reason the connection ended, nil while it is open.
+func (c *Client) Err() error {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+	return c.err
+}
+
+// Poll applies everything received since the last call to the world in
+// the order it arrived. It stops at a chunk that is still decoded and
+// goes on there the next time. It has to be called from the goroutine
+// that ticks and renders the world.
+func (c *Client) Poll() error {
+	for {
+		if c.next == nil {
+			select {
+			case item := <-c.incoming:
+				c.next = &item
+			default:
+				return c.Err()
+			}
+		}
+		if c.next.chunk == nil {
+			c.handlePacket(c.next.packet)
+		} else {
+			select {
+			case chunk := <-c.next.chunk:
+				if chunk != nil {
+					c.World.Chunks.insertChunk(chunk)
+				}
+			default:
+				return c.Err()
+			}
+		}
+		c.next = nil
+	}
+}
+
+func (c *Client) handlePacket(p Packet) {
+	switch p := p.(type) {
+	case *PlayerSnapshotPacket:
+		c.reconcile(p)
+	case *InventoryPacket:
+		c.Player.GameMode = p.GameMode
+		c.Player.Inventory.Slots = [inventorySize]ItemStack{}
+		copy(c.Player.Inventory.Slots[:], p.Slots)
+		if c.Reserved != nil {
+			for _, s := range c.Reserved() {
+				c.Player.Inventory.Take(s.Type, s.Count)
+			}
+		}
+	case *BlockChangePacket:
+		x, y, z := int(p.X), int(p.Y), int(p.Z)
+		if p.Removed {
+			c.World.Chunks.RemoveBlock(x, y, z)
+		} else {
+			c.World.Chunks.SetBlock(x, y, z, p.Type)
+		}
+	case *PlayerJoinPacket:
+		c.Others[p.PlayerID] = &RemotePlayer{ID: p.PlayerID, Name: p.Name}
+	case *PlayerLeavePacket:
+		delete(c.Others, p.PlayerID)
+	case *PlayerMovePacket:
+		if other, ok := c.Others[p.PlayerID]; ok {
+			other.Position = p.Position
+			other.Yaw, other.Pitch = p.Yaw, p.Pitch
+		}
+	case *ChatPacket:
+		c.Chat = append(c.Chat, ChatMessage{Sender: p.Sender, Message: p.Message})
+	case *KeepAlivePacket:
+		// the nonce is the tick of the server, it keeps the time of day
+		// in sync
+		c.World.Ticks = p.Nonce
+	}
+}
+
+func (c *Client) send(p Packet) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := c.pc.Write(p); err != nil {
+		c.fail(err)
+		return err
+	}
+	return nil
+}
+
+// Tick predicts the next tick of the local player and sends its input
+// to the server, the world is ticked with dt.
+func (c *Client) Tick(dt float32) error {
+	c.sequence++
+	in := &PlayerInputPacket{
+		Sequence: c.sequence,
+		Input:    c.Player.Input,
+		Mode:     c.Player.Mode,
+		Pitch:    c.Pitch,
+	}
+	c.pending = append(c.pending, in)
+	if len(c.pending) > maxPendingInputs {
+		c.pending = c.pending[len(c.pending)-maxPendingInputs:]
+	}
+
+	c.World.Tick(dt)
+	return c.send(in)
+}
+
+// reconcile resets the player to the state of the server and replays the
+// inputs the server has not seen yet.
+func (c *Client) reconcile(snap *PlayerSnapshotPacket) {
+	// snapshots can arrive out of order, an older one carries nothing new
+	if snap.Sequence < c.acked {
+		return
+	}
+	c.acked = snap.Sequence
+
+	n := 0
+	for n < len(c.pending) && c.pending[n].Sequence <= snap.Sequence {
+		n++
+	}
+	// the server refused the mode of the acknowledged input, stop asking for
+	// it. The packets are copied, a delayed link may still be writing them.
+	if n > 0 && c.pending[n-1].Sequence == snap.Sequence && c.pending[n-1].Mode != snap.Mode {
+		for i, in := range c.pending[n:] {
+			changed := *in
+			changed.Mode = snap.Mode
+			c.pending[n+i] = &changed
+		}
+	}
+	c.pending = c.pending[n:]
+
+	p := c.Player
+	p.Position = snap.Position
+	p.Velocity = snap.Velocity
+	p.Mode = snap.Mode
+	p.OnGround = snap.OnGround
+
+	dt := 1 / float32(c.TickRate)
+	for _, in := range c.pending {
+		p.SetMode(in.Mode)
+		c.World.StepPlayer(p, in.Input, dt)
+	}
+}
+
+// BreakBlock removes a block and asks the server to do the same. The
+// server undoes it if the action was not allowed.
+func (c *Client) BreakBlock(x, y, z int) error {
+	c.World.Chunks.RemoveBlock(x, y, z)
+	return c.send(&BlockActionPacket{
+		Action: BlockActionBreak,
+		X:      int32(x),
+		Y:      int32(y),
+		Z:      int32(z),
+	})
+}
+
+// PlaceBlock places a block from the inventory and asks the server to do
+// the same.
+func (c *Client) PlaceBlock(x, y, z int, typ BlockType) error {
+	if !c.Player.useBlock(typ) {
+		return ErrNotInInventory
+	}
+	c.World.Chunks.SetBlock(x, y, z, typ)
+	return c.send(&BlockActionPacket{
+		Action: BlockActionPlace,
+		X:      int32(x),
+		Y:      int32(y),
+		Z:      int32(z),
+		Type:   typ,
+	})
+}
+
+// Craft asks the server to craft once with the blocks in grid. The
+// caller already crafted locally, the server answers with the new
+// inventory.
+func (c *Client) Craft(grid *CraftingGrid) error {
+	p := &CraftPacket{Grid: *NewCraftingGrid(grid.Width)}
+	copy(p.Grid.Slots, grid.Slots)
+	return c.send(p)
+}
+
+// Say sends a chat message to everyone on the server.
+func (c *Client) Say(message string) error {
+	return c.send(&ChatPacket{Message: message})
+}
+
+// DebugLines describes the connection for the debug screen.
+func (c *Client) DebugLines() []string {
+	return []string{
+		fmt.Sprintf("server: %s, id %d, %d others", c.conn.RemoteAddr(), c.ID, len(c.Others)),
+		fmt.Sprintf("inputs: seq %d, acked %d, %d pending", c.sequence, c.acked, len(c.pending)),
+	}
+}
+
+// Close leaves the server.
+func (c *Client) Close() error {
+	// the reason is set first, the server closing its end must not
+	// replace it
+	c.errMu.Lock()
+	if c.err == nil {
+		c.err = ErrDisconnected
+		close(c.done)
+	}
+	c.errMu.Unlock()
+	c.send(&DisconnectPacket{Reason: "quit"})
+	return c.conn.Close()
+}
@@ -0,0 +1,350 @@
+package gocraft
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/urfave/cli/v2"
+)
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+// configEnvPrefix starts the environment variables overriding the config
+// file. The rest of the name is the json path in upper case joined by
+// underscores, e.g. GOCRAFT_WIDTH or GOCRAFT_TERRAIN_FREQUENCY.
+const configEnvPrefix = "GOCRAFT"
+
+// LightConfig is the directional light of the world.
+type LightConfig struct {
+	// Color is a hex color like "#d3b083".
+	Color string `json:"color"`
+	// Ambient is the light level of surfaces facing away from the light.
+	Ambient float32 `json:"ambient"`
+}
+
+// Config holds everything the game reads at start. Values are taken
+// from the defaults, then the config file, then GOCRAFT_* environment
+// variables and at last the command line flags.
+type Config struct {
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Title    string `json:"title"`
+	LogLevel string `json:"log_level"`
+	Seed     int    `json:"seed"`
+	TickRate int    `json:"tick_rate"`
+	// ChunkSize and Terrain are used for new worlds, saved worlds keep
+	// the ones they were created with.
+	ChunkSize float32       `json:"chunk_size"`
+	Terrain   TerrainConfig `json:"terrain"`
+	// MoveSpeed is the walking speed in single player games.
+	MoveSpeed float32     `json:"move_speed"`
+	Light     LightConfig `json:"light"`
+	Sky       SkyConfig   `json:"sky"`
+	// ResourcePacks are directories or zip files layered over the
+	// embedded resources, the first one wins.
+	ResourcePacks []string `json:"resource_packs"`
+	// Settings can be changed in the settings screen, which writes them
+	// back to the config file.
+	Settings Settings `json:"settings"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Width:     800,
+		Height:    450,
+		Title:     "GoCraft",
+		LogLevel:  "info",
+		Seed:      DefaultSeed,
+		TickRate:  DefaultTickRate,
+		ChunkSize: DefaultChunkSize,
+		Terrain:   DefaultTerrain(),
+		MoveSpeed: playerWalkSpeed,
+		Light: LightConfig{
+			Color:   colorHex(rl.Beige),
+			Ambient: 0.2,
+		},
+		Sky:      DefaultSky(),
+		Settings: DefaultSettings(),
+	}
+}
+
+func (c Config) Validate() error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidConfig, fmt.Sprintf(format, args...))
+	}
+	switch {
+	case c.Width < 1 || c.Height < 1:
+		return invalid("width and height must be at least 1")
+	case c.Title == "":
+		return invalid("title is empty")
+	case !validLogLevel(c.LogLevel):
+		return invalid("log_level must be one of %s", strings.Join(logLevels, ", "))
+	case c.TickRate < 1 || c.TickRate > 1000:
+		return invalid("tick_rate must be 1 to 1000")
+	case !validChunkSize(c.ChunkSize):
+		return invalid("chunk_size must be a whole number from %d to %d", minChunkSize, maxChunkSize)
+	case c.MoveSpeed <= 0 || c.MoveSpeed > 100:
+		return invalid("move_speed must be in (0, 100]")
+	case c.Light.Ambient < 0 || c.Light.Ambient > 1:
+		return invalid("light.ambient must be 0 to 1")
+	}
+	if err := c.Terrain.Validate(c.ChunkSize); err != nil {
+		return invalid("terrain: %s", err.Error())
+	}
+	if err := c.Sky.Validate(); err != nil {
+		return invalid("sky: %s", err.Error())
+	}
+	if _, err := parseColor(c.Light.Color); err != nil {
+		return invalid("light.color: %s", err.Error())
+	}
+	if err := c.Settings.Validate(); err != nil {
+		return invalid("settings: %s", err.Error())
+	}
+	return nil
+}
+
+const (
+	minChunkSize = 16
+	maxChunkSize = 256
+)
+
+func validChunkSize(size float32) bool {
+	return size >= minChunkSize && size <= maxChunkSize && size == float32(int(size))
+}
+
+// LightColor is the parsed Light.Color, Validate makes sure it parses.
+func (c Config) LightColor() rl.Color {
+	color, _ := parseColor(c.Light.Color)
+	return color
+}
+
+// ConfigPath is the default location of the config file.
+func ConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gocraft", "config.json"), nil
+}
+
+// LoadConfig reads the config file over the defaults without validating
+// it. A missing file leaves the defaults untouched.
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ResolveConfig merges defaults, the config file, the environment and
+// the flags of ctx and validates the result. It also returns the path of
+// the config file, which is --config-file or ConfigPath.
+func ResolveConfig(ctx *cli.Context) (Config, string, error) {
+	path := ctx.String("config-file")
+	if path == "" {
+		var err error
+		if path, err = ConfigPath(); err != nil {
+			return DefaultConfig(), "", err
+		}
+	} else if _, err := os.Stat(path); err != nil {
+		// a file given explicitly has to exist
+		return DefaultConfig(), path, err
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		return config, path, err
+	}
+	if err := applyEnv(reflect.ValueOf(&config).Elem(), configEnvPrefix, os.LookupEnv); err != nil {
+		return config, path, err
+	}
+	applyFlags(&config, ctx)
+	if err := config.Validate(); err != nil {
+		return config, path, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, path, nil
+}
+
+// applyEnv sets every field of v that has an environment variable. The
+// variable names are built from the json tags below prefix.
+func applyEnv(v reflect.Value, prefix string, lookup func(string) (string, bool)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		var (
+			name  = prefix + "_" + strings.ToUpper(tag)
+			field = v.Field(i)
+		)
+		if field.Kind() == reflect.Struct {
+			if err := applyEnv(field, name, lookup); err != nil {
+				return err
+			}
+			continue
+		}
+		value, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		if err := setField(field, value); err != nil {
+			return fmt.Errorf("%w: %s: %s", ErrInvalidConfig, name, err.Error())
+		}
+	}
+	return nil
+}
+
+func setField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("can not be set from the environment")
+		}
+		// lists are separated like PATH
+		field.Set(reflect.ValueOf(filepath.SplitList(value)))
+	default:
+		return fmt.Errorf("can not be set from the environment")
+	}
+	return nil
+}
+
+// applyFlags copies the flags given on the command line into config.
+// Flags that were not given keep the value of the lower layers.
+func applyFlags(config *Config, ctx *cli.Context) {
+	ints := map[string]*int{
+		"width":  &config.Width,
+		"height": &config.Height,
+		"seed":   &config.Seed,
+		"tps":    &config.TickRate,
+		"fps":    &config.Settings.MaxFPS,
+	}
+	for name, dst := range ints {
+		if ctx.IsSet(name) {
+			*dst = ctx.Int(name)
+		}
+	}
+	floats := map[string]*float32{
+		"fov":         &config.Settings.FOV,
+		"sensitivity": &config.Settings.Sensitivity,
+	}
+	for name, dst := range floats {
+		if ctx.IsSet(name) {
+			*dst = float32(ctx.Float64(name))
+		}
+	}
+	if ctx.IsSet("title") {
+		config.Title = ctx.String("title")
+	}
+	if ctx.IsSet("loglevel") {
+		config.LogLevel = ctx.String("loglevel")
+	}
+	if ctx.IsSet("resource-pack") {
+		config.ResourcePacks = ctx.StringSlice("resource-pack")
+	}
+}
+
+// SaveConfigSettings writes the settings changed from before to after
+// into the config file at path. The other settings and keys of the file
+// are kept as they are, so values that came from the environment or
+// flags are not written unless they were changed in the game.
+func SaveConfigSettings(path string, before, after Settings) error {
+	file := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	settings := DefaultSettings()
+	if saved, ok := file["settings"]; ok {
+		if err := json.Unmarshal(saved, &settings); err != nil {
+			return fmt.Errorf("%s: settings: %w", path, err)
+		}
+	}
+	if file["settings"], err = json.Marshal(mergeSettings(settings, before, after)); err != nil {
+		return err
+	}
+	if data, err = json.MarshalIndent(file, "", "  "); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// RunConfig is the action of the config command, it prints the
+// effective configuration.
+func RunConfig(ctx *cli.Context) error {
+	config, path, err := ResolveConfig(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(ctx.App.ErrWriter, "config file: %s\n", path)
+	fmt.Fprintln(ctx.App.Writer, string(data))
+	return nil
+}
+
+// parseColor reads colors like "#d3b083" or "#d3b083ff".
+func parseColor(s string) (rl.Color, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if !strings.HasPrefix(s, "#") || len(hex) != 8 {
+		return rl.Color{}, fmt.Errorf("%q is not a color like #rrggbb", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return rl.Color{}, fmt.Errorf("%q is not a color like #rrggbb", s)
+	}
+	return rl.NewColor(uint8(v>>24), uint8(v>>16), uint8(v>>8), uint8(v)), nil
+}
+
+func colorHex(c rl.Color) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
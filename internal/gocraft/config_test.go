@@ -0,0 +1,247 @@
+package gocraft
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+// configContext parses args with the global flags ResolveConfig reads.
+func configContext(t *testing.T, args ...string) *cli.Context {
+	t.Helper()
+	defaults := DefaultConfig()
+	set := flag.NewFlagSet("gocraft", flag.ContinueOnError)
+	for _, f := range []cli.Flag{
+		&cli.IntFlag{Name: "width", Value: defaults.Width},
+		&cli.StringFlag{Name: "title", Value: defaults.Title},
+		&cli.Float64Flag{Name: "fov", Value: float64(defaults.Settings.FOV)},
+		&cli.StringFlag{Name: "config-file"},
+	} {
+		if err := f.Apply(set); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return cli.NewContext(nil, set, nil)
+}
+
+func TestResolveConfigPrecedence(t *testing.T) {
+	defaults := DefaultConfig()
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		// want are the width, title and fov of the result
+		wantWidth int
+		wantTitle string
+		wantFOV   float32
+		wantErr   error
+	}{
+		{
+			name:      "defaults",
+			file:      `{}`,
+			wantWidth: defaults.Width, wantTitle: defaults.Title, wantFOV: defaults.Settings.FOV,
+		},
+		{
+			name:      "file over defaults",
+			file:      `{"width": 1024, "settings": {"fov": 70}}`,
+			wantWidth: 1024, wantTitle: defaults.Title, wantFOV: 70,
+		},
+		{
+			name:      "env over file",
+			file:      `{"width": 1024, "settings": {"fov": 70}}`,
+			env:       map[string]string{"GOCRAFT_WIDTH": "1280", "GOCRAFT_SETTINGS_FOV": "80"},
+			wantWidth: 1280, wantTitle: defaults.Title, wantFOV: 80,
+		},
+		{
+			name:      "flags over env",
+			file:      `{"width": 1024, "title": "file", "settings": {"fov": 70}}`,
+			env:       map[string]string{"GOCRAFT_WIDTH": "1280", "GOCRAFT_SETTINGS_FOV": "80"},
+			args:      []string{"--width", "1600", "--fov", "90"},
+			wantWidth: 1600, wantTitle: "file", wantFOV: 90,
+		},
+		{
+			// a flag that is not given keeps its default out of the way
+			name:      "flag defaults do not count",
+			file:      `{"title": "file"}`,
+			args:      []string{"--width", "1600"},
+			wantWidth: 1600, wantTitle: "file", wantFOV: defaults.Settings.FOV,
+		},
+		{
+			name:    "env not a number",
+			file:    `{}`,
+			env:     map[string]string{"GOCRAFT_WIDTH": "wide"},
+			wantErr: ErrInvalidConfig,
+		},
+		{
+			name:    "flag out of range",
+			file:    `{}`,
+			args:    []string{"--fov", "500"},
+			wantErr: ErrInvalidConfig,
+		},
+		{
+			name:    "file out of range",
+			file:    `{"width": 0}`,
+			wantErr: ErrInvalidConfig,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			ctx := configContext(t, append([]string{"--config-file", path}, tt.args...)...)
+
+			config, got, err := ResolveConfig(ctx)
+			if got != path {
+				t.Errorf("config file %q, want %q", got, path)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Width != tt.wantWidth || config.Title != tt.wantTitle || config.Settings.FOV != tt.wantFOV {
+				t.Errorf("width %d, title %q, fov %.0f, want %d, %q, %.0f",
+					config.Width, config.Title, config.Settings.FOV, tt.wantWidth, tt.wantTitle, tt.wantFOV)
+			}
+		})
+	}
+}
+
+func TestResolveConfigMissingFile(t *testing.T) {
+	ctx := configContext(t, "--config-file", filepath.Join(t.TempDir(), "missing.json"))
+	if _, _, err := ResolveConfig(ctx); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	type nested struct {
+		Rate float32 `json:"rate"`
+	}
+	type values struct {
+		Name     string            `json:"name"`
+		Count    int               `json:"count,omitempty"`
+		On       bool              `json:"on"`
+		Inner    nested            `json:"inner"`
+		List     []string          `json:"list"`
+		Ignored  string            `json:"-"`
+		Untagged string            // no tag, no variable
+		Map      map[string]string `json:"map"`
+	}
+	list := string(filepath.ListSeparator)
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    values
+		wantErr bool
+	}{
+		{name: "nothing set", env: map[string]string{}},
+		{
+			name: "every kind",
+			env: map[string]string{
+				"TEST_NAME":       "gocraft",
+				"TEST_COUNT":      "3",
+				"TEST_ON":         "true",
+				"TEST_INNER_RATE": "0.5",
+				"TEST_LIST":       "a" + list + "b",
+			},
+			want: values{Name: "gocraft", Count: 3, On: true, Inner: nested{Rate: 0.5}, List: []string{"a", "b"}},
+		},
+		{
+			name: "no tag or skipped",
+			env:  map[string]string{"TEST_IGNORED": "x", "TEST_UNTAGGED": "x"},
+		},
+		{name: "bad int", env: map[string]string{"TEST_COUNT": "three"}, wantErr: true},
+		{name: "bad bool", env: map[string]string{"TEST_ON": "maybe"}, wantErr: true},
+		{name: "bad float", env: map[string]string{"TEST_INNER_RATE": "fast"}, wantErr: true},
+		{name: "unsupported kind", env: map[string]string{"TEST_MAP": "a=b"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got values
+			lookup := func(name string) (string, bool) {
+				v, ok := tt.env[name]
+				return v, ok
+			}
+			err := applyEnv(reflect.ValueOf(&got).Elem(), "TEST", lookup)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidConfig) {
+					t.Errorf("got %v, want %v", err, ErrInvalidConfig)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveConfigSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"width": 1024, "settings": {"fov": 70, "sensitivity": 0.5}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// the game started with --fov 100, only the render distance was
+	// changed in the settings screen
+	before := DefaultSettings()
+	before.FOV, before.Sensitivity = 100, 0.5
+	after := before
+	after.RenderDistance = 3
+	if err := SaveConfigSettings(path, before, after); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultSettings()
+	want.FOV, want.Sensitivity, want.RenderDistance = 70, 0.5, 3
+	if config.Settings != want {
+		t.Errorf("saved %+v, want %+v", config.Settings, want)
+	}
+	if config.Width != 1024 {
+		t.Errorf("width %d, the other keys have to stay", config.Width)
+	}
+
+	// changing a value given by a flag writes it
+	before = after
+	after.FOV = 90
+	if err := SaveConfigSettings(path, before, after); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file struct{ Settings Settings }
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	if file.Settings.FOV != 90 || file.Settings.RenderDistance != 3 {
+		t.Errorf("saved %+v, want fov 90 and render distance 3", file.Settings)
+	}
+}
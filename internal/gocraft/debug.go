@@ -0,0 +1,192 @@
+package gocraft
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const (
+	debugFontSize   = 10
+	debugLineHeight = 12
+	debugMargin     = 5
+	// debugFrames is the number of frames in the frame-time graph.
+	debugFrames = 240
+	// debugGraphHeight is the graph height of a frame at 30 FPS.
+	debugGraphHeight = 60
+	// memory stats stop the world, they are read twice a second only
+	debugMemInterval float32 = 0.5
+)
+
+// DebugLines returns the lines a subsystem shows on the debug screen.
+type DebugLines func() []string
+
+type debugSection struct {
+	name  string
+	lines DebugLines
+}
+
+// DebugOverlay is the F3 screen. Subsystems register their lines once,
+// they are only called while the overlay is visible.
+type DebugOverlay struct {
+	Visible bool
+
+	sections   []debugSection
+	frameTimes [debugFrames]float32
+	frame      int
+	mem        runtime.MemStats
+	memAge     float32
+}
+
+// Register adds a section of lines below the ones registered before. A
+// section registered again under the same name replaces the old one.
+func (d *DebugOverlay) Register(name string, lines DebugLines) {
+	for i, s := range d.sections {
+		if s.name == name {
+			d.sections[i].lines = lines
+			return
+		}
+	}
+	d.sections = append(d.sections, debugSection{name: name, lines: lines})
+}
+
+// Unregister removes a section.
+func (d *DebugOverlay) Unregister(name string) {
+	for i, s := range d.sections {
+		if s.name == name {
+			d.sections = append(d.sections[:i], d.sections[i+1:]...)
+			return
+		}
+	}
+}
+
+// Lines returns the lines of all sections in order.
+func (d *DebugOverlay) Lines() []string {
+	var lines []string
+	for _, s := range d.sections {
+		lines = append(lines, s.lines()...)
+	}
+	return lines
+}
+
+// Record adds the duration of a frame to the graph. It is called every
+// frame, also while the overlay is hidden.
+func (d *DebugOverlay) Record(frameTime float32) {
+	d.frameTimes[d.frame] = frameTime
+	d.frame = (d.frame + 1) % debugFrames
+
+	d.memAge -= frameTime
+	if d.Visible && d.memAge <= 0 {
+		runtime.ReadMemStats(&d.mem)
+		d.memAge = debugMemInterval
+	}
+}
+
+// systemLines are shown on the right side of the screen.
+func (d *DebugOverlay) systemLines() []string {
+	const mb = 1 << 20
+	return []string{
+		fmt.Sprintf("go: %s %s/%s", runtime.Version(), runtime.GOOS, runtime.GOARCH),
+		fmt.Sprintf("mem: %d MB / %d MB", d.mem.HeapAlloc/mb, d.mem.Sys/mb),
+		fmt.Sprintf("gc: %d", d.mem.NumGC),
+		fmt.Sprintf("goroutines: %d", runtime.NumGoroutine()),
+	}
+}
+
+func (d *DebugOverlay) Draw(r Renderer) {
+	if !d.Visible {
+		return
+	}
+	y := int32(debugMargin)
+	for _, line := range d.Lines() {
+		drawDebugText(r, line, debugMargin, y)
+		y += debugLineHeight
+	}
+
+	w, _ := r.ScreenSize()
+	y = debugMargin
+	for _, line := range d.systemLines() {
+		drawDebugText(r, line, w-r.MeasureText(line, debugFontSize)-2*debugMargin, y)
+		y += debugLineHeight
+	}
+
+	d.drawGraph(r)
+}
+
+// drawGraph draws one bar per frame in the bottom left corner, the
+// lines mark 60 and 30 FPS.
+func (d *DebugOverlay) drawGraph(r Renderer) {
+	var (
+		_, screenH = r.ScreenSize()
+		bottom     = screenH - debugMargin
+		scale      = float32(debugGraphHeight) * 30
+	)
+	r.DrawRectangle(recti(debugMargin, bottom-debugGraphHeight, debugFrames, debugGraphHeight), rl.Fade(rl.Black, 0.5))
+	for i := 0; i < debugFrames; i++ {
+		t := d.frameTimes[(d.frame+i)%debugFrames]
+		h := int32(t * scale)
+		if h > debugGraphHeight {
+			h = debugGraphHeight
+		}
+		color := rl.Green
+		switch {
+		case t > 1.0/30:
+			color = rl.Red
+		case t > 1.0/60:
+			color = rl.Yellow
+		}
+		x := float32(debugMargin + i)
+		r.DrawLine(rl.NewVector2(x, float32(bottom)), rl.NewVector2(x, float32(bottom-h)), color)
+	}
+	for _, fps := range []float32{60, 30} {
+		y := bottom - int32(scale/fps)
+		r.DrawLine(rl.NewVector2(debugMargin, float32(y)), rl.NewVector2(debugMargin+debugFrames, float32(y)), rl.Fade(rl.White, 0.6))
+		drawDebugText(r, fmt.Sprintf("%.0f fps", fps), debugMargin+debugFrames+debugMargin, y-debugFontSize/2)
+	}
+}
+
+// drawDebugText draws text on a dark background to keep it readable in
+// front of the sky.
+func drawDebugText(r Renderer, text string, x, y int32) {
+	r.DrawRectangle(recti(x-1, y-1, r.MeasureText(text, debugFontSize)+2, debugLineHeight), rl.Fade(rl.Black, 0.5))
+	r.DrawText(text, x, y, debugFontSize, rl.RayWhite)
+}
+
+// facing names the compass direction of a yaw. Yaw 0 looks along +x,
+// 90 along +z.
+func facing(yaw float32) string {
+	names := [4]string{"east (+x)", "south (+z)", "west (-x)", "north (-z)"}
+	a := math.Mod(float64(yaw)+45, 360)
+	if a < 0 {
+		a += 360
+	}
+	return names[int(a/90)%4]
+}
+
+// RenderStats counts the draw work of one frame.
+type RenderStats struct {
+	DrawCalls int
+	Instances int
+	Chunks    int
+	Sections  int
+	// sections skipped because they are outside of the view or can not
+	// be seen from the camera
+	FrustumCulled int
+	CaveCulled    int
+	// FarTiles are the drawn tiles of the far terrain.
+	FarTiles int
+}
+
+func (s *RenderStats) Reset() {
+	*s = RenderStats{}
+}
+
+func (s *RenderStats) DebugLines() []string {
+	return []string{
+		fmt.Sprintf("draw: %d calls, %d instances, %d chunks", s.DrawCalls, s.Instances, s.Chunks),
+		fmt.Sprintf("sections: %d drawn, %d frustum culled, %d cave culled", s.Sections, s.FrustumCulled, s.CaveCulled),
+		fmt.Sprintf("far terrain: %d tiles", s.FarTiles),
+	}
+}
@@ -0,0 +1,347 @@
+package gocraft
+
+import (
+	"math"
+	"math/rand"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+type EntityID uint32
+
+type EntityKind uint8
+
+const (
+	EntityKindItem EntityKind = iota + 1
+	EntityKindMob
+)
+
+func (k EntityKind) String() string {
+	switch k {
+	case EntityKindItem:
+		return "item"
+	case EntityKindMob:
+		return "mob"
+	}
+	return "unknown"
+}
+
+const (
+	itemSize float32 = 0.25
+	// dropped items disappear after five minutes
+	itemDespawnTime float32 = 300
+	itemPopSpeed    float32 = 3
+	itemSpinSpeed   float32 = 90
+	// ground friction of items, the velocity left after one second
+	itemFriction float32 = 0.02
+
+	mobWidth        float32 = 0.9
+	mobHeight       float32 = 0.9
+	mobWalkSpeed    float32 = 1.5
+	mobRunSpeed     float32 = 3.5
+	mobJumpVelocity float32 = playerJumpVelocity
+	mobsPerChunk            = 2
+)
+
+// Behaviour is a piece of logic attached to an entity. Behaviours run in
+// order every tick before the entity is moved by its velocity.
+type Behaviour interface {
+	Update(e *Entity, w *World, dt float32)
+}
+
+// Entity is anything besides blocks and players that lives in the world.
+// Entities belong to the chunk they stand in and are loaded, saved and
+// unloaded with it. They live where the world is simulated, in a single
+// player game or on a server, the clients of a server do not get them.
+type Entity struct {
+	ID            EntityID
+	Kind          EntityKind
+	Position      rl.Vector3 // feet position
+	Velocity      rl.Vector3
+	Width, Height float32
+	Yaw           float32
+	OnGround      bool
+	// Blocked is set when a wall stopped the last horizontal move.
+	Blocked bool
+	// Age is the time in seconds the entity exists.
+	Age float32
+	// Item and Count are the content of dropped items.
+	Item  BlockType
+	Count int
+
+	Behaviours []Behaviour
+
+	prevPosition rl.Vector3
+	removed      bool
+}
+
+// NewEntity creates an entity of the given kind with its default size
+// and behaviours.
+func NewEntity(kind EntityKind, position rl.Vector3) *Entity {
+	e := &Entity{
+		Kind:         kind,
+		Position:     position,
+		prevPosition: position,
+	}
+	switch kind {
+	case EntityKindItem:
+		e.Width, e.Height = itemSize, itemSize
+		e.Count = 1
+		e.Behaviours = []Behaviour{
+			&DespawnBehaviour{After: itemDespawnTime},
+			&SpinBehaviour{Speed: itemSpinSpeed},
+		}
+	case EntityKindMob:
+		e.Width, e.Height = mobWidth, mobHeight
+		e.Behaviours = []Behaviour{NewMobBehaviour()}
+	}
+	return e
+}
+
+// NewItemEntity creates a dropped stack of blocks.
+func NewItemEntity(typ BlockType, count int, position rl.Vector3) *Entity {
+	e := NewEntity(EntityKindItem, position)
+	e.Item = typ
+	e.Count = count
+	return e
+}
+
+func (e *Entity) Box() AABB {
+	return NewAABB(e.Position, e.Width, e.Height)
+}
+
+// InterpolatedPosition is the position between the previous and the
+// current tick, alpha 0 is the previous tick.
+func (e *Entity) InterpolatedPosition(alpha float32) rl.Vector3 {
+	return rl.Vector3Lerp(e.prevPosition, e.Position, alpha)
+}
+
+// Remove takes the entity out of the world at the end of the tick.
+func (e *Entity) Remove() {
+	e.removed = true
+}
+
+func (e *Entity) Removed() bool {
+	return e.removed
+}
+
+// physics applies gravity and moves the entity by its velocity.
+func (e *Entity) physics(world VoxelWorld, dt float32) {
+	e.Velocity.Y -= playerGravity * dt
+	if e.Velocity.Y < -playerMaxFallSpeed {
+		e.Velocity.Y = -playerMaxFallSpeed
+	}
+	if e.Kind == EntityKindItem && e.OnGround {
+		friction := float32(math.Pow(float64(itemFriction), float64(dt)))
+		e.Velocity.X *= friction
+		e.Velocity.Z *= friction
+	}
+	e.move(world, rl.Vector3Scale(e.Velocity, dt))
+}
+
+// move works like Player.move without the sneaking edge guard.
+func (e *Entity) move(world VoxelWorld, delta rl.Vector3) {
+	box := e.Box()
+
+	dy := sweepAxis(world, box, axisY, delta.Y)
+	box = box.Offset(rl.NewVector3(0, dy, 0))
+	e.OnGround = delta.Y < 0 && dy != delta.Y
+	if dy != delta.Y {
+		e.Velocity.Y = 0
+	}
+
+	e.Blocked = false
+	for _, axis := range []int{axisX, axisZ} {
+		want := vecAxis(delta, axis)
+		d := sweepAxis(world, box, axis, want)
+		var offset rl.Vector3
+		setVecAxis(&offset, axis, d)
+		box = box.Offset(offset)
+		if d != want {
+			setVecAxis(&e.Velocity, axis, 0)
+			e.Blocked = true
+		}
+	}
+
+	e.Position = rl.NewVector3(
+		(box.Min.X+box.Max.X)/2,
+		box.Min.Y,
+		(box.Min.Z+box.Max.Z)/2,
+	)
+}
+
+// DespawnBehaviour removes the entity once it is older than After seconds.
+type DespawnBehaviour struct {
+	After float32
+}
+
+func (b *DespawnBehaviour) Update(e *Entity, w *World, dt float32) {
+	if e.Age > b.After {
+		e.Remove()
+	}
+}
+
+// SpinBehaviour turns the entity around its vertical axis.
+type SpinBehaviour struct {
+	Speed float32 // degrees per second
+}
+
+func (b *SpinBehaviour) Update(e *Entity, w *World, dt float32) {
+	e.Yaw = float32(math.Mod(float64(e.Yaw+b.Speed*dt), 360))
+}
+
+// SpawnEntity adds e to the chunk it stands in and gives it an ID.
+func (w *World) SpawnEntity(e *Entity) error {
+	chunk := w.Chunks.chunkAt(e.Position)
+	if chunk == nil {
+		return ErrChunkNotLoaded
+	}
+	e.ID = w.newEntityID()
+	chunk.addEntity(e)
+	return nil
+}
+
+func (w *World) newEntityID() EntityID {
+	w.lastEntityID++
+	return w.lastEntityID
+}
+
+// DropItem spawns count blocks of typ at the block position x, y, z,
+// popping up in a random direction.
+func (w *World) DropItem(typ BlockType, count int, x, y, z int) error {
+	e := NewItemEntity(typ, count, rl.NewVector3(float32(x), float32(y)+0.5-itemSize/2, float32(z)))
+	angle := w.rng.Float64() * 2 * math.Pi
+	e.Velocity = rl.NewVector3(
+		float32(math.Cos(angle))*itemPopSpeed/3,
+		itemPopSpeed,
+		float32(math.Sin(angle))*itemPopSpeed/3,
+	)
+	return w.SpawnEntity(e)
+}
+
+// Entities returns the entities of all loaded chunks in the order of
+// the chunks, see ChunkManager.Chunks.
+func (w *World) Entities() []*Entity {
+	var entities []*Entity
+	for _, chunk := range w.Chunks.Chunks() {
+		entities = append(entities, chunk.entities...)
+	}
+	return entities
+}
+
+// tickEntities runs the behaviours and physics of all entities and moves
+// them to the chunk they end up in. Entities can not walk into chunks
+// that are not loaded. The chunks are visited in a fixed order, so the
+// random numbers of the world are drawn the same way every run.
+func (w *World) tickEntities(dt float32) {
+	// the chunks own their entities before anything moves, so an entity
+	// that changes chunks is neither ticked twice nor lost
+	type owned struct {
+		chunk  *Chunk
+		entity *Entity
+	}
+	var entities []owned
+	for _, chunk := range w.Chunks.Chunks() {
+		for _, e := range chunk.entities {
+			entities = append(entities, owned{chunk, e})
+		}
+	}
+
+	for _, o := range entities {
+		e := o.entity
+		if e.removed {
+			continue
+		}
+		if e.ID == 0 {
+			// loaded from a chunk file
+			e.ID = w.newEntityID()
+		}
+		e.prevPosition = e.Position
+		e.Age += dt
+		for _, b := range e.Behaviours {
+			b.Update(e, w, dt)
+		}
+		if e.removed {
+			continue
+		}
+
+		e.physics(w.Chunks, dt)
+		to := w.Chunks.chunkAt(e.Position)
+		switch {
+		case to == nil:
+			// back to where it started the tick, inside of its chunk
+			e.Position = e.prevPosition
+			e.Velocity.X, e.Velocity.Z = 0, 0
+		case to != o.chunk:
+			o.chunk.removeEntity(e)
+			to.addEntity(e)
+		}
+		if e.Position != e.prevPosition {
+			w.Chunks.chunkAt(e.Position).moved = true
+		}
+		if e.Position.Y < fallOutOfWorld {
+			e.Remove()
+		}
+	}
+
+	for _, chunk := range w.Chunks.chunkMap {
+		chunk.removeDeadEntities()
+	}
+}
+
+func (c *Chunk) addEntity(e *Entity) {
+	c.entities = append(c.entities, e)
+	c.dirty = true
+}
+
+func (c *Chunk) removeEntity(e *Entity) {
+	for i, other := range c.entities {
+		if other == e {
+			c.entities = append(c.entities[:i], c.entities[i+1:]...)
+			c.dirty = true
+			return
+		}
+	}
+}
+
+func (c *Chunk) removeDeadEntities() {
+	alive := c.entities[:0]
+	for _, e := range c.entities {
+		if !e.removed {
+			alive = append(alive, e)
+		}
+	}
+	if len(alive) != len(c.entities) {
+		c.dirty = true
+	}
+	for i := len(alive); i < len(c.entities); i++ {
+		c.entities[i] = nil
+	}
+	c.entities = alive
+}
+
+// chunkAt returns the loaded chunk containing pos or nil.
+func (cm *ChunkManager) chunkAt(pos rl.Vector3) *Chunk {
+	x, _, z := blockCoord(pos)
+	return cm.chunkMap[cm.chunkCenter(x, z)]
+}
+
+// populate spawns the mobs of a freshly generated chunk. The positions
+// only depend on the seed and the chunk.
+func (cm *ChunkManager) populate(chunk *Chunk) {
+	rng := rand.New(rand.NewSource(
+		int64(cm.seed) ^ int64(chunk.center.X)*73856093 ^ int64(chunk.center.Y)*19349663,
+	))
+	for i := 0; i < mobsPerChunk; i++ {
+		var (
+			x = 1 + rng.Intn(len(chunk.blockList)-2)
+			z = 1 + rng.Intn(len(chunk.blockList[0][0])-2)
+		)
+		for y := len(chunk.blockList[x]) - 2; y >= 0; y-- {
+			if chunk.HasBlock(x, y, z) {
+				chunk.entities = append(chunk.entities, NewEntity(EntityKindMob, chunk.worldPosition(x, y+1, z)))
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package gocraft
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const testChunkSize float32 = 32
+
+// testTerrain is a low terrain for small, fast test chunks.
+func testTerrain() TerrainConfig {
+	terrain := DefaultTerrain()
+	terrain.Height = 24
+	terrain.CaveThreshold = 5
+	return terrain
+}
+
+// teleportBehaviour moves the entity to a position once.
+type teleportBehaviour struct {
+	to   rl.Vector3
+	done bool
+}
+
+func (b *teleportBehaviour) Update(e *Entity, w *World, dt float32) {
+	if !b.done {
+		e.Position, b.done = b.to, true
+	}
+}
+
+// count is how often e is stored in the chunks of the world.
+func count(w *World, e *Entity) int {
+	n := 0
+	for _, other := range w.Entities() {
+		if other == e {
+			n++
+		}
+	}
+	return n
+}
+
+func TestEntityMovedByBehaviourChangesChunk(t *testing.T) {
+	w := NewWorld(testChunkSize, 7, nil, testTerrain())
+	w.Chunks.LoadAround(rl.Vector3Zero(), 1)
+	start := rl.NewVector3(0, float32(w.Chunks.SurfaceHeight(0, 0)), 0)
+	e := NewEntity(EntityKindItem, start)
+	if err := w.SpawnEntity(e); err != nil {
+		t.Fatal(err)
+	}
+	// one chunk over, the chunk of the old position is the wrong one
+	to := rl.NewVector3(testChunkSize, float32(w.Chunks.SurfaceHeight(int(testChunkSize), 0)), 0)
+	e.Behaviours = append(e.Behaviours, &teleportBehaviour{to: to})
+	w.tickEntities(testDT)
+
+	if n := count(w, e); n != 1 {
+		t.Fatalf("entity is stored %d times", n)
+	}
+	if chunk := w.Chunks.chunkAt(e.Position); !containsEntity(chunk, e) {
+		t.Errorf("entity at %v is not in its chunk", e.Position)
+	}
+}
+
+func TestEntityMovedOutOfLoadedChunks(t *testing.T) {
+	w := NewWorld(testChunkSize, 7, nil, testTerrain())
+	start := rl.NewVector3(0, float32(w.Chunks.SurfaceHeight(0, 0)), 0)
+	e := NewEntity(EntityKindItem, start)
+	if err := w.SpawnEntity(e); err != nil {
+		t.Fatal(err)
+	}
+	e.Behaviours = append(e.Behaviours, &teleportBehaviour{to: rl.NewVector3(1000, 20, 1000)})
+	w.tickEntities(testDT)
+
+	if e.Position != start {
+		t.Errorf("entity left the loaded chunks to %v", e.Position)
+	}
+	if n := count(w, e); n != 1 {
+		t.Errorf("entity is stored %d times", n)
+	}
+}
+
+func containsEntity(chunk *Chunk, e *Entity) bool {
+	if chunk == nil {
+		return false
+	}
+	for _, other := range chunk.entities {
+		if other == e {
+			return true
+		}
+	}
+	return false
+}
+
+func TestChunkSaveKeepsEntityAge(t *testing.T) {
+	store, err := NewWorldStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := NewItemEntity(BlockTypeSnow, 3, rl.NewVector3(1, 2, 3))
+	item.Age = 120.5
+	center := rl.NewVector2(16, -16)
+	if err := store.SaveChunk(center, nil, []*Entity{item}); err != nil {
+		t.Fatal(err)
+	}
+	save, err := store.LoadChunk(center)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(save.entities) != 1 {
+		t.Fatalf("%d entities loaded, want 1", len(save.entities))
+	}
+	got := save.entities[0]
+	if got.Age != item.Age || got.Item != item.Item || got.Count != item.Count || got.Position != item.Position {
+		t.Errorf("loaded %+v, saved %+v", got, item)
+	}
+}
+
+func TestLoadChunkRejectsOtherVersions(t *testing.T) {
+	store, err := NewWorldStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	center := rl.NewVector2(16, -16)
+	if err := store.SaveChunk(center, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	path := store.chunkPath(center)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the version follows the magic
+	binary.LittleEndian.PutUint16(data[len(chunkFileMagic):], chunkFileVersion+1)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.LoadChunk(center); !errors.Is(err, ErrInvalidChunkFile) {
+		t.Errorf("got %v, want %v", err, ErrInvalidChunkFile)
+	}
+}
+
+func TestChunkSavedOnlyWhenEntitiesChange(t *testing.T) {
+	store, err := NewWorldStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWorld(testChunkSize, 7, store, testTerrain())
+	chunk := w.CurrentChunk()
+	if len(chunk.entities) == 0 {
+		t.Fatal("the chunk has no mobs")
+	}
+	saved := func() bool {
+		if err := w.Chunks.Save(); err != nil {
+			t.Fatal(err)
+		}
+		save, err := store.LoadChunk(chunk.center)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if save != nil {
+			// start over for the next check
+			if err := os.Remove(store.chunkPath(chunk.center)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return save != nil
+	}
+
+	if saved() {
+		t.Error("a chunk nobody touched was saved")
+	}
+	for _, e := range chunk.entities {
+		e.Behaviours = nil
+	}
+	w.tickEntities(testDT)
+	// without behaviours the mobs stand still on the ground
+	if saved() {
+		t.Error("a chunk whose entities stood still was saved")
+	}
+	chunk.entities[0].Velocity.X = 1
+	w.tickEntities(testDT)
+	if !saved() {
+		t.Error("a chunk whose entity moved was not saved")
+	}
+	if saved() {
+		t.Error("the chunk was saved again without a change")
+	}
+}
@@ -0,0 +1,79 @@
+package gocraft
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// blockColors are used where a block is drawn without its texture.
+var blockColors = map[BlockType]rl.Color{
+	BlockTypeDirt:  rl.NewColor(134, 96, 67, 255),
+	BlockTypeGras:  rl.NewColor(95, 159, 53, 255),
+	BlockTypeSnow:  rl.NewColor(240, 251, 251, 255),
+	BlockTypeRock:  rl.NewColor(125, 125, 125, 255),
+	BlockTypeGroud: rl.NewColor(89, 61, 41, 255),
+}
+
+// modelPart is a colored box of an entity model. Offset is the center of
+// the box relative to the feet of the entity, facing along +x.
+type modelPart struct {
+	Offset rl.Vector3
+	Size   rl.Vector3
+	Color  rl.Color
+}
+
+var (
+	mobModel = []modelPart{
+		{Offset: rl.NewVector3(0, 0.45, 0), Size: rl.NewVector3(0.9, 0.5, 0.6), Color: rl.Pink},
+		{Offset: rl.NewVector3(0.5, 0.65, 0), Size: rl.NewVector3(0.4, 0.4, 0.4), Color: rl.Pink},
+		{Offset: rl.NewVector3(0.72, 0.6, 0), Size: rl.NewVector3(0.06, 0.15, 0.2), Color: rl.Maroon},
+		{Offset: rl.NewVector3(0.3, 0.1, 0.2), Size: rl.NewVector3(0.2, 0.2, 0.2), Color: rl.Pink},
+		{Offset: rl.NewVector3(0.3, 0.1, -0.2), Size: rl.NewVector3(0.2, 0.2, 0.2), Color: rl.Pink},
+		{Offset: rl.NewVector3(-0.3, 0.1, 0.2), Size: rl.NewVector3(0.2, 0.2, 0.2), Color: rl.Pink},
+		{Offset: rl.NewVector3(-0.3, 0.1, -0.2), Size: rl.NewVector3(0.2, 0.2, 0.2), Color: rl.Pink},
+	}
+	playerModel = []modelPart{
+		{Offset: rl.NewVector3(0, 0.375, 0.1), Size: rl.NewVector3(0.25, 0.75, 0.2), Color: rl.DarkBlue},
+		{Offset: rl.NewVector3(0, 0.375, -0.1), Size: rl.NewVector3(0.25, 0.75, 0.2), Color: rl.DarkBlue},
+		{Offset: rl.NewVector3(0, 1.125, 0), Size: rl.NewVector3(0.25, 0.75, 0.5), Color: rl.SkyBlue},
+		{Offset: rl.NewVector3(0, 1.125, 0.35), Size: rl.NewVector3(0.2, 0.7, 0.2), Color: rl.Beige},
+		{Offset: rl.NewVector3(0, 1.125, -0.35), Size: rl.NewVector3(0.2, 0.7, 0.2), Color: rl.Beige},
+		{Offset: rl.NewVector3(0, 1.6, 0), Size: rl.NewVector3(0.4, 0.4, 0.4), Color: rl.Beige},
+	}
+)
+
+// drawModel draws the parts at the feet position rotated by yaw degrees.
+func drawModel(r Renderer, parts []modelPart, feet rl.Vector3, yaw float32) {
+	// yaw turns from +x towards +z, which is a negative rotation around y
+	model := rl.MatrixMultiply(rl.MatrixRotate(rl.NewVector3(0, 1, 0), radians(-yaw)), rl.MatrixTranslate(feet.X, feet.Y, feet.Z))
+	for _, part := range parts {
+		r.DrawCube(rl.MatrixMultiply(
+			rl.MatrixMultiply(
+				rl.MatrixScale(part.Size.X, part.Size.Y, part.Size.Z),
+				rl.MatrixTranslate(part.Offset.X, part.Offset.Y, part.Offset.Z),
+			),
+			model,
+		), part.Color)
+	}
+}
+
+// RenderEntity draws an entity with its simple model.
+func RenderEntity(r Renderer, e *Entity, alpha float32) {
+	pos := e.InterpolatedPosition(alpha)
+	switch e.Kind {
+	case EntityKindItem:
+		// items bob up and down while they lie around
+		pos.Y += 0.1 + 0.05*sin32(e.Age*3)
+		drawModel(r, []modelPart{{
+			Offset: rl.NewVector3(0, itemSize/2, 0),
+			Size:   rl.NewVector3(itemSize, itemSize, itemSize),
+			Color:  blockColors[e.Item],
+		}}, pos, e.Yaw)
+	case EntityKindMob:
+		drawModel(r, mobModel, pos, e.Yaw)
+	}
+}
+
+// RenderRemotePlayer draws another player of a server.
+func RenderRemotePlayer(r Renderer, p *RemotePlayer) {
+	drawModel(r, playerModel, p.Position, p.Yaw)
+}
@@ -0,0 +1,521 @@
+package gocraft
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/urfave/cli/v2"
+)
+
+var ErrInvalidRegion = errors.New("invalid region")
+
+// BlockBox is a box of blocks in world coordinates, Max is not part of
+// it.
+type BlockBox struct {
+	Min, Max [3]int
+}
+
+// ParseBlockBox reads a box from two corners like "-32,0,-32" and
+// "32,64,32", the order of the corners does not matter.
+func ParseBlockBox(from, to string) (BlockBox, error) {
+	a, err := parseBlockPos(from)
+	if err != nil {
+		return BlockBox{}, err
+	}
+	b, err := parseBlockPos(to)
+	if err != nil {
+		return BlockBox{}, err
+	}
+	var box BlockBox
+	for i := range box.Min {
+		box.Min[i], box.Max[i] = minInt(a[i], b[i]), maxInt(a[i], b[i])
+	}
+	if box.Empty() {
+		return box, fmt.Errorf("%w: %s to %s is empty", ErrInvalidRegion, from, to)
+	}
+	return box, nil
+}
+
+func parseBlockPos(s string) ([3]int, error) {
+	var pos [3]int
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return pos, fmt.Errorf("%w: %q is not a position like x,y,z", ErrInvalidRegion, s)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return pos, fmt.Errorf("%w: %q is not a position like x,y,z", ErrInvalidRegion, s)
+		}
+		pos[i] = n
+	}
+	return pos, nil
+}
+
+// Empty reports whether the box has no blocks.
+func (b BlockBox) Empty() bool {
+	return b.Min[0] >= b.Max[0] || b.Min[1] >= b.Max[1] || b.Min[2] >= b.Max[2]
+}
+
+func (b BlockBox) size(axis int) int {
+	return b.Max[axis] - b.Min[axis]
+}
+
+// blockFace is the part of a block texture a face uses. The textures
+// are strips of the top, side and bottom tile.
+type blockFace int
+
+const (
+	blockFaceTop blockFace = iota
+	blockFaceSide
+	blockFaceBottom
+)
+
+var blockFaceNames = [...]string{"top", "side", "bottom"}
+
+// blockTile cuts the tile of the face out of a block texture. Textures
+// that are not a strip of three tiles are used whole.
+func blockTile(img image.Image, face blockFace) image.Image {
+	b := img.Bounds()
+	if b.Dx() != 3*b.Dy() {
+		return img
+	}
+	tile := image.NewRGBA(image.Rect(0, 0, b.Dy(), b.Dy()))
+	x := b.Min.X + int(face)*b.Dy()
+	for y := 0; y < b.Dy(); y++ {
+		for i := 0; i < b.Dy(); i++ {
+			tile.Set(i, y, img.At(x+i, b.Min.Y+y))
+		}
+	}
+	return tile
+}
+
+// exportQuad is a face of one or more blocks. The corners go counter
+// clockwise seen from outside, the texture repeats once per block.
+type exportQuad struct {
+	Corners [4]rl.Vector3
+	Normal  rl.Vector3
+	// Texcoords have their origin at the bottom left like in obj files.
+	Texcoords [4]rl.Vector2
+}
+
+// exportGroup are the faces of one material.
+type exportGroup struct {
+	Block BlockType
+	Face  blockFace
+	Quads []exportQuad
+}
+
+// Name is the material name, e.g. gras_top.
+func (g *exportGroup) Name() string {
+	return g.Block.String() + "_" + blockFaceNames[g.Face]
+}
+
+// ExportMesh is the surface of a region of the world. Only faces between
+// a block and air are kept and neighbouring faces of the same material
+// are merged.
+type ExportMesh struct {
+	Box    BlockBox
+	groups []*exportGroup
+}
+
+// Triangles is the number of triangles of the mesh.
+func (m *ExportMesh) Triangles() int {
+	n := 0
+	for _, g := range m.groups {
+		n += 2 * len(g.Quads)
+	}
+	return n
+}
+
+// exportDirection is the normal of a face and the directions its texture
+// goes, right and up seen from outside.
+type exportDirection struct {
+	axis, sign int
+	face       blockFace
+	normal     rl.Vector3
+	right, up  rl.Vector3
+}
+
+var exportDirections = []exportDirection{
+	{axis: 0, sign: 1, face: blockFaceSide, normal: rl.NewVector3(1, 0, 0), right: rl.NewVector3(0, 0, -1), up: rl.NewVector3(0, 1, 0)},
+	{axis: 0, sign: -1, face: blockFaceSide, normal: rl.NewVector3(-1, 0, 0), right: rl.NewVector3(0, 0, 1), up: rl.NewVector3(0, 1, 0)},
+	{axis: 1, sign: 1, face: blockFaceTop, normal: rl.NewVector3(0, 1, 0), right: rl.NewVector3(1, 0, 0), up: rl.NewVector3(0, 0, -1)},
+	{axis: 1, sign: -1, face: blockFaceBottom, normal: rl.NewVector3(0, -1, 0), right: rl.NewVector3(1, 0, 0), up: rl.NewVector3(0, 0, 1)},
+	{axis: 2, sign: 1, face: blockFaceSide, normal: rl.NewVector3(0, 0, 1), right: rl.NewVector3(1, 0, 0), up: rl.NewVector3(0, 1, 0)},
+	{axis: 2, sign: -1, face: blockFaceSide, normal: rl.NewVector3(0, 0, -1), right: rl.NewVector3(-1, 0, 0), up: rl.NewVector3(0, 1, 0)},
+}
+
+// ExportRegion builds the surface of the blocks in box. Chunks of the box
+// that are not loaded are generated, blocks outside of the box count as
+// air so the surface is closed.
+func ExportRegion(chunks *ChunkManager, box BlockBox) *ExportMesh {
+	box.Min[1] = maxInt(box.Min[1], 0)
+	box.Max[1] = minInt(box.Max[1], int(chunks.height))
+	m := &ExportMesh{Box: box}
+	if box.Empty() {
+		return m
+	}
+	size := chunks.Size()
+	for x := box.Min[0]; x < box.Max[0]+int(size); x += int(size) {
+		for z := box.Min[2]; z < box.Max[2]+int(size); z += int(size) {
+			chunks.GetChunk(chunks.chunkCenter(minInt(x, box.Max[0]-1), minInt(z, box.Max[2]-1)), rl.White)
+		}
+	}
+
+	// the block types of the box, -1 is air
+	var (
+		sx, sy, sz = box.size(0), box.size(1), box.size(2)
+		types      = make([]int, sx*sy*sz)
+	)
+	for x := 0; x < sx; x++ {
+		for y := 0; y < sy; y++ {
+			for z := 0; z < sz; z++ {
+				types[(x*sy+y)*sz+z] = -1
+				if b := chunks.BlockAt(box.Min[0]+x, box.Min[1]+y, box.Min[2]+z); b != nil && !b.carved {
+					types[(x*sy+y)*sz+z] = int(b.blockType)
+				}
+			}
+		}
+	}
+	typeAt := func(p [3]int) int {
+		if p[0] < 0 || p[1] < 0 || p[2] < 0 || p[0] >= sx || p[1] >= sy || p[2] >= sz {
+			return -1
+		}
+		return types[(p[0]*sy+p[1])*sz+p[2]]
+	}
+
+	groups := make(map[[2]int]*exportGroup)
+	for _, d := range exportDirections {
+		var (
+			u, v  = (d.axis + 1) % 3, (d.axis + 2) % 3
+			su    = box.size(u)
+			sv    = box.size(v)
+			mask  = make([]int, su*sv)
+			group = func(typ int) *exportGroup {
+				key := [2]int{typ, int(d.face)}
+				g, ok := groups[key]
+				if !ok {
+					g = &exportGroup{Block: BlockType(typ), Face: d.face}
+					groups[key] = g
+				}
+				return g
+			}
+		)
+		for c := 0; c < box.size(d.axis); c++ {
+			// mask holds the type+1 of every visible face of the slice
+			for j := 0; j < sv; j++ {
+				for i := 0; i < su; i++ {
+					var p [3]int
+					p[d.axis], p[u], p[v] = c, i, j
+					mask[j*su+i] = 0
+					typ := typeAt(p)
+					if typ < 0 {
+						continue
+					}
+					p[d.axis] += d.sign
+					if typeAt(p) < 0 {
+						mask[j*su+i] = typ + 1
+					}
+				}
+			}
+			// merge faces greedily into rectangles, first along u
+			for j := 0; j < sv; j++ {
+				for i := 0; i < su; {
+					k := mask[j*su+i]
+					if k == 0 {
+						i++
+						continue
+					}
+					w := 1
+					for i+w < su && mask[j*su+i+w] == k {
+						w++
+					}
+					h := 1
+				grow:
+					for j+h < sv {
+						for n := 0; n < w; n++ {
+							if mask[(j+h)*su+i+n] != k {
+								break grow
+							}
+						}
+						h++
+					}
+					for y := 0; y < h; y++ {
+						for n := 0; n < w; n++ {
+							mask[(j+y)*su+i+n] = 0
+						}
+					}
+					g := group(k - 1)
+					g.Quads = append(g.Quads, m.quad(d, c, u, v, i, j, w, h))
+					i += w
+				}
+			}
+		}
+	}
+
+	for _, g := range groups {
+		m.groups = append(m.groups, g)
+	}
+	sort.Slice(m.groups, func(i, j int) bool {
+		a, b := m.groups[i], m.groups[j]
+		return a.Block < b.Block || a.Block == b.Block && a.Face < b.Face
+	})
+	return m
+}
+
+// quad is the face in direction d of the w by h blocks at i, j in slice
+// c of the box.
+func (m *ExportMesh) quad(d exportDirection, c, u, v, i, j, w, h int) exportQuad {
+	// a block at x, y, z fills x-0.5..x+0.5, y..y+1 and z-0.5..z+0.5
+	var lo, hi [3]float32
+	lo[d.axis] = float32(m.Box.Min[d.axis] + c)
+	if d.sign > 0 {
+		lo[d.axis]++
+	}
+	hi[d.axis] = lo[d.axis]
+	lo[u], hi[u] = float32(m.Box.Min[u]+i), float32(m.Box.Min[u]+i+w)
+	lo[v], hi[v] = float32(m.Box.Min[v]+j), float32(m.Box.Min[v]+j+h)
+	for _, axis := range []int{0, 2} {
+		lo[axis] -= 0.5
+		hi[axis] -= 0.5
+	}
+
+	var corners [4]rl.Vector3
+	n := 0
+	for _, a := range [2]float32{lo[u], hi[u]} {
+		for _, b := range [2]float32{lo[v], hi[v]} {
+			var p [3]float32
+			p[d.axis], p[u], p[v] = lo[d.axis], a, b
+			corners[n] = rl.NewVector3(p[0], p[1], p[2])
+			n++
+		}
+	}
+	// order the corners by the texture directions: bottom left, bottom
+	// right, top right, top left
+	q := exportQuad{Normal: d.normal}
+	origin := corners[0]
+	for _, p := range corners[1:] {
+		if rl.Vector3DotProduct(p, d.right)+rl.Vector3DotProduct(p, d.up) < rl.Vector3DotProduct(origin, d.right)+rl.Vector3DotProduct(origin, d.up) {
+			origin = p
+		}
+	}
+	for _, p := range corners {
+		t := rl.NewVector2(
+			rl.Vector3DotProduct(rl.Vector3Subtract(p, origin), d.right),
+			rl.Vector3DotProduct(rl.Vector3Subtract(p, origin), d.up),
+		)
+		var k int
+		switch {
+		case t.X == 0 && t.Y == 0:
+			k = 0
+		case t.Y == 0:
+			k = 1
+		case t.X == 0:
+			k = 3
+		default:
+			k = 2
+		}
+		q.Corners[k] = p
+		q.Texcoords[k] = t
+	}
+	return q
+}
+
+// WriteOBJ writes the mesh as obj file using the materials of the mtl
+// file, see WriteMTL. The faces of each material are an object of their
+// own.
+func (m *ExportMesh) WriteOBJ(w io.Writer, mtl string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# gocraft region %v to %v\n", m.Box.Min, m.Box.Max)
+	fmt.Fprintf(bw, "mtllib %s\n", mtl)
+	// positions and texture coordinates are numbered together, there is
+	// one normal per face
+	vertex, normal := 1, 1
+	for _, g := range m.groups {
+		fmt.Fprintf(bw, "o %s\nusemtl %s\n", g.Name(), g.Name())
+		for _, q := range g.Quads {
+			for k, p := range q.Corners {
+				fmt.Fprintf(bw, "v %g %g %g\n", p.X, p.Y, p.Z)
+				fmt.Fprintf(bw, "vt %g %g\n", q.Texcoords[k].X, q.Texcoords[k].Y)
+			}
+			fmt.Fprintf(bw, "vn %g %g %g\n", q.Normal.X, q.Normal.Y, q.Normal.Z)
+			fmt.Fprintf(bw, "f %[1]d/%[1]d/%[5]d %[2]d/%[2]d/%[5]d %[3]d/%[3]d/%[5]d %[4]d/%[4]d/%[5]d\n",
+				vertex, vertex+1, vertex+2, vertex+3, normal)
+			vertex += 4
+			normal++
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteMTL writes the materials of the mesh. Their textures are
+// expected as png files named like the materials in textureDir.
+func (m *ExportMesh) WriteMTL(w io.Writer, textureDir string) error {
+	bw := bufio.NewWriter(w)
+	for _, g := range m.groups {
+		fmt.Fprintf(bw, "newmtl %s\n", g.Name())
+		fmt.Fprintf(bw, "Ka 1 1 1\nKd 1 1 1\nKs 0 0 0\nd 1\nillum 1\n")
+		fmt.Fprintf(bw, "map_Kd %s\n\n", path.Join(textureDir, textureFile(g)))
+	}
+	return bw.Flush()
+}
+
+// Textures returns the texture tile of every material by material name,
+// cut from the block textures.
+func (m *ExportMesh) Textures(blocks map[BlockType]image.Image) map[string]image.Image {
+	tiles := make(map[string]image.Image)
+	for _, g := range m.groups {
+		img, ok := blocks[g.Block]
+		if !ok {
+			img = placeholderImage()
+		}
+		tiles[g.Name()] = blockTile(img, g.Face)
+	}
+	return tiles
+}
+
+// textureFile is the file name of the texture of a material.
+func textureFile(g *exportGroup) string {
+	return g.Name() + ".png"
+}
+
+// RunExport is the action of the export command. It writes the surface
+// of a box of a saved world, or of a new world of the seed, as obj with
+// mtl and png textures or as glb with the textures embedded, picked by
+// the extension of --out. The written files are read back and checked.
+func RunExport(ctx *cli.Context) error {
+	config, _, err := ResolveConfig(ctx)
+	if err != nil {
+		return err
+	}
+	box, err := ParseBlockBox(ctx.String("from"), ctx.String("to"))
+	if err != nil {
+		return err
+	}
+	out := ctx.String("out")
+	ext := strings.ToLower(filepath.Ext(out))
+	if ext != ".obj" && ext != ".glb" {
+		return fmt.Errorf("%s: export writes .obj or .glb files", out)
+	}
+
+	// the textures can come from resource packs
+	resources, err := NewResources(config.ResourcePacks)
+	if err != nil {
+		return err
+	}
+	defer resources.Close()
+	defs, err := resources.LoadBlockDefinitions()
+	if err != nil {
+		return err
+	}
+	blocks := make(map[BlockType]image.Image)
+	for _, def := range defs {
+		img, err := resources.LoadTextureImage(def.Texture)
+		if err != nil {
+			rl.TraceLog(rl.LogWarning, "block %s: %s", def.Type, err.Error())
+		}
+		blocks[def.Type] = img
+	}
+
+	world, err := openWorldForExport(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	mesh := ExportRegion(world.Chunks, box)
+	if mesh.Triangles() == 0 {
+		return fmt.Errorf("%w: %v to %v only holds air", ErrInvalidRegion, box.Min, box.Max)
+	}
+	textures := mesh.Textures(blocks)
+	var triangles int
+	if ext == ".obj" {
+		triangles, err = exportOBJ(mesh, textures, out)
+	} else {
+		triangles, err = exportGLB(mesh, textures, out)
+	}
+	if err != nil {
+		return err
+	}
+	if triangles != mesh.Triangles() {
+		return fmt.Errorf("%s: read back %d triangles, wrote %d", out, triangles, mesh.Triangles())
+	}
+	fmt.Fprintf(ctx.App.Writer, "wrote %s (seed %d, %v to %v, %d triangles, %d materials)\n",
+		out, world.Chunks.Seed(), mesh.Box.Min, mesh.Box.Max, triangles, len(textures))
+	return nil
+}
+
+// exportOBJ writes the obj file, its mtl file next to it and the
+// textures into a directory named after it. It returns the triangles
+// of the obj file read back.
+func exportOBJ(mesh *ExportMesh, textures map[string]image.Image, out string) (int, error) {
+	var (
+		base     = strings.TrimSuffix(out, filepath.Ext(out))
+		mtl      = base + ".mtl"
+		texDir   = base + "_textures"
+		obj, lib strings.Builder
+	)
+	if err := os.MkdirAll(texDir, 0o755); err != nil {
+		return 0, err
+	}
+	for _, name := range sortedNames(textures) {
+		if err := writePNG(filepath.Join(texDir, name+".png"), textures[name]); err != nil {
+			return 0, err
+		}
+	}
+	if err := mesh.WriteMTL(&lib, filepath.Base(texDir)); err != nil {
+		return 0, err
+	}
+	if err := writeFileAtomic(mtl, []byte(lib.String())); err != nil {
+		return 0, err
+	}
+	if err := mesh.WriteOBJ(&obj, filepath.Base(mtl)); err != nil {
+		return 0, err
+	}
+	if err := writeFileAtomic(out, []byte(obj.String())); err != nil {
+		return 0, err
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	data, err := ParseOBJ(f)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", out, err)
+	}
+	return data.Triangles(), nil
+}
+
+// exportGLB writes the glb file and returns its triangles read back.
+func exportGLB(mesh *ExportMesh, textures map[string]image.Image, out string) (int, error) {
+	var buf bytes.Buffer
+	if err := mesh.WriteGLB(&buf, textures); err != nil {
+		return 0, err
+	}
+	if err := writeFileAtomic(out, buf.Bytes()); err != nil {
+		return 0, err
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	triangles, err := ValidateGLB(f)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", out, err)
+	}
+	return triangles, nil
+}
@@ -0,0 +1,142 @@
+package gocraft
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// exportWorld is an empty chunk of 16 blocks around x 0, z 0.
+func exportWorld() *ChunkManager {
+	cm := NewRemoteChunkManager(navChunkSize)
+	chunk := NewChunk(navChunkSize, navChunkSize, navChunkSize)
+	chunk.center = cm.chunkCenter(0, 0)
+	cm.insertChunk(chunk)
+	return cm
+}
+
+func TestExportRegion(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(t *testing.T, cm *ChunkManager)
+		box   BlockBox
+		// quads after merging, each is two triangles
+		quads int
+	}{
+		{
+			// one quad for each side of the slab, 12 triangles
+			name: "flat 4x4 slab",
+			build: func(t *testing.T, cm *ChunkManager) {
+				setBlocks(t, cm, [3]int{-2, 1, -2}, [3]int{1, 1, 1})
+			},
+			box:   BlockBox{Min: [3]int{-4, 0, -4}, Max: [3]int{4, 4, 4}},
+			quads: 6,
+		},
+		{
+			// the sides of both layers are apart, they are different
+			// materials
+			name: "gras on dirt",
+			build: func(t *testing.T, cm *ChunkManager) {
+				setBlocks(t, cm, [3]int{-2, 1, -2}, [3]int{1, 1, 1})
+				for x := -2; x <= 1; x++ {
+					for z := -2; z <= 1; z++ {
+						if err := cm.SetBlock(x, 2, z, BlockTypeGras); err != nil {
+							t.Fatal(err)
+						}
+					}
+				}
+			},
+			box:   BlockBox{Min: [3]int{-4, 0, -4}, Max: [3]int{4, 4, 4}},
+			quads: 10,
+		},
+		{
+			// the blocks outside of the box are air, the cut is closed
+			name: "box cuts the slab",
+			build: func(t *testing.T, cm *ChunkManager) {
+				setBlocks(t, cm, [3]int{-2, 1, -2}, [3]int{1, 1, 1})
+			},
+			box:   BlockBox{Min: [3]int{-4, 0, -4}, Max: [3]int{0, 4, 4}},
+			quads: 6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := exportWorld()
+			tt.build(t, cm)
+			mesh := ExportRegion(cm, tt.box)
+			if got := mesh.Triangles(); got != 2*tt.quads {
+				t.Fatalf("%d triangles, want %d", got, 2*tt.quads)
+			}
+
+			for _, g := range mesh.groups {
+				for _, q := range g.Quads {
+					c := q.Corners
+					cross := rl.Vector3CrossProduct(rl.Vector3Subtract(c[1], c[0]), rl.Vector3Subtract(c[2], c[0]))
+					if rl.Vector3DotProduct(cross, q.Normal) <= 0 {
+						t.Errorf("%s quad %v turns away from its normal %v", g.Name(), c, q.Normal)
+					}
+					// the texture repeats once per block
+					w := rl.Vector3Length(rl.Vector3Subtract(c[1], c[0]))
+					h := rl.Vector3Length(rl.Vector3Subtract(c[3], c[0]))
+					want := [4]rl.Vector2{{X: 0, Y: 0}, {X: w, Y: 0}, {X: w, Y: h}, {X: 0, Y: h}}
+					if q.Texcoords != want {
+						t.Errorf("%s quad %v has texcoords %v, want %v", g.Name(), c, q.Texcoords, want)
+					}
+				}
+			}
+
+			dir := t.TempDir()
+			textures := mesh.Textures(nil)
+			obj := filepath.Join(dir, "region.obj")
+			if _, err := exportOBJ(mesh, textures, obj); err != nil {
+				t.Fatal(err)
+			}
+			f, err := os.Open(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			data, err := ParseOBJ(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := data.Triangles(); got != 2*tt.quads {
+				t.Errorf("obj has %d triangles, want %d", got, 2*tt.quads)
+			}
+			for i := 0; i+2 < len(data.Positions); i += 3 {
+				p := data.Positions[i : i+3]
+				cross := rl.Vector3CrossProduct(rl.Vector3Subtract(p[1], p[0]), rl.Vector3Subtract(p[2], p[0]))
+				if rl.Vector3DotProduct(cross, data.Normals[i]) <= 0 {
+					t.Errorf("obj triangle %v turns away from its normal %v", p, data.Normals[i])
+				}
+			}
+			for _, uv := range data.Texcoords {
+				// ParseOBJ moves the origin to the top left
+				u, v := float64(uv.X), float64(1-uv.Y)
+				if u < 0 || v < 0 || u > 4 || v > 4 || u != math.Round(u) || v != math.Round(v) {
+					t.Errorf("obj texcoord %v is not a block corner of the slab", uv)
+				}
+			}
+
+			glb := filepath.Join(dir, "region.glb")
+			if _, err := exportGLB(mesh, textures, glb); err != nil {
+				t.Fatal(err)
+			}
+			g, err := os.Open(glb)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer g.Close()
+			triangles, err := ValidateGLB(g)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if triangles != 2*tt.quads {
+				t.Errorf("glb has %d triangles, want %d", triangles, 2*tt.quads)
+			}
+		})
+	}
+}
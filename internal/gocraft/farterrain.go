@@ -0,0 +1,174 @@
+package gocraft
+
+import (
+	"math"
+	"sort"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	fastnoise "github.com/tinogoehlert/gocraft/pkg/go-fastnoiselite"
+)
+
+// farTilesPerFrame limits how many tiles are built in one frame, the
+// noise is sampled on the render thread.
+const farTilesPerFrame = 2
+
+// farMaterials are plain colored block materials for the far terrain,
+// textures would only flicker at that distance.
+var farMaterials = make(map[BlockType]Material)
+
+// farStep is the width in blocks of the columns a tile is built of,
+// tiles lose detail with their distance to the player in chunks.
+func farStep(ring int) int {
+	switch {
+	case ring <= 2:
+		return 2
+	case ring <= 4:
+		return 4
+	}
+	return 8
+}
+
+// farTile is the far terrain in place of one chunk.
+type farTile struct {
+	center    rl.Vector2
+	step      int
+	box       AABB
+	instances map[BlockType][]rl.Matrix
+}
+
+// FarTerrain draws the terrain around the loaded chunks. It is built
+// straight from the terrain noise as columns of step × step blocks, so
+// there is no voxel data and no caves or edits. Columns reach down to
+// the bottom of the world, so tiles of different detail and the loaded
+// chunks next to them leave no gaps.
+type FarTerrain struct {
+	// Distance in chunks around the player, 0 draws nothing.
+	Distance int
+
+	chunks *ChunkManager
+	noise  *fastnoise.NoiseState
+	tiles  map[rl.Vector2]*farTile
+}
+
+func NewFarTerrain(chunks *ChunkManager) *FarTerrain {
+	return &FarTerrain{
+		chunks: chunks,
+		noise:  chunks.newTerrainNoise(),
+		tiles:  make(map[rl.Vector2]*farTile),
+	}
+}
+
+// Update builds the missing tiles around pos, nearest first, and drops
+// those out of distance. A tile that needs another step keeps being
+// drawn until its replacement is built.
+func (f *FarTerrain) Update(pos rl.Vector3) {
+	var (
+		size    = f.chunks.Size()
+		x, _, z = blockCoord(pos)
+		center  = f.chunks.chunkCenter(x, z)
+		wanted  = make(map[rl.Vector2]int)
+	)
+	ring := func(c rl.Vector2) int {
+		dx := math.Abs(float64(c.X-center.X)) / float64(size)
+		dz := math.Abs(float64(c.Y-center.Y)) / float64(size)
+		return int(math.Round(math.Max(dx, dz)))
+	}
+	if f.Distance > 0 {
+		for _, c := range f.chunks.centersAround(pos, f.Distance) {
+			wanted[c] = farStep(ring(c))
+		}
+	}
+	for c := range f.tiles {
+		if _, ok := wanted[c]; !ok {
+			delete(f.tiles, c)
+		}
+	}
+
+	var missing []rl.Vector2
+	for c, step := range wanted {
+		if t, ok := f.tiles[c]; !ok || t.step != step {
+			missing = append(missing, c)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return ring(missing[i]) < ring(missing[j]) })
+	for i, c := range missing {
+		if i == farTilesPerFrame {
+			break
+		}
+		f.tiles[c] = f.buildTile(c, wanted[c])
+	}
+}
+
+// buildTile samples the terrain height once per column.
+func (f *FarTerrain) buildTile(center rl.Vector2, step int) *farTile {
+	var (
+		size    = int(f.chunks.Size())
+		terrain = f.chunks.Terrain()
+		// the world position of the first block of the chunk, see
+		// Chunk.worldPosition
+		x0 = -center.X - float32(size/2)
+		z0 = -center.Y - float32(size/2)
+		t  = &farTile{
+			center:    center,
+			step:      step,
+			instances: make(map[BlockType][]rl.Matrix),
+		}
+		top float32 = 1
+	)
+	for i := 0; i < size; i += step {
+		for j := 0; j < size; j += step {
+			// the middle block of the column, its outline is centered on
+			// the blocks it replaces
+			var (
+				bx = x0 + float32(i+step/2)
+				bz = z0 + float32(j+step/2)
+				h  = terrainHeight(f.noise, terrain, bx, bz)
+				cx = x0 + float32(i) - 0.5 + float32(step)/2
+				cz = z0 + float32(j) - 0.5 + float32(step)/2
+			)
+			// there is always the ground block
+			if h < 1 {
+				h = 1
+			}
+			typ := BlockTypeGras
+			switch {
+			case h-1 > snowLine:
+				typ = BlockTypeSnow
+			case h == 1:
+				typ = BlockTypeGroud
+			}
+			t.instances[typ] = append(t.instances[typ], rl.MatrixMultiply(
+				rl.MatrixScale(float32(step), h, float32(step)),
+				rl.MatrixTranslate(cx, 0, cz),
+			))
+			if h > top {
+				top = h
+			}
+		}
+	}
+	t.box = AABB{
+		Min: rl.NewVector3(x0-0.5, 0, z0-0.5),
+		Max: rl.NewVector3(x0-0.5+float32(size), top, z0-0.5+float32(size)),
+	}
+	return t
+}
+
+// Draw draws the tiles in the frustum that are not covered by one of
+// the loaded chunks.
+func (f *FarTerrain) Draw(r Renderer, block Mesh, frustum Frustum, loaded []*Chunk, stats *RenderStats) {
+	covered := make(map[rl.Vector2]bool, len(loaded))
+	for _, c := range loaded {
+		covered[c.center] = true
+	}
+	for c, t := range f.tiles {
+		if covered[c] || !frustum.IntersectsBox(t.box) {
+			continue
+		}
+		stats.FarTiles++
+		for typ, transforms := range t.instances {
+			stats.DrawCalls++
+			stats.Instances += len(transforms)
+			r.DrawMeshInstanced(block, farMaterials[typ], transforms)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package gocraft
+
+import (
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// the clip distances BeginMode3D uses, see RL_CULL_DISTANCE_NEAR and
+// RL_CULL_DISTANCE_FAR
+const (
+	cameraNear float32 = 0.01
+	cameraFar  float32 = 1000
+)
+
+// Plane is the set of points p with Normal·p + D = 0. Points on the side
+// the normal points to have a positive distance.
+type Plane struct {
+	Normal rl.Vector3
+	D      float32
+}
+
+// newPlane returns the plane through point with the given normal.
+func newPlane(normal, point rl.Vector3) Plane {
+	normal = rl.Vector3Normalize(normal)
+	return Plane{Normal: normal, D: -rl.Vector3DotProduct(normal, point)}
+}
+
+// Distance is the signed distance of p to the plane.
+func (p Plane) Distance(v rl.Vector3) float32 {
+	return rl.Vector3DotProduct(p.Normal, v) + p.D
+}
+
+// Frustum is the volume a perspective camera sees, bounded by planes
+// whose normals point inside.
+type Frustum [6]Plane
+
+// NewFrustum builds the frustum of a perspective camera drawn with the
+// given aspect ratio (width / height).
+func NewFrustum(cam rl.Camera3D, aspect float32) Frustum {
+	var (
+		front = rl.Vector3Normalize(rl.Vector3Subtract(cam.Target, cam.Position))
+		right = rl.Vector3Normalize(rl.Vector3CrossProduct(front, cam.Up))
+		up    = rl.Vector3CrossProduct(right, front)
+		tanV  = float32(math.Tan(float64(radians(cam.Fovy) / 2)))
+		tanH  = tanV * aspect
+		pos   = cam.Position
+	)
+	return Frustum{
+		newPlane(front, rl.Vector3Add(pos, rl.Vector3Scale(front, cameraNear))),
+		newPlane(rl.Vector3Negate(front), rl.Vector3Add(pos, rl.Vector3Scale(front, cameraFar))),
+		// the side planes go through the camera, their normals lean
+		// towards the view direction by the half angle
+		newPlane(rl.Vector3Add(right, rl.Vector3Scale(front, tanH)), pos),
+		newPlane(rl.Vector3Add(rl.Vector3Negate(right), rl.Vector3Scale(front, tanH)), pos),
+		newPlane(rl.Vector3Add(up, rl.Vector3Scale(front, tanV)), pos),
+		newPlane(rl.Vector3Add(rl.Vector3Negate(up), rl.Vector3Scale(front, tanV)), pos),
+	}
+}
+
+// ContainsPoint reports whether v is inside the frustum.
+func (f Frustum) ContainsPoint(v rl.Vector3) bool {
+	for _, p := range f {
+		if p.Distance(v) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IntersectsBox reports whether any part of the box may be inside the
+// frustum. Boxes near a corner of the frustum can be reported although
+// they are outside, which only costs a draw.
+func (f Frustum) IntersectsBox(box AABB) bool {
+	for _, p := range f {
+		// the corner of the box farthest along the normal
+		corner := box.Min
+		for axis := axisX; axis <= axisZ; axis++ {
+			if vecAxis(p.Normal, axis) > 0 {
+				setVecAxis(&corner, axis, vecAxis(box.Max, axis))
+			}
+		}
+		if p.Distance(corner) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// worldToScreen projects v onto a screen of the given size seen through
+// cam. It reports false for points behind the camera.
+func worldToScreen(v rl.Vector3, cam rl.Camera3D, width, height int32) (rl.Vector2, bool) {
+	var (
+		front = rl.Vector3Normalize(rl.Vector3Subtract(cam.Target, cam.Position))
+		right = rl.Vector3Normalize(rl.Vector3CrossProduct(front, cam.Up))
+		up    = rl.Vector3CrossProduct(right, front)
+		tanV  = float32(math.Tan(float64(radians(cam.Fovy) / 2)))
+		tanH  = tanV * float32(width) / float32(height)
+		d     = rl.Vector3Subtract(v, cam.Position)
+		z     = rl.Vector3DotProduct(d, front)
+	)
+	if z <= 0 {
+		return rl.Vector2{}, false
+	}
+	x := rl.Vector3DotProduct(d, right) / (z * tanH)
+	y := rl.Vector3DotProduct(d, up) / (z * tanV)
+	return rl.NewVector2((x+1)/2*float32(width), (1-y)/2*float32(height)), true
+}
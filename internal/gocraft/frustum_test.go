@@ -0,0 +1,79 @@
+package gocraft
+
+import (
+	"math"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// testFrustum looks from the origin along +x, +z is to the right.
+func testFrustum() Frustum {
+	cam := rl.Camera3D{
+		Position:   rl.NewVector3(0, 0, 0),
+		Target:     rl.NewVector3(1, 0, 0),
+		Up:         rl.NewVector3(0, 1, 0),
+		Fovy:       70,
+		Projection: rl.CameraPerspective,
+	}
+	return NewFrustum(cam, 16.0/9)
+}
+
+// cube is a box of size 2 around center.
+func cube(x, y, z float32) AABB {
+	return AABB{Min: rl.NewVector3(x-1, y-1, z-1), Max: rl.NewVector3(x+1, y+1, z+1)}
+}
+
+func TestFrustum(t *testing.T) {
+	var (
+		f = testFrustum()
+		// the half width and height of the view 10 blocks ahead
+		halfV = 10 * float32(math.Tan(float64(radians(70)/2)))
+		halfH = halfV * 16 / 9
+	)
+	tests := []struct {
+		name   string
+		center rl.Vector3
+		inside bool
+		// box is a cube around center, it differs from the point when it
+		// straddles a plane
+		box bool
+	}{
+		{"in front", rl.NewVector3(10, 0, 0), true, true},
+		{"behind", rl.NewVector3(-10, 0, 0), false, false},
+		{"left", rl.NewVector3(10, 0, -100), false, false},
+		{"right", rl.NewVector3(10, 0, 100), false, false},
+		{"above", rl.NewVector3(10, 100, 0), false, false},
+		{"below", rl.NewVector3(10, -100, 0), false, false},
+		{"beyond the far plane", rl.NewVector3(cameraFar+10, 0, 0), false, false},
+		{"inside the left edge", rl.NewVector3(10, 0, -halfH+0.5), true, true},
+		{"across the left edge", rl.NewVector3(10, 0, -halfH-0.5), false, true},
+		{"across the right edge", rl.NewVector3(10, 0, halfH+0.5), false, true},
+		{"across the top edge", rl.NewVector3(10, halfV+0.5, 0), false, true},
+		{"across the bottom edge", rl.NewVector3(10, -halfV-0.5, 0), false, true},
+		{"around the camera", rl.NewVector3(0, 0, 0), false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.ContainsPoint(tt.center); got != tt.inside {
+				t.Errorf("point %v inside %v, want %v", tt.center, got, tt.inside)
+			}
+			box := cube(tt.center.X, tt.center.Y, tt.center.Z)
+			if got := f.IntersectsBox(box); got != tt.box {
+				t.Errorf("box %v intersects %v, want %v", box, got, tt.box)
+			}
+		})
+	}
+}
+
+func TestFrustumPlanesPointInside(t *testing.T) {
+	ahead := rl.NewVector3(10, 0, 0)
+	for i, p := range testFrustum() {
+		if d := p.Distance(ahead); d <= 0 {
+			t.Errorf("plane %d has the view direction at distance %.2f", i, d)
+		}
+		if l := rl.Vector3Length(p.Normal); !near(l, 1, 1e-5) {
+			t.Errorf("plane %d has a normal of length %.4f", i, l)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package gocraft
+
+import (
+	"math"
+)
+
+// gamepad codes follow raylib's GamepadButton and GamepadAxis enums, the
+// Gamepad* constants of the go bindings are from an older raylib version.
+const (
+	gamepadDpadUp int32 = iota + 1
+	gamepadDpadRight
+	gamepadDpadDown
+	gamepadDpadLeft
+	gamepadY
+	gamepadB
+	gamepadA
+	gamepadX
+	gamepadLB
+	gamepadLT
+	gamepadRB
+	gamepadRT
+	gamepadSelect
+	gamepadHome
+	gamepadStart
+	gamepadLeftThumb
+	gamepadRightThumb
+)
+
+const (
+	gamepadAxisLeftX int32 = iota
+	gamepadAxisLeftY
+	gamepadAxisRightX
+	gamepadAxisRightY
+	gamepadAxisLeftTrigger
+	gamepadAxisRightTrigger
+)
+
+// maxGamepads is the number of pads searched for the first connected one.
+const maxGamepads = 4
+
+// GamepadSettings shape the raw stick and trigger values.
+type GamepadSettings struct {
+	// StickDeadzone is the radius around the stick center that is ignored.
+	StickDeadzone float32
+	// TriggerDeadzone is how far a trigger has to be pulled to count.
+	TriggerDeadzone float32
+	// MoveCurve and LookCurve are exponents applied to the stick
+	// deflection, values above 1 give finer control near the center.
+	MoveCurve float32
+	LookCurve float32
+	// LookSpeed is the turn rate in degrees per second at full deflection.
+	LookSpeed float32
+}
+
+func DefaultGamepadSettings() GamepadSettings {
+	return GamepadSettings{
+		StickDeadzone:   0.15,
+		TriggerDeadzone: 0.1,
+		MoveCurve:       1,
+		LookCurve:       2,
+		LookSpeed:       180,
+	}
+}
+
+func defaultGamepadBindings() map[Action][]Binding {
+	axis := func(code int32, dir float32) []Binding {
+		return []Binding{{Kind: BindingGamepadAxis, Code: code, Direction: dir}}
+	}
+	button := func(code int32) []Binding {
+		return []Binding{{Kind: BindingGamepadButton, Code: code}}
+	}
+	return map[Action][]Binding{
+		ActionMoveForward:  axis(gamepadAxisLeftY, -1),
+		ActionMoveBack:     axis(gamepadAxisLeftY, 1),
+		ActionMoveLeft:     axis(gamepadAxisLeftX, -1),
+		ActionMoveRight:    axis(gamepadAxisLeftX, 1),
+		ActionLookLeft:     axis(gamepadAxisRightX, -1),
+		ActionLookRight:    axis(gamepadAxisRightX, 1),
+		ActionLookUp:       axis(gamepadAxisRightY, -1),
+		ActionLookDown:     axis(gamepadAxisRightY, 1),
+		ActionBreak:        axis(gamepadAxisRightTrigger, 1),
+		ActionPlace:        axis(gamepadAxisLeftTrigger, 1),
+		ActionJump:         button(gamepadA),
+		ActionSneak:        button(gamepadB),
+		ActionSprint:       button(gamepadLeftThumb),
+		ActionHotbarNext:   button(gamepadRB),
+		ActionHotbarPrev:   button(gamepadLB),
+		ActionMenu:         button(gamepadStart),
+		ActionToggleFly:    button(gamepadDpadUp),
+		ActionToggleNoclip: button(gamepadDpadDown),
+		ActionCameraMode:   button(gamepadSelect),
+		ActionInventory:    button(gamepadY),
+	}
+}
+
+// selectGamepad keeps the current pad while it is connected and otherwise
+// switches to the first available one.
+func (in *Input) selectGamepad() {
+	if in.device.GamepadAvailable(in.gamepad) {
+		return
+	}
+	for pad := int32(0); pad < maxGamepads; pad++ {
+		if in.device.GamepadAvailable(pad) {
+			in.gamepad = pad
+			return
+		}
+	}
+}
+
+// gamepadAxis returns the shaped value of an axis. Sticks use a radial
+// deadzone over both of their axes, triggers are mapped from raylib's
+// -1 (released) .. 1 (pulled) to 0..1.
+func (in *Input) gamepadAxis(code int32) float32 {
+	raw := in.device.GamepadAxis(in.gamepad, code)
+
+	switch code {
+	case gamepadAxisLeftTrigger, gamepadAxisRightTrigger:
+		return deadzone((raw+1)/2, in.Gamepad.TriggerDeadzone)
+	}
+
+	var partner int32
+	curve := in.Gamepad.MoveCurve
+	switch code {
+	case gamepadAxisLeftX:
+		partner = gamepadAxisLeftY
+	case gamepadAxisLeftY:
+		partner = gamepadAxisLeftX
+	case gamepadAxisRightX:
+		partner = gamepadAxisRightY
+		curve = in.Gamepad.LookCurve
+	case gamepadAxisRightY:
+		partner = gamepadAxisRightX
+		curve = in.Gamepad.LookCurve
+	default:
+		return raw
+	}
+
+	other := in.device.GamepadAxis(in.gamepad, partner)
+	length := float32(math.Hypot(float64(raw), float64(other)))
+	if length == 0 {
+		return 0
+	}
+	shaped := deadzone(length, in.Gamepad.StickDeadzone)
+	if curve > 0 {
+		shaped = float32(math.Pow(float64(shaped), float64(curve)))
+	}
+	return raw / length * shaped
+}
+
+// deadzone rescales v so values below dz become 0 and 1 stays 1.
+func deadzone(v, dz float32) float32 {
+	if v <= dz {
+		return 0
+	}
+	if dz >= 1 {
+		return 1
+	}
+	return clamp32((v-dz)/(1-dz), 0, 1)
+}
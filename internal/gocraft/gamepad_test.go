@@ -0,0 +1,147 @@
+package gocraft
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDeadzone(t *testing.T) {
+	tests := []struct {
+		name  string
+		v, dz float32
+		want  float32
+	}{
+		{"zero", 0, 0.15, 0},
+		{"inside", 0.1, 0.15, 0},
+		{"at the edge", 0.15, 0.15, 0},
+		{"half way", 0.575, 0.15, 0.5},
+		{"full deflection", 1, 0.15, 1},
+		{"beyond full", 1.2, 0.15, 1},
+		{"no deadzone", 0.3, 0, 0.3},
+		{"everything dead", 1, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := deadzone(tt.v, tt.dz); math.Abs(float64(got-tt.want)) > 1e-6 {
+				t.Errorf("deadzone(%v, %v) = %v, want %v", tt.v, tt.dz, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGamepadAxis(t *testing.T) {
+	// the default settings: stick deadzone 0.15, trigger deadzone 0.1,
+	// linear move curve and a squared look curve
+	tests := []struct {
+		name string
+		axes map[int32]float32
+		code int32
+		want float32
+	}{
+		{"centered", nil, gamepadAxisLeftX, 0},
+		{"inside the deadzone", map[int32]float32{gamepadAxisLeftX: 0.1}, gamepadAxisLeftX, 0},
+		{"at the edge", map[int32]float32{gamepadAxisLeftX: 0.15}, gamepadAxisLeftX, 0},
+		{"half way", map[int32]float32{gamepadAxisLeftX: -0.575}, gamepadAxisLeftX, -0.5},
+		{"full deflection", map[int32]float32{gamepadAxisLeftY: -1}, gamepadAxisLeftY, -1},
+		// the deadzone is round, both axes together are outside of it
+		{"diagonal inside", map[int32]float32{gamepadAxisLeftX: 0.1, gamepadAxisLeftY: 0.1}, gamepadAxisLeftX, 0},
+		{"diagonal full", map[int32]float32{gamepadAxisLeftX: math.Sqrt2 / 2, gamepadAxisLeftY: math.Sqrt2 / 2}, gamepadAxisLeftX, math.Sqrt2 / 2},
+		{"look curve", map[int32]float32{gamepadAxisRightX: 0.575}, gamepadAxisRightX, 0.25},
+		{"look full", map[int32]float32{gamepadAxisRightY: 1}, gamepadAxisRightY, 1},
+		{"trigger released", map[int32]float32{gamepadAxisRightTrigger: -1}, gamepadAxisRightTrigger, 0},
+		{"trigger inside the deadzone", map[int32]float32{gamepadAxisRightTrigger: -0.85}, gamepadAxisRightTrigger, 0},
+		{"trigger pulled", map[int32]float32{gamepadAxisLeftTrigger: 1}, gamepadAxisLeftTrigger, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newFakeDevice()
+			d.pads[0] = true
+			d.axes[gamepadAxisLeftTrigger] = -1
+			d.axes[gamepadAxisRightTrigger] = -1
+			for code, v := range tt.axes {
+				d.axes[code] = v
+			}
+			in := newInput(d)
+			if got := in.gamepadAxis(tt.code); math.Abs(float64(got-tt.want)) > 1e-5 {
+				t.Errorf("axis %d is %v, want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGamepadActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		pads    []int32
+		axes    map[int32]float32
+		buttons []int32
+		// forward, strafe, turn and up are the axes processInput reads
+		forward, strafe, turn, up float32
+		down                      []Action
+	}{
+		{name: "no gamepad", axes: map[int32]float32{gamepadAxisLeftY: -1}},
+		{
+			name:    "forward and right",
+			pads:    []int32{0},
+			axes:    map[int32]float32{gamepadAxisLeftY: -1, gamepadAxisRightX: 1},
+			forward: 1, turn: 1,
+		},
+		{
+			name: "inside the deadzone",
+			pads: []int32{0},
+			axes: map[int32]float32{gamepadAxisLeftX: 0.1, gamepadAxisRightY: -0.1},
+		},
+		{
+			name:   "strafe and look up on the second pad",
+			pads:   []int32{2},
+			axes:   map[int32]float32{gamepadAxisLeftX: -0.575, gamepadAxisRightY: -0.575},
+			strafe: -0.5, up: 0.25,
+		},
+		{
+			name:    "triggers and buttons",
+			pads:    []int32{0},
+			axes:    map[int32]float32{gamepadAxisRightTrigger: 1, gamepadAxisLeftTrigger: 1},
+			buttons: []int32{gamepadA, gamepadRB},
+			down:    []Action{ActionBreak, ActionPlace, ActionJump, ActionHotbarNext},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newFakeDevice()
+			d.axes[gamepadAxisLeftTrigger] = -1
+			d.axes[gamepadAxisRightTrigger] = -1
+			for _, pad := range tt.pads {
+				d.pads[pad] = true
+			}
+			for code, v := range tt.axes {
+				d.axes[code] = v
+			}
+			for _, b := range tt.buttons {
+				d.buttons[b] = true
+			}
+			in := newInput(d)
+			in.Capture(true)
+			in.Update()
+
+			// the same axes the keyboard feeds, see processInput
+			got := [4]float32{
+				in.Axis(ActionMoveBack, ActionMoveForward),
+				in.Axis(ActionMoveLeft, ActionMoveRight),
+				in.Axis(ActionLookLeft, ActionLookRight),
+				in.Axis(ActionLookDown, ActionLookUp),
+			}
+			want := [4]float32{tt.forward, tt.strafe, tt.turn, tt.up}
+			for i := range got {
+				if math.Abs(float64(got[i]-want[i])) > 1e-5 {
+					t.Errorf("forward, strafe, turn, up are %v, want %v", got, want)
+					break
+				}
+			}
+			for _, a := range tt.down {
+				if !in.Down(a) {
+					t.Errorf("%s is not down", a)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,474 @@
+package gocraft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+)
+
+var ErrInvalidGLB = errors.New("invalid glb file")
+
+const (
+	glbMagic     = 0x46546c67 // glTF
+	glbVersion   = 2
+	glbChunkJSON = 0x4e4f534a // JSON
+	glbChunkBIN  = 0x004e4942 // BIN
+
+	gltfFloat         = 5126
+	gltfUnsignedInt   = 5125
+	gltfArrayBuffer   = 34962
+	gltfElementBuffer = 34963
+	gltfNearest       = 9728
+	gltfRepeat        = 10497
+)
+
+// gltfDocument is the part of glTF 2.0 the export uses.
+type gltfDocument struct {
+	Asset       gltfAsset        `json:"asset"`
+	Scene       int              `json:"scene"`
+	Scenes      []gltfScene      `json:"scenes"`
+	Nodes       []gltfNode       `json:"nodes"`
+	Meshes      []gltfMesh       `json:"meshes"`
+	Materials   []gltfMaterial   `json:"materials,omitempty"`
+	Textures    []gltfTexture    `json:"textures,omitempty"`
+	Images      []gltfImage      `json:"images,omitempty"`
+	Samplers    []gltfSampler    `json:"samplers,omitempty"`
+	Accessors   []gltfAccessor   `json:"accessors"`
+	BufferViews []gltfBufferView `json:"bufferViews"`
+	Buffers     []gltfBuffer     `json:"buffers"`
+}
+
+type gltfAsset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator,omitempty"`
+}
+
+type gltfScene struct {
+	Nodes []int `json:"nodes"`
+}
+
+type gltfNode struct {
+	Name string `json:"name,omitempty"`
+	Mesh int    `json:"mesh"`
+}
+
+type gltfMesh struct {
+	Name       string          `json:"name,omitempty"`
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    int            `json:"indices"`
+	Material   int            `json:"material"`
+}
+
+type gltfMaterial struct {
+	Name string  `json:"name"`
+	PBR  gltfPBR `json:"pbrMetallicRoughness"`
+}
+
+type gltfPBR struct {
+	BaseColorTexture gltfTextureRef `json:"baseColorTexture"`
+	MetallicFactor   float32        `json:"metallicFactor"`
+	RoughnessFactor  float32        `json:"roughnessFactor"`
+}
+
+type gltfTextureRef struct {
+	Index int `json:"index"`
+}
+
+type gltfTexture struct {
+	Sampler int `json:"sampler"`
+	Source  int `json:"source"`
+}
+
+type gltfImage struct {
+	Name       string `json:"name,omitempty"`
+	BufferView int    `json:"bufferView"`
+	MimeType   string `json:"mimeType"`
+}
+
+type gltfSampler struct {
+	MagFilter int `json:"magFilter"`
+	MinFilter int `json:"minFilter"`
+	WrapS     int `json:"wrapS"`
+	WrapT     int `json:"wrapT"`
+}
+
+type gltfAccessor struct {
+	BufferView    int       `json:"bufferView"`
+	ComponentType int       `json:"componentType"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float32 `json:"min,omitempty"`
+	Max           []float32 `json:"max,omitempty"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	Target     int `json:"target,omitempty"`
+}
+
+type gltfBuffer struct {
+	ByteLength int `json:"byteLength"`
+}
+
+// gltfComponents is the number of components of an accessor type.
+var gltfComponents = map[string]int{"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4}
+
+// gltfBuilder collects the binary buffer of a document.
+type gltfBuilder struct {
+	doc gltfDocument
+	bin bytes.Buffer
+}
+
+// view appends data as buffer view, views start at multiples of four.
+func (b *gltfBuilder) view(data []byte, target int) int {
+	for b.bin.Len()%4 != 0 {
+		b.bin.WriteByte(0)
+	}
+	b.doc.BufferViews = append(b.doc.BufferViews, gltfBufferView{
+		ByteOffset: b.bin.Len(),
+		ByteLength: len(data),
+		Target:     target,
+	})
+	b.bin.Write(data)
+	return len(b.doc.BufferViews) - 1
+}
+
+func (b *gltfBuilder) floats(values []float32, typ string, bounds bool) int {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, values)
+	accessor := gltfAccessor{
+		BufferView:    b.view(buf.Bytes(), gltfArrayBuffer),
+		ComponentType: gltfFloat,
+		Count:         len(values) / gltfComponents[typ],
+		Type:          typ,
+	}
+	if bounds {
+		// positions need their bounds
+		n := gltfComponents[typ]
+		accessor.Min, accessor.Max = make([]float32, n), make([]float32, n)
+		for i := 0; i < n; i++ {
+			accessor.Min[i], accessor.Max[i] = math.MaxFloat32, -math.MaxFloat32
+		}
+		for i, v := range values {
+			accessor.Min[i%n] = float32(math.Min(float64(accessor.Min[i%n]), float64(v)))
+			accessor.Max[i%n] = float32(math.Max(float64(accessor.Max[i%n]), float64(v)))
+		}
+	}
+	b.doc.Accessors = append(b.doc.Accessors, accessor)
+	return len(b.doc.Accessors) - 1
+}
+
+func (b *gltfBuilder) indices(values []uint32) int {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, values)
+	b.doc.Accessors = append(b.doc.Accessors, gltfAccessor{
+		BufferView:    b.view(buf.Bytes(), gltfElementBuffer),
+		ComponentType: gltfUnsignedInt,
+		Count:         len(values),
+		Type:          "SCALAR",
+	})
+	return len(b.doc.Accessors) - 1
+}
+
+// WriteGLB writes the mesh as binary glTF 2.0 with the textures of the
+// materials embedded as png, see Textures. glTF has the origin of the
+// texture coordinates at the top left.
+func (m *ExportMesh) WriteGLB(w io.Writer, textures map[string]image.Image) error {
+	// glTF has no valid document without a primitive
+	if m.Triangles() == 0 {
+		return fmt.Errorf("%w: %v to %v has no faces", ErrInvalidRegion, m.Box.Min, m.Box.Max)
+	}
+	b := &gltfBuilder{doc: gltfDocument{
+		Asset:    gltfAsset{Version: "2.0", Generator: "gocraft"},
+		Scenes:   []gltfScene{{Nodes: []int{0}}},
+		Nodes:    []gltfNode{{Name: "region", Mesh: 0}},
+		Meshes:   []gltfMesh{{Name: "region"}},
+		Samplers: []gltfSampler{{MagFilter: gltfNearest, MinFilter: gltfNearest, WrapS: gltfRepeat, WrapT: gltfRepeat}},
+	}}
+	for _, g := range m.groups {
+		var png bytes.Buffer
+		if err := encodePNG(&png, textures[g.Name()]); err != nil {
+			return err
+		}
+		b.doc.Images = append(b.doc.Images, gltfImage{Name: g.Name(), BufferView: b.view(png.Bytes(), 0), MimeType: "image/png"})
+		b.doc.Textures = append(b.doc.Textures, gltfTexture{Sampler: 0, Source: len(b.doc.Images) - 1})
+		b.doc.Materials = append(b.doc.Materials, gltfMaterial{
+			Name: g.Name(),
+			PBR:  gltfPBR{BaseColorTexture: gltfTextureRef{Index: len(b.doc.Textures) - 1}, RoughnessFactor: 1},
+		})
+
+		var (
+			positions = make([]float32, 0, 12*len(g.Quads))
+			normals   = make([]float32, 0, 12*len(g.Quads))
+			texcoords = make([]float32, 0, 8*len(g.Quads))
+			indices   = make([]uint32, 0, 6*len(g.Quads))
+		)
+		for _, q := range g.Quads {
+			base := uint32(len(positions) / 3)
+			for k, p := range q.Corners {
+				positions = append(positions, p.X, p.Y, p.Z)
+				normals = append(normals, q.Normal.X, q.Normal.Y, q.Normal.Z)
+				texcoords = append(texcoords, q.Texcoords[k].X, 1-q.Texcoords[k].Y)
+			}
+			indices = append(indices, base, base+1, base+2, base, base+2, base+3)
+		}
+		b.doc.Meshes[0].Primitives = append(b.doc.Meshes[0].Primitives, gltfPrimitive{
+			Attributes: map[string]int{
+				"POSITION":   b.floats(positions, "VEC3", true),
+				"NORMAL":     b.floats(normals, "VEC3", false),
+				"TEXCOORD_0": b.floats(texcoords, "VEC2", false),
+			},
+			Indices:  b.indices(indices),
+			Material: len(b.doc.Materials) - 1,
+		})
+	}
+	for b.bin.Len()%4 != 0 {
+		b.bin.WriteByte(0)
+	}
+	b.doc.Buffers = []gltfBuffer{{ByteLength: b.bin.Len()}}
+
+	doc, err := json.Marshal(b.doc)
+	if err != nil {
+		return err
+	}
+	// the json chunk is padded with spaces
+	for len(doc)%4 != 0 {
+		doc = append(doc, ' ')
+	}
+	header := []uint32{glbMagic, glbVersion, uint32(12 + 8 + len(doc) + 8 + b.bin.Len())}
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	for _, chunk := range []struct {
+		typ  uint32
+		data []byte
+	}{{glbChunkJSON, doc}, {glbChunkBIN, b.bin.Bytes()}} {
+		if err := binary.Write(w, binary.LittleEndian, []uint32{uint32(len(chunk.data)), chunk.typ}); err != nil {
+			return err
+		}
+		if _, err := w.Write(chunk.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodePNG(w io.Writer, img image.Image) error {
+	if img == nil {
+		img = placeholderImage()
+	}
+	return png.Encode(w, img)
+}
+
+// ValidateGLB reads a binary glTF file back and checks that its
+// accessors, buffer views, indices, materials and images fit together.
+// It returns the number of triangles.
+func ValidateGLB(r io.Reader) (int, error) {
+	invalid := func(format string, args ...interface{}) (int, error) {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidGLB, fmt.Sprintf(format, args...))
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) < 12 {
+		return invalid("no header")
+	}
+	var (
+		le     = binary.LittleEndian
+		length = int(le.Uint32(data[8:]))
+	)
+	if le.Uint32(data) != glbMagic || le.Uint32(data[4:]) != glbVersion {
+		return invalid("not a glTF 2.0 binary")
+	}
+	if length != len(data) {
+		return invalid("length %d, file has %d bytes", length, len(data))
+	}
+	chunks := make(map[uint32][]byte)
+	for off := 12; off < len(data); {
+		if off+8 > len(data) {
+			return invalid("chunk header at %d is cut off", off)
+		}
+		size, typ := int(le.Uint32(data[off:])), le.Uint32(data[off+4:])
+		if size%4 != 0 || off+8+size > len(data) {
+			return invalid("chunk at %d has a bad length %d", off, size)
+		}
+		chunks[typ] = data[off+8 : off+8+size]
+		off += 8 + size
+	}
+	// arrays of glTF are either left out or have items, the document
+	// structs can not tell null and empty arrays from missing ones
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(chunks[glbChunkJSON], &top); err != nil {
+		return invalid("json chunk: %s", err.Error())
+	}
+	for _, key := range sortedKeys(top) {
+		switch v := string(bytes.TrimSpace(top[key])); v {
+		case "null", "[]":
+			return invalid("%s is %s", key, v)
+		}
+	}
+	var doc gltfDocument
+	if err := json.Unmarshal(chunks[glbChunkJSON], &doc); err != nil {
+		return invalid("json chunk: %s", err.Error())
+	}
+	bin := chunks[glbChunkBIN]
+	if doc.Asset.Version != "2.0" {
+		return invalid("asset version %q", doc.Asset.Version)
+	}
+	if len(doc.Buffers) != 1 || doc.Buffers[0].ByteLength > len(bin) {
+		return invalid("the buffer does not match the binary chunk")
+	}
+	if doc.Buffers[0].ByteLength == 0 {
+		return invalid("the buffer is empty")
+	}
+
+	viewData := func(i int) ([]byte, error) {
+		if i < 0 || i >= len(doc.BufferViews) {
+			return nil, fmt.Errorf("%w: buffer view %d does not exist", ErrInvalidGLB, i)
+		}
+		v := doc.BufferViews[i]
+		if v.Buffer != 0 || v.ByteOffset < 0 || v.ByteLength < 1 || v.ByteOffset+v.ByteLength > doc.Buffers[0].ByteLength {
+			return nil, fmt.Errorf("%w: buffer view %d is outside of the buffer", ErrInvalidGLB, i)
+		}
+		return bin[v.ByteOffset : v.ByteOffset+v.ByteLength], nil
+	}
+	accessor := func(i int, componentType int, typ string) (gltfAccessor, []byte, error) {
+		if i < 0 || i >= len(doc.Accessors) {
+			return gltfAccessor{}, nil, fmt.Errorf("%w: accessor %d does not exist", ErrInvalidGLB, i)
+		}
+		a := doc.Accessors[i]
+		if a.Count < 1 {
+			return a, nil, fmt.Errorf("%w: accessor %d is empty", ErrInvalidGLB, i)
+		}
+		if a.ComponentType != componentType || a.Type != typ {
+			return a, nil, fmt.Errorf("%w: accessor %d is %d %s, expected %d %s", ErrInvalidGLB, i, a.ComponentType, a.Type, componentType, typ)
+		}
+		data, err := viewData(a.BufferView)
+		if err != nil {
+			return a, nil, err
+		}
+		if a.Count*gltfComponents[typ]*4 > len(data) {
+			return a, nil, fmt.Errorf("%w: accessor %d is longer than its buffer view", ErrInvalidGLB, i)
+		}
+		return a, data, nil
+	}
+
+	for i, img := range doc.Images {
+		data, err := viewData(img.BufferView)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+			return invalid("image %d: %s", i, err.Error())
+		}
+	}
+	for i, t := range doc.Textures {
+		if t.Source < 0 || t.Source >= len(doc.Images) || t.Sampler < 0 || t.Sampler >= len(doc.Samplers) {
+			return invalid("texture %d has no image or sampler", i)
+		}
+	}
+	for i, m := range doc.Materials {
+		if t := m.PBR.BaseColorTexture.Index; t < 0 || t >= len(doc.Textures) {
+			return invalid("material %d has no texture", i)
+		}
+	}
+	if doc.Scene < 0 || doc.Scene >= len(doc.Scenes) {
+		return invalid("scene %d does not exist", doc.Scene)
+	}
+	for _, n := range doc.Scenes[doc.Scene].Nodes {
+		if n < 0 || n >= len(doc.Nodes) {
+			return invalid("scene %d has no node %d", doc.Scene, n)
+		}
+	}
+	for _, n := range doc.Nodes {
+		if n.Mesh < 0 || n.Mesh >= len(doc.Meshes) {
+			return invalid("node %q has no mesh", n.Name)
+		}
+	}
+
+	triangles := 0
+	for m, mesh := range doc.Meshes {
+		if len(mesh.Primitives) == 0 {
+			return invalid("mesh %d has no primitives", m)
+		}
+		for i, p := range mesh.Primitives {
+			if p.Material < 0 || p.Material >= len(doc.Materials) {
+				return invalid("primitive %d has no material", i)
+			}
+			index, ok := p.Attributes["POSITION"]
+			if !ok {
+				return invalid("primitive %d has no positions", i)
+			}
+			position, _, err := accessor(index, gltfFloat, "VEC3")
+			if err != nil {
+				return 0, err
+			}
+			if len(position.Min) != 3 || len(position.Max) != 3 {
+				return invalid("positions of primitive %d have no bounds", i)
+			}
+			// the other attributes are optional but have one value per vertex
+			for _, attr := range []struct{ name, typ string }{{"NORMAL", "VEC3"}, {"TEXCOORD_0", "VEC2"}} {
+				index, ok := p.Attributes[attr.name]
+				if !ok {
+					continue
+				}
+				a, _, err := accessor(index, gltfFloat, attr.typ)
+				if err != nil {
+					return 0, err
+				}
+				if a.Count != position.Count {
+					return invalid("primitive %d has %d %s for %d vertices", i, a.Count, attr.name, position.Count)
+				}
+			}
+			indices, data, err := accessor(p.Indices, gltfUnsignedInt, "SCALAR")
+			if err != nil {
+				return 0, err
+			}
+			if indices.Count%3 != 0 {
+				return invalid("primitive %d has %d indices, not triangles", i, indices.Count)
+			}
+			for k := 0; k < indices.Count; k++ {
+				if int(le.Uint32(data[4*k:])) >= position.Count {
+					return invalid("primitive %d has index %d of %d vertices", i, le.Uint32(data[4*k:]), position.Count)
+				}
+			}
+			triangles += indices.Count / 3
+		}
+	}
+	return triangles, nil
+}
+
+// sortedKeys returns the keys of a json object in order, so the same
+// file always fails the same way.
+func sortedKeys(object map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedNames returns the keys of the textures in order.
+func sortedNames(textures map[string]image.Image) []string {
+	names := make([]string, 0, len(textures))
+	for name := range textures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,125 @@
+package gocraft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// quadMesh is a mesh of a single dirt face.
+func quadMesh() *ExportMesh {
+	return &ExportMesh{groups: []*exportGroup{{
+		Block: BlockTypeDirt,
+		Face:  blockFaceTop,
+		Quads: []exportQuad{{
+			Corners:   [4]rl.Vector3{{X: 0, Y: 1, Z: 1}, {X: 1, Y: 1, Z: 1}, {X: 1, Y: 1, Z: 0}, {X: 0, Y: 1, Z: 0}},
+			Normal:    rl.NewVector3(0, 1, 0),
+			Texcoords: [4]rl.Vector2{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 1}},
+		}},
+	}}}
+}
+
+// rewriteGLB replaces the json chunk of a glb file with the one edit
+// leaves, the binary chunk is kept.
+func rewriteGLB(t *testing.T, glb []byte, edit func(doc map[string]interface{})) []byte {
+	t.Helper()
+	le := binary.LittleEndian
+	jsonLen := int(le.Uint32(glb[12:]))
+	var doc map[string]interface{}
+	if err := json.Unmarshal(glb[20:20+jsonLen], &doc); err != nil {
+		t.Fatal(err)
+	}
+	edit(doc)
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for len(data)%4 != 0 {
+		data = append(data, ' ')
+	}
+
+	bin := glb[20+jsonLen:]
+	var out bytes.Buffer
+	binary.Write(&out, le, []uint32{glbMagic, glbVersion, uint32(12 + 8 + len(data) + len(bin))})
+	binary.Write(&out, le, []uint32{uint32(len(data)), glbChunkJSON})
+	out.Write(data)
+	out.Write(bin)
+	return out.Bytes()
+}
+
+func TestWriteGLBRefusesEmptyMesh(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (&ExportMesh{}).WriteGLB(&buf, nil); !errors.Is(err, ErrInvalidRegion) {
+		t.Errorf("got %v, want %v", err, ErrInvalidRegion)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wrote %d bytes", buf.Len())
+	}
+}
+
+func TestValidateGLB(t *testing.T) {
+	var buf bytes.Buffer
+	if err := quadMesh().WriteGLB(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	glb := buf.Bytes()
+	primitive := func(doc map[string]interface{}) map[string]interface{} {
+		mesh := doc["meshes"].([]interface{})[0].(map[string]interface{})
+		return mesh["primitives"].([]interface{})[0].(map[string]interface{})
+	}
+
+	tests := []struct {
+		name string
+		// edit changes the json of a valid file, nil keeps it valid
+		edit func(doc map[string]interface{})
+	}{
+		{"valid", nil},
+		{"null accessors", func(doc map[string]interface{}) { doc["accessors"] = nil }},
+		{"no buffer views", func(doc map[string]interface{}) { doc["bufferViews"] = []interface{}{} }},
+		{"null meshes", func(doc map[string]interface{}) { doc["meshes"] = nil }},
+		{"empty primitives", func(doc map[string]interface{}) {
+			doc["meshes"].([]interface{})[0].(map[string]interface{})["primitives"] = []interface{}{}
+		}},
+		{"null primitives", func(doc map[string]interface{}) {
+			doc["meshes"].([]interface{})[0].(map[string]interface{})["primitives"] = nil
+		}},
+		{"null attributes", func(doc map[string]interface{}) { primitive(doc)["attributes"] = nil }},
+		{"zero length buffer", func(doc map[string]interface{}) {
+			doc["buffers"] = []interface{}{map[string]interface{}{"byteLength": 0}}
+		}},
+		{"empty buffer view", func(doc map[string]interface{}) {
+			doc["bufferViews"].([]interface{})[1].(map[string]interface{})["byteLength"] = 0
+		}},
+		{"empty accessor", func(doc map[string]interface{}) {
+			doc["accessors"].([]interface{})[0].(map[string]interface{})["count"] = 0
+		}},
+		{"scene of a missing node", func(doc map[string]interface{}) {
+			doc["scenes"] = []interface{}{map[string]interface{}{"nodes": []int{3}}}
+		}},
+		{"index past the vertices", func(doc map[string]interface{}) {
+			doc["accessors"].([]interface{})[0].(map[string]interface{})["count"] = 2
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := glb
+			if tt.edit != nil {
+				data = rewriteGLB(t, glb, tt.edit)
+			}
+			triangles, err := ValidateGLB(bytes.NewReader(data))
+			if tt.edit == nil {
+				if err != nil || triangles != 2 {
+					t.Errorf("got %d triangles, %v, want 2", triangles, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidGLB) {
+				t.Errorf("got %d triangles, %v, want %v", triangles, err, ErrInvalidGLB)
+			}
+		})
+	}
+}
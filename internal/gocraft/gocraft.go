@@ -1,6 +1,8 @@
 package gocraft
 
 import (
+	"fmt"
+
 	rl "github.com/gen2brain/raylib-go/raylib"
 	"github.com/urfave/cli/v2"
 )
@@ -11,160 +13,444 @@ type engine struct {
 	screenheight int32
 	title        string
 	loglevel     rl.TraceLogLevel
-	maxFPS       int
-	camera       rl.Camera3D
-	cunkMan      *ChunkManager
-
-	// cam vars (move to own object)
-	cameraDirection rl.Vector3
-	cameraRight     rl.Vector3
-	cameraFront     rl.Vector3
+	seed         int
+	config       Config
+	configPath   string
+	settings     Settings
+	camera       *Camera
+	world        *World
+	ticker       *Ticker
+	player       *Player
+	input        *Input
+	inventory    *InventoryScreen
+	hud          *HUD
+	renderer     Renderer
+	stats        RenderStats
+	far          *FarTerrain
+	sky          *Sky
+	state        GameState
+	menu         menuState
+	// quit ends the main loop like closing the window
+	quit bool
+	// connect is the server address, the engine plays offline when it is empty
+	connect string
+	name    string
+	client  *Client
+
+	// savedSettings are the settings when the game started or was last
+	// saved, only the ones changed since then go into the config file
+	savedSettings Settings
 }
 
-func newEngine(ctx *cli.Context) *engine {
+func newEngine(ctx *cli.Context, config Config, configPath string) *engine {
 	return &engine{
-		screenWidth:  int32(ctx.Int("width")),
-		screenheight: int32(ctx.Int("height")),
-		title:        ctx.String("title"),
-		loglevel:     loglevelFromString(ctx.String("loglevel")),
-		maxFPS:       ctx.Int("fps"),
-		camera: rl.NewCamera3D(
-			rl.NewVector3(0, 20, 0),
-			rl.NewVector3(0, 100, 100),
-			rl.NewVector3(0, 1, 0),
-			60,
-			rl.CameraPerspective,
-		),
-		cameraFront: rl.NewVector3(0, 0, -1),
-		cunkMan:     NewChunkManager(128),
+		screenWidth:  int32(config.Width),
+		screenheight: int32(config.Height),
+		title:        config.Title,
+		loglevel:     loglevelFromString(config.LogLevel),
+		seed:         config.Seed,
+		config:       config,
+		configPath:   configPath,
+		settings:     config.Settings,
+		camera:       NewCamera(config.Settings.FOV, config.Settings.Sensitivity),
+		ticker:       NewTicker(config.TickRate),
+		input:        NewInput(),
+		hud:          NewHUD(),
+		sky:          NewSky(config.Sky, config.Seed),
+		connect:      ctx.String("connect"),
+		name:         ctx.String("name"),
+
+		savedSettings: config.Settings,
 	}
 }
 
 func RunEngine(ctx *cli.Context) error {
-	es := newEngine(ctx)
+	config, path, err := ResolveConfig(ctx)
+	if err != nil {
+		return err
+	}
+	es := newEngine(ctx, config, path)
+	rl.SetTraceLog(es.loglevel)
 	rl.SetConfigFlags(rl.FlagMsaa4xHint)
 	rl.InitWindow(es.screenWidth, es.screenheight, es.title)
-	rl.SetTargetFPS(int32(es.maxFPS))
+	applySettings(es)
+	// escape opens the pause menu, the window closes through its close
+	// button or the quit buttons of the menus
+	rl.SetExitKey(0)
 
-	mainLoop(es)
+	if path, err := BindingsPath(); err == nil {
+		if err := es.input.LoadBindings(path); err != nil {
+			rl.TraceLog(rl.LogWarning, "bindings: %s", err.Error())
+		}
+	}
+
+	if es.connect != "" {
+		client, err := Dial(es.connect, es.name)
+		if err != nil {
+			rl.CloseWindow()
+			return err
+		}
+		defer client.Close()
+		es.client = client
+		es.ticker = NewTicker(client.TickRate)
+	}
+
+	err = mainLoop(es)
 
 	rl.CloseWindow()
-	return nil
+	return err
 }
 
-func mainLoop(state *engine) {
-	cube := rl.LoadModel("res/models/brick.obj")
-	// Load basic lighting shader
-	shader := rl.LoadShader("res/shaders/lighting.vs", "res/shaders/lighting.fs")
-	// Get some required shader locations
-	shader.UpdateLocation(rl.LocMatrixMvp, rl.GetShaderLocation(shader, "mvp"))
-	shader.UpdateLocation(rl.LocVectorView, rl.GetShaderLocation(shader, "viewPos"))
-	shader.UpdateLocation(rl.LocMatrixModel, rl.GetShaderLocationAttrib(shader, "instanceTransform"))
-
-	// ambient light level
-	ambientLoc := rl.GetShaderLocation(shader, "ambient")
-	rl.SetShaderValue(shader, ambientLoc, []float32{0.2, 0.2, 0.2, 1.0}, rl.ShaderUniformVec4)
-	NewLight(LightTypeDirectional, rl.NewVector3(50.0, 50.0, 0.0), rl.Vector3Zero(), rl.Beige, shader)
-
-	addBlockMaterial(BlockTypeDirt, "res/textures/dirt.png", shader)
-	addBlockMaterial(BlockTypeGras, "res/textures/gras.png", shader)
-	addBlockMaterial(BlockTypeSnow, "res/textures/snow.png", shader)
-	addBlockMaterial(BlockTypeRock, "res/textures/rock.png", shader)
-	addBlockMaterial(BlockTypeGroud, "res/textures/ground.png", shader)
-
-	firstChunk := state.cunkMan.GetChunk(rl.Vector2Zero(), rl.White)
-	for !rl.WindowShouldClose() {
-		// Update the light shader with the camera view position
-		rl.SetShaderValue(shader, shader.GetLocation(rl.LocVectorView),
-			[]float32{state.camera.Position.X, state.camera.Position.Y, state.camera.Position.Z}, rl.ShaderUniformVec3)
-
-		rl.BeginDrawing()
-
-		rl.ClearBackground(rl.SkyBlue)
-		processInput(state)
-		updateCamera(state)
-		rl.BeginMode3D(state.camera)
-		{
-			chunks := state.cunkMan.GetChunks(state.camera.Position, firstChunk)
-			for i, chunk := range chunks {
-				if i == 0 {
-					firstChunk = chunk
+func mainLoop(state *engine) error {
+	resources, err := NewResources(state.config.ResourcePacks)
+	if err != nil {
+		return err
+	}
+	defer resources.Close()
+
+	renderer := NewRaylibRenderer()
+	defer renderer.Unload()
+	state.renderer = renderer
+
+	cubeData, err := resources.LoadMesh("models/brick.obj")
+	if err != nil {
+		return err
+	}
+	cube, err := renderer.LoadMesh(cubeData)
+	if err != nil {
+		return err
+	}
+	// Load basic lighting shader, it is reloaded when its files change
+	shader, err := resources.LoadShader("shaders/lighting.vs", "shaders/lighting.fs")
+	if err != nil {
+		return err
+	}
+	defer shader.Unload()
+	shader.OnLoad = func(p *ShaderProgram) {
+		// Get some required shader locations
+		p.Shader.UpdateLocation(rl.LocMatrixMvp, p.Location("mvp"))
+		p.Shader.UpdateLocation(rl.LocVectorView, p.Location("viewPos"))
+		p.Shader.UpdateLocation(rl.LocMatrixModel, p.AttribLocation("instanceTransform"))
+	}
+	shader.OnLoad(shader)
+	renderer.Shader = shader
+
+	// the sky sets the ambient light and moves the light with the sun
+	state.sky.Bind(shader)
+	light := NewLight(LightTypeDirectional, rl.NewVector3(50.0, 50.0, 0.0), rl.Vector3Zero(), state.config.LightColor(), shader)
+
+	blocks, err := resources.LoadBlockDefinitions()
+	if err != nil {
+		return err
+	}
+	loadBlocks(renderer, resources, blocks)
+
+	recipes, err := LoadRecipes(resources, "recipes")
+	if err != nil {
+		rl.TraceLog(rl.LogWarning, "recipes: %s", err.Error())
+		recipes = &RecipeBook{}
+	}
+	state.inventory = NewInventoryScreen(recipes)
+
+	registerDebugLines(state)
+	if state.client != nil {
+		// a server game starts right away, there is no world to choose
+		state.world = state.client.World
+		state.player = state.client.Player
+		state.client.Reserved = state.inventory.Reserved
+		state.inventory.OnCraft = func(grid *CraftingGrid) {
+			state.client.Craft(grid)
+		}
+		setState(state, StatePlaying)
+	} else {
+		setState(state, StateMainMenu)
+	}
+	defer leaveWorld(state)
+
+	for !rl.WindowShouldClose() && !state.quit {
+		state.hud.Debug.Record(rl.GetFrameTime())
+		if err := shader.ReloadIfChanged(); err != nil {
+			rl.TraceLog(rl.LogWarning, "shader: %s", err.Error())
+		}
+		state.stats.Reset()
+		if state.client != nil {
+			if err := state.client.Poll(); err != nil {
+				return err
+			}
+		}
+
+		state.input.Update()
+		if state.state == StatePlaying {
+			processInput(state)
+		} else if state.input.Pressed(ActionMenu) {
+			back(state)
+		}
+		// a server does not wait for a paused client
+		if state.state == StatePlaying || state.client != nil && state.inGame() {
+			for ticks := state.ticker.Advance(rl.GetFrameTime()); ticks > 0; ticks-- {
+				if state.client != nil {
+					state.client.Pitch = state.camera.Pitch()
+					state.client.Tick(state.ticker.Step())
+				} else {
+					state.world.Tick(state.ticker.Step())
 				}
-				chunk.RenderChunk(cube)
 			}
-			rl.DrawGrid(128, 128)
 		}
-		rl.EndMode3D()
-		state.cunkMan.DebugChunks(state.camera.Position, firstChunk)
-		rl.DrawFPS(5, 5)
-		rl.EndDrawing()
+
+		if state.inGame() {
+			state.sky.Update(state.world.Ticks, state.ticker.Rate())
+			state.sky.Apply(&light, state.config.LightColor(), state.config.Light.Ambient, drawDistance(state))
+			renderer.BeginFrame(state.sky.FogColor())
+			// Update the light shader with the camera view position
+			updateCamera(state)
+			shader.SetVec3("viewPos", state.camera.Position())
+			drawWorld(state, cube)
+		} else {
+			renderer.BeginFrame(rl.SkyBlue)
+		}
+		drawMenu(state)
+		renderer.EndFrame()
 	}
+	return nil
 }
 
-var (
-	moveSpeed           = 13
-	sensitivity float32 = 0.3
-	yaw         float32
-	pitch       float32
-)
+// drawWorld draws the world and the HUD of the player.
+func drawWorld(state *engine, cube Mesh) {
+	var (
+		r       = state.renderer
+		w, h    = r.ScreenSize()
+		cam     = state.camera.Camera3D()
+		aspect  = float32(w) / float32(h)
+		frustum = NewFrustum(cam, aspect)
+		chunks  = state.world.VisibleChunks()
+	)
+	if state.far == nil || state.far.chunks != state.world.Chunks {
+		state.far = NewFarTerrain(state.world.Chunks)
+	}
+	state.far.Distance = state.settings.FarDistance
+	state.far.Update(state.player.Position)
+	sections := state.world.Chunks.cullSections(chunks, cam.Position, frustum, state.settings.CaveCulling, &state.stats)
+	state.sky.DrawBackground(r, cam)
+	r.Begin3D(cam)
+	{
+		for _, s := range sections {
+			s.render(r, cube, &state.stats)
+		}
+		state.far.Draw(r, cube, frustum, chunks, &state.stats)
+		state.sky.DrawClouds(r, cube, cam.Position, drawDistance(state), &state.stats)
+		if state.hud.Debug.Visible {
+			r.DrawGrid(128, 128)
+		}
+		for _, e := range state.world.Entities() {
+			RenderEntity(r, e, state.ticker.Alpha())
+		}
+		if state.client != nil {
+			for _, other := range state.client.Others {
+				RenderRemotePlayer(r, other)
+			}
+		}
+	}
+	r.End3D()
 
-// TODO: make own object
-func updateCamera(s *engine) {
-	s.cameraDirection = rl.Vector3Subtract(s.camera.Position, s.camera.Target)
-	s.cameraDirection = rl.Vector3Normalize(s.cameraDirection)
-	s.cameraRight = rl.Vector3CrossProduct(s.camera.Up, s.cameraDirection)
-	s.cameraRight = rl.Vector3Normalize(s.cameraRight)
+	minimap := state.hud.Minimap
+	if minimap.Map == nil || minimap.Map.chunks != state.world.Chunks {
+		minimap.Map = NewMapRenderer(state.world.Chunks)
+	}
+	minimap.Visible = state.settings.Minimap
+	minimap.Rotate = state.settings.MinimapRotate
+	minimap.Update(r, state.player.Position, state.camera.Yaw(), rl.GetFrameTime())
 
-	s.camera.Target = rl.Vector3Add(s.camera.Position, s.cameraFront)
+	playing := state.state == StatePlaying
+	state.hud.Crosshair = playing && !state.inventory.Open && state.camera.Mode != CameraModeSpectator
+	state.hud.Draw(r, state.player)
+	if playing && state.inventory.Open {
+		state.inventory.Draw(r, &state.player.Inventory, state.player.GameMode)
+	}
 }
 
-func processInput(s *engine) {
-	if rl.IsKeyDown(rl.KeyW) {
-		s.camera.Position = rl.Vector3Add(
-			s.camera.Position,
-			rl.Vector3Scale(s.cameraFront, float32(moveSpeed)*rl.GetFrameTime()),
+// drawDistance is how far the world is drawn, the fog ends there.
+func drawDistance(s *engine) float32 {
+	chunks := maxInt(s.world.ViewDistance, s.settings.FarDistance)
+	return clamp32((float32(chunks)+0.5)*s.world.Chunks.Size(), 0, cameraFar)
+}
+
+// registerDebugLines adds the sections of the debug screen.
+func registerDebugLines(s *engine) {
+	s.hud.Debug.Register("engine", func() []string {
+		return []string{
+			fmt.Sprintf("gocraft %d fps (%.1f ms), %d tps", rl.GetFPS(), rl.GetFrameTime()*1000, s.ticker.Rate()),
+		}
+	})
+	s.hud.Debug.Register("player", func() []string {
+		var (
+			pos     = s.player.Position
+			x, y, z = blockCoord(pos)
+			center  = s.world.Chunks.chunkCenter(x, z)
+			size    = s.world.Chunks.Size()
 		)
+		return []string{
+			fmt.Sprintf("xyz: %.3f / %.3f / %.3f", pos.X, pos.Y, pos.Z),
+			fmt.Sprintf("block: %d %d %d", x, y, z),
+			fmt.Sprintf("chunk: %d %d", int(-center.X/size), int(-center.Y/size)),
+			fmt.Sprintf("facing: %s (yaw %.1f / pitch %.1f)", facing(s.camera.Yaw()), s.camera.Yaw(), s.camera.Pitch()),
+			fmt.Sprintf("mode: %s, %s, camera %s", s.player.Mode, s.player.GameMode, s.camera.Mode),
+		}
+	})
+	s.hud.Debug.Register("world", func() []string { return s.world.DebugLines() })
+	s.hud.Debug.Register("render", s.stats.DebugLines)
+	s.hud.Debug.Register("sky", s.sky.DebugLines)
+	if s.client != nil {
+		s.hud.Debug.Register("network", s.client.DebugLines)
 	}
-	if rl.IsKeyDown(rl.KeyS) {
-		s.camera.Position = rl.Vector3Subtract(
-			s.camera.Position,
-			rl.Vector3Scale(s.cameraFront, float32(moveSpeed)*rl.GetFrameTime()),
-		)
+}
+
+func updateCamera(s *engine) {
+	s.camera.Update(s.player.InterpolatedEye(s.ticker.Alpha()), s.world.Chunks, rl.GetFrameTime())
+}
+
+func processInput(s *engine) {
+	in := PlayerInput{
+		Forward: s.input.Axis(ActionMoveBack, ActionMoveForward),
+		Strafe:  s.input.Axis(ActionMoveLeft, ActionMoveRight),
+		Jump:    s.input.Down(ActionJump),
+		Sneak:   s.input.Down(ActionSneak),
+		Sprint:  s.input.Down(ActionSprint),
+		Yaw:     s.camera.Yaw(),
 	}
-	if rl.IsKeyDown(rl.KeyD) {
-		s.camera.Position = rl.Vector3Add(
-			s.camera.Position,
-			rl.Vector3Scale(
-				rl.Vector3Normalize(rl.Vector3CrossProduct(s.cameraFront, s.camera.Up)),
-				float32(moveSpeed)*rl.GetFrameTime(),
-			),
-		)
+
+	if s.input.Pressed(ActionDebug) {
+		s.hud.Debug.Visible = !s.hud.Debug.Visible
 	}
-	if rl.IsKeyDown(rl.KeyA) {
-		s.camera.Position = rl.Vector3Subtract(
-			s.camera.Position,
-			rl.Vector3Scale(
-				rl.Vector3Normalize(rl.Vector3CrossProduct(s.cameraFront, s.camera.Up)),
-				float32(moveSpeed)*rl.GetFrameTime(),
-			),
-		)
+	if s.input.Pressed(ActionMinimap) {
+		s.settings.Minimap = !s.settings.Minimap
+	}
+	if s.input.Pressed(ActionMenu) && !s.inventory.Open {
+		s.player.Input = PlayerInput{Yaw: s.camera.Yaw()}
+		setState(s, StatePaused)
+		return
+	}
+	if s.input.Pressed(ActionInventory) || (s.inventory.Open && s.input.Pressed(ActionMenu)) {
+		toggleInventory(s)
+	}
+	// the player stands still while a screen is open
+	if s.inventory.Open {
+		s.player.Input = PlayerInput{Yaw: in.Yaw}
+		return
+	}
+
+	if s.input.Pressed(ActionToggleFly) {
+		s.player.ToggleMode(PlayerModeFly)
+	}
+	if s.input.Pressed(ActionToggleNoclip) {
+		s.player.ToggleMode(PlayerModeNoclip)
+	}
+	if s.input.Pressed(ActionCameraMode) {
+		s.camera.NextMode()
+	}
+	// on a server the game mode is set by the server
+	if s.input.Pressed(ActionGameMode) && s.client == nil {
+		if s.player.GameMode == GameModeCreative {
+			s.player.SetGameMode(GameModeSurvival)
+		} else {
+			s.player.SetGameMode(GameModeCreative)
+		}
+	}
+
+	// the spectator camera takes the movement keys, the player stands still
+	if s.camera.Mode == CameraModeSpectator {
+		var up float32
+		if in.Jump {
+			up++
+		}
+		if in.Sneak {
+			up--
+		}
+		s.camera.Fly(in.Forward, in.Strafe, up, rl.GetFrameTime())
+		in = PlayerInput{Yaw: in.Yaw}
 	}
-	if rl.IsWindowFocused() {
-		mousepos := rl.GetMouseDelta()
+	s.player.Input = in
 
-		yaw += mousepos.X * sensitivity
-		pitch += -mousepos.Y * sensitivity
+	look := s.input.Look()
+	s.camera.Rotate(look.X, look.Y)
+	turn := s.input.Gamepad.LookSpeed * rl.GetFrameTime()
+	s.camera.Turn(
+		s.input.Axis(ActionLookLeft, ActionLookRight)*turn,
+		s.input.Axis(ActionLookDown, ActionLookUp)*turn,
+	)
 
-		var direction rl.Vector3
+	selectBlock(s)
+	interact(s)
+}
 
-		direction.X = cos32(radians(yaw)) * cos32(radians(pitch))
-		direction.Y = sin32(radians(pitch))
-		direction.Z = sin32(radians(yaw)) * cos32(radians(pitch))
-		s.cameraFront = rl.Vector3Normalize(direction)
+// toggleInventory opens or closes the inventory screen. Offline the
+// blocks left in the crafting grid that do not fit back are dropped.
+func toggleInventory(s *engine) {
+	if !s.inventory.Open {
+		s.inventory.Open = true
+		s.input.SetMenu(true)
+		return
+	}
+	left := s.inventory.Close(&s.player.Inventory)
+	s.input.SetMenu(false)
+	if s.client != nil {
+		// the server never took them out of the inventory
+		return
+	}
+	x, y, z := blockCoord(s.player.Position)
+	for _, stack := range left {
+		s.world.DropItem(stack.Type, stack.Count, x, y, z)
+	}
+}
 
-		rl.SetMousePosition(int(s.screenWidth)/2, int(s.screenheight)/2)
-		rl.HideCursor()
-	} else {
-		rl.ShowCursor()
+func selectBlock(s *engine) {
+	inv := &s.player.Inventory
+	if s.input.Pressed(ActionHotbarNext) {
+		inv.Scroll(1)
+	}
+	if s.input.Pressed(ActionHotbarPrev) {
+		inv.Scroll(-1)
+	}
+	for i := 0; i < hotbarSlots; i++ {
+		if s.input.Pressed(ActionHotbar(i)) {
+			inv.Select(i)
+		}
+	}
+}
+
+// interact breaks or places the block the player is looking at.
+func interact(s *engine) {
+	if !s.input.Pressed(ActionBreak) && !s.input.Pressed(ActionPlace) {
+		return
+	}
+	if s.camera.Mode == CameraModeSpectator {
+		return
+	}
+
+	hit, ok := Raycast(s.world.Chunks, s.player.EyePosition(), s.camera.Front(), playerReach)
+	if !ok {
+		return
+	}
+
+	if s.input.Pressed(ActionBreak) {
+		if s.client != nil {
+			s.client.BreakBlock(hit.X, hit.Y, hit.Z)
+			return
+		}
+		s.world.BreakBlock(s.player, hit.X, hit.Y, hit.Z)
+		return
+	}
+
+	x, y, z := hit.X+hit.Normal[0], hit.Y+hit.Normal[1], hit.Z+hit.Normal[2]
+	if s.player.Mode != PlayerModeNoclip && s.player.Box().Intersects(blockAABB(x, y, z)) {
+		return
+	}
+	held := s.player.Inventory.Held()
+	if held.Empty() {
+		return
+	}
+	if s.client != nil {
+		s.client.PlaceBlock(x, y, z, held.Type)
+		return
 	}
+	s.world.PlaceBlock(s.player, x, y, z, held.Type)
 }
@@ -2,34 +2,20 @@ package gocraft
 
 import (
 	"math"
-	"reflect"
-	"unsafe"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
-func setShaderInt32(shader rl.Shader, locIndex int32, value int32) {
-	// not pretty -_-
-	// need nicer api
-	sh := &reflect.SliceHeader{
-		Len: 4,
-		Cap: 4,
-	}
-	sh.Data = uintptr(unsafe.Pointer(&value))
-	rl.SetShaderValue(shader, value, *(*[]float32)(unsafe.Pointer(sh)), rl.ShaderUniformInt)
-}
+// logLevels are the names understood by loglevelFromString.
+var logLevels = []string{"all", "trace", "debug", "info", "warn", "error", "fatal"}
 
-func setShaderVec3(shader rl.Shader, locIndex int32, vec rl.Vector3) {
-	rl.SetShaderValue(shader, locIndex, []float32{vec.X, vec.Y, vec.Z}, rl.ShaderUniformVec3)
-}
-
-func setShaderColor(shader rl.Shader, locIndex int32, col rl.Color) {
-	rl.SetShaderValue(shader, locIndex, []float32{
-		float32(col.R) / 255,
-		float32(col.G) / 255,
-		float32(col.B) / 255,
-		float32(col.A) / 255,
-	}, rl.ShaderUniformVec4)
+func validLogLevel(level string) bool {
+	for _, l := range logLevels {
+		if l == level {
+			return true
+		}
+	}
+	return false
 }
 
 func loglevelFromString(level string) rl.TraceLogLevel {
@@ -68,3 +54,35 @@ func radians(deg float32) float32 {
 func degrees(rad float32) float32 {
 	return rad * (180 / math.Pi)
 }
+
+func clamp32(v, min, max float32) float32 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// lerpColor blends from a to b, t is 0 to 1.
+func lerpColor(a, b rl.Color, t float32) rl.Color {
+	mix := func(x, y uint8) uint8 {
+		return uint8(float32(x) + (float32(y)-float32(x))*t + 0.5)
+	}
+	return rl.NewColor(mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), mix(a.A, b.A))
+}
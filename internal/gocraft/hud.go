@@ -0,0 +1,109 @@
+package gocraft
+
+import (
+	"fmt"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const (
+	hudSlotSize   int32 = 40
+	hudSlotMargin int32 = 4
+	hudIconSize   int32 = 28
+	// hudCrosshair is half the length of a crosshair line.
+	hudCrosshair int32 = 8
+)
+
+// HUD is drawn in screen space on top of the world.
+type HUD struct {
+	// Crosshair is hidden e.g. while a screen uses the mouse.
+	Crosshair bool
+	Debug     DebugOverlay
+	Minimap   *Minimap
+}
+
+func NewHUD() *HUD {
+	return &HUD{Crosshair: true, Minimap: NewMinimap()}
+}
+
+// Draw draws the crosshair, the hotbar of p, the minimap and the debug
+// screen, which takes the place of the minimap.
+func (h *HUD) Draw(r Renderer, p *Player) {
+	if h.Crosshair {
+		drawCrosshair(r)
+	}
+	drawHotbar(r, &p.Inventory, p.GameMode)
+	if !h.Debug.Visible {
+		h.Minimap.Draw(r)
+	}
+	h.Debug.Draw(r)
+}
+
+// drawCrosshair draws a cross in the middle of the screen with a dark
+// outline, so it is visible on bright and dark blocks.
+func drawCrosshair(r Renderer) {
+	w, h := r.ScreenSize()
+	cx, cy := w/2, h/2
+	r.DrawRectangle(recti(cx-hudCrosshair-1, cy-2, 2*hudCrosshair+2, 4), rl.Fade(rl.Black, 0.5))
+	r.DrawRectangle(recti(cx-2, cy-hudCrosshair-1, 4, 2*hudCrosshair+2), rl.Fade(rl.Black, 0.5))
+	r.DrawRectangle(recti(cx-hudCrosshair, cy-1, 2*hudCrosshair, 2), rl.White)
+	r.DrawRectangle(recti(cx-1, cy-hudCrosshair, 2, 2*hudCrosshair), rl.White)
+}
+
+// drawHotbar draws the hotbar slots centered at the bottom of the screen.
+func drawHotbar(r Renderer, inv *Inventory, mode GameMode) {
+	var (
+		sw, sh = r.ScreenSize()
+		width  = hotbarSlots*hudSlotSize + (hotbarSlots-1)*hudSlotMargin
+		x      = (sw - width) / 2
+		y      = sh - hudSlotSize - 2*hudSlotMargin
+	)
+
+	for i := 0; i < hotbarSlots; i++ {
+		drawSlot(r, x, y, inv.Slots[i], mode, i == inv.Selected)
+		x += hudSlotSize + hudSlotMargin
+	}
+}
+
+// drawSlot draws one slot with its stack at x, y. The count is hidden in
+// creative mode where stacks are not used up.
+func drawSlot(r Renderer, x, y int32, stack ItemStack, mode GameMode, selected bool) {
+	slot := recti(x, y, hudSlotSize, hudSlotSize)
+	r.DrawRectangle(slot, rl.Fade(rl.Black, 0.5))
+	if selected {
+		r.DrawRectangleLines(slot, 3, rl.White)
+	} else {
+		r.DrawRectangleLines(slot, 1, rl.Gray)
+	}
+	drawStack(r, x, y, stack, mode)
+}
+
+// drawStack draws the icon and count of a stack in a slot at x, y.
+func drawStack(r Renderer, x, y int32, stack ItemStack, mode GameMode) {
+	if stack.Empty() {
+		return
+	}
+	drawBlockIcon(r, stack.Type, x+(hudSlotSize-hudIconSize)/2, y+(hudSlotSize-hudIconSize)/2, hudIconSize)
+	if mode != GameModeCreative && stack.Count > 1 {
+		count := fmt.Sprint(stack.Count)
+		r.DrawText(count, x+hudSlotSize-r.MeasureText(count, 10)-3, y+hudSlotSize-12, 10, rl.White)
+	}
+}
+
+// drawBlockIcon draws the side of a block. The block textures are atlases
+// of three faces, the middle one is the side.
+func drawBlockIcon(r Renderer, typ BlockType, x, y, size int32) {
+	m, ok := blockMaterials[typ]
+	if !ok {
+		r.DrawRectangle(recti(x, y, size, size), blockColors[typ])
+		return
+	}
+	tex := m.Texture
+	face := float32(tex.Width) / 3
+	r.DrawTexture(
+		tex,
+		rl.NewRectangle(face, 0, face, float32(tex.Height)),
+		recti(x, y, size, size),
+		rl.White,
+	)
+}
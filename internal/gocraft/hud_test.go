@@ -0,0 +1,147 @@
+package gocraft
+
+import (
+	"reflect"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// drawTestFrame draws the chunk of cm at the origin in 3D and the HUD of
+// p on top, like a frame of the game.
+func drawTestFrame(t *testing.T, cm *ChunkManager, hud *HUD, p *Player) (*RecordingRenderer, RenderStats) {
+	t.Helper()
+	r := NewRecordingRenderer(800, 600)
+	block, err := r.LoadMesh(&MeshData{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hud.Minimap.Map = NewMapRenderer(cm)
+	hud.Minimap.Update(r, p.Position, 0, testDT)
+
+	var stats RenderStats
+	r.BeginFrame(rl.Black)
+	r.Begin3D(rl.Camera3D{})
+	cm.chunkMap[cm.chunkCenter(0, 0)].RenderChunk(r, block, &stats)
+	r.End3D()
+	hud.Draw(r, p)
+	r.EndFrame()
+	return r, stats
+}
+
+// hudChunk has dirt and rock in its lower section and snow in the upper
+// one.
+func hudChunk(t *testing.T) *ChunkManager {
+	t.Helper()
+	cm := NewRemoteChunkManager(navChunkSize)
+	chunk := NewChunk(navChunkSize, 2*chunkSectionHeight, navChunkSize)
+	chunk.center = cm.chunkCenter(0, 0)
+	cm.insertChunk(chunk)
+	for _, b := range []struct {
+		x, y, z int
+		typ     BlockType
+	}{
+		{0, 0, 0, BlockTypeDirt},
+		{1, 0, 0, BlockTypeDirt},
+		{0, 1, 0, BlockTypeRock},
+		{0, 20, 0, BlockTypeSnow},
+	} {
+		if err := cm.SetBlock(b.x, b.y, b.z, b.typ); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cm
+}
+
+func TestDrawChunkAndHUD(t *testing.T) {
+	cm := hudChunk(t)
+	p := NewPlayer(rl.NewVector3(0, 2, 0))
+	p.Inventory.Add(BlockTypeDirt, 5)
+	p.Inventory.Add(BlockTypeRock, 1)
+
+	hud := NewHUD()
+	r, stats := drawTestFrame(t, cm, hud, p)
+
+	// one instanced draw per block type and section
+	if n := r.Count(DrawOpMesh); n != 3 {
+		t.Errorf("%d mesh draws, want 3", n)
+	}
+	if stats.Sections != 2 || stats.Instances != 4 || stats.DrawCalls != 3 {
+		t.Errorf("stats %+v, want 2 sections with 4 instances in 3 draws", stats)
+	}
+	for _, c := range r.Calls {
+		if in3D := c.Op == DrawOpMesh; c.In3D != in3D {
+			t.Errorf("%v drawn in 3D %v, want %v", c.Op, c.In3D, in3D)
+		}
+	}
+	// the count of the dirt stack, the north marker and the position
+	// below the minimap
+	if want := []string{"5", "N", "0, 0"}; !reflect.DeepEqual(r.Texts(), want) {
+		t.Errorf("texts %q, want %q", r.Texts(), want)
+	}
+	// the hotbar icons are textures too once the blocks are loaded
+	minimap := 0
+	for _, c := range r.Calls {
+		if c.Op == DrawOpTexture && c.Texture == hud.Minimap.texture {
+			minimap++
+		}
+	}
+	if minimap != 1 {
+		t.Errorf("minimap drawn %d times, want once", minimap)
+	}
+}
+
+func TestHUDVariants(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(hud *HUD, p *Player)
+		// texts drawn first, more may follow when more is set
+		texts     []string
+		more      bool
+		crosshair bool
+	}{
+		{
+			name:      "creative hides counts",
+			setup:     func(hud *HUD, p *Player) { p.SetGameMode(GameModeCreative) },
+			texts:     []string{"N", "0, 0"},
+			crosshair: true,
+		},
+		{
+			name:  "no minimap",
+			setup: func(hud *HUD, p *Player) { hud.Minimap.Visible = false },
+			texts: []string{"5"},
+		},
+		{
+			name: "debug screen replaces the minimap",
+			setup: func(hud *HUD, p *Player) {
+				hud.Debug.Visible = true
+				hud.Debug.Register("test", func() []string { return []string{"hello"} })
+			},
+			texts: []string{"5", "hello"},
+			more:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hud := NewHUD()
+			p := NewPlayer(rl.NewVector3(0, 2, 0))
+			p.Inventory.Add(BlockTypeDirt, 5)
+			tt.setup(hud, p)
+			hud.Crosshair = tt.crosshair
+
+			r, _ := drawTestFrame(t, hudChunk(t), hud, p)
+			texts := r.Texts()
+			if len(texts) < len(tt.texts) || !reflect.DeepEqual(texts[:len(tt.texts)], tt.texts) {
+				t.Errorf("texts %q, want them to start with %q", texts, tt.texts)
+			}
+			if !tt.more && len(texts) != len(tt.texts) {
+				t.Errorf("texts %q, want %q", texts, tt.texts)
+			}
+			// the crosshair is the first thing drawn after the world
+			first := r.Calls[r.Count(DrawOpMesh)]
+			if got := first.Op == DrawOpRectangle && first.Rect.Width == float32(2*hudCrosshair+2); got != tt.crosshair {
+				t.Errorf("crosshair drawn %v, want %v", got, tt.crosshair)
+			}
+		})
+	}
+}
@@ -0,0 +1,600 @@
+package gocraft
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+var (
+	ErrUnknownBinding = errors.New("unknown binding")
+	ErrUnknownAction  = errors.New("unknown action")
+)
+
+// Action is a named thing the player can do. Game logic only asks for
+// actions, the Input decides which keys or buttons trigger them.
+type Action string
+
+const (
+	ActionMoveForward  Action = "move_forward"
+	ActionMoveBack     Action = "move_back"
+	ActionMoveLeft     Action = "move_left"
+	ActionMoveRight    Action = "move_right"
+	ActionJump         Action = "jump"
+	ActionSneak        Action = "sneak"
+	ActionSprint       Action = "sprint"
+	ActionBreak        Action = "break"
+	ActionPlace        Action = "place"
+	ActionMenu         Action = "menu"
+	ActionHotbarNext   Action = "hotbar_next"
+	ActionHotbarPrev   Action = "hotbar_prev"
+	ActionToggleFly    Action = "toggle_fly"
+	ActionToggleNoclip Action = "toggle_noclip"
+	ActionCameraMode   Action = "camera_mode"
+	ActionGameMode     Action = "game_mode"
+	ActionInventory    Action = "inventory"
+	ActionDebug        Action = "debug"
+	ActionMinimap      Action = "minimap"
+	ActionLookLeft     Action = "look_left"
+	ActionLookRight    Action = "look_right"
+	ActionLookUp       Action = "look_up"
+	ActionLookDown     Action = "look_down"
+)
+
+// hotbarSlots is the number of hotbar slots with their own action.
+const hotbarSlots = 9
+
+// ActionHotbar returns the action selecting the given hotbar slot (0 based).
+func ActionHotbar(slot int) Action {
+	return Action(fmt.Sprintf("hotbar_%d", slot+1))
+}
+
+type BindingKind string
+
+const (
+	BindingKey           BindingKind = "key"
+	BindingMouse         BindingKind = "mouse"
+	BindingWheel         BindingKind = "wheel"
+	BindingGamepadButton BindingKind = "gamepad"
+	BindingGamepadAxis   BindingKind = "axis"
+)
+
+// Binding maps one physical input to an action. Axis and wheel bindings
+// only trigger in Direction (+1 or -1).
+type Binding struct {
+	Kind      BindingKind
+	Code      int32
+	Direction float32
+}
+
+func defaultBindings() map[Action][]Binding {
+	b := map[Action][]Binding{
+		ActionMoveForward:  {{Kind: BindingKey, Code: rl.KeyW}},
+		ActionMoveBack:     {{Kind: BindingKey, Code: rl.KeyS}},
+		ActionMoveLeft:     {{Kind: BindingKey, Code: rl.KeyA}},
+		ActionMoveRight:    {{Kind: BindingKey, Code: rl.KeyD}},
+		ActionJump:         {{Kind: BindingKey, Code: rl.KeySpace}},
+		ActionSneak:        {{Kind: BindingKey, Code: rl.KeyLeftShift}},
+		ActionSprint:       {{Kind: BindingKey, Code: rl.KeyLeftControl}},
+		ActionBreak:        {{Kind: BindingMouse, Code: rl.MouseLeftButton}},
+		ActionPlace:        {{Kind: BindingMouse, Code: rl.MouseRightButton}},
+		ActionMenu:         {{Kind: BindingKey, Code: rl.KeyEscape}},
+		ActionHotbarNext:   {{Kind: BindingWheel, Direction: -1}},
+		ActionHotbarPrev:   {{Kind: BindingWheel, Direction: 1}},
+		ActionToggleFly:    {{Kind: BindingKey, Code: rl.KeyF}},
+		ActionToggleNoclip: {{Kind: BindingKey, Code: rl.KeyN}},
+		ActionCameraMode:   {{Kind: BindingKey, Code: rl.KeyF5}},
+		ActionGameMode:     {{Kind: BindingKey, Code: rl.KeyG}},
+		ActionInventory:    {{Kind: BindingKey, Code: rl.KeyE}},
+		ActionDebug:        {{Kind: BindingKey, Code: rl.KeyF3}},
+		ActionMinimap:      {{Kind: BindingKey, Code: rl.KeyM}},
+	}
+	for i := 0; i < hotbarSlots; i++ {
+		b[ActionHotbar(i)] = []Binding{{Kind: BindingKey, Code: rl.KeyOne + int32(i)}}
+	}
+	for action, pad := range defaultGamepadBindings() {
+		b[action] = append(b[action], pad...)
+	}
+	return b
+}
+
+// inputDevice is the raw hardware state. It is an interface so the
+// Input can be driven without a window.
+type inputDevice interface {
+	KeyDown(key int32) bool
+	MouseButtonDown(button int32) bool
+	MouseDelta() rl.Vector2
+	MouseWheel() float32
+	GamepadAvailable(pad int32) bool
+	GamepadButtonDown(pad, button int32) bool
+	GamepadAxis(pad, axis int32) float32
+	Focused() bool
+	SetCursorCaptured(captured bool)
+}
+
+type raylibDevice struct{}
+
+func (raylibDevice) KeyDown(key int32) bool            { return rl.IsKeyDown(key) }
+func (raylibDevice) MouseButtonDown(button int32) bool { return rl.IsMouseButtonDown(button) }
+func (raylibDevice) MouseDelta() rl.Vector2            { return rl.GetMouseDelta() }
+func (raylibDevice) MouseWheel() float32               { return rl.GetMouseWheelMove() }
+func (raylibDevice) GamepadAvailable(pad int32) bool   { return rl.IsGamepadAvailable(pad) }
+func (raylibDevice) GamepadButtonDown(pad, button int32) bool {
+	return rl.IsGamepadButtonDown(pad, button)
+}
+func (raylibDevice) GamepadAxis(pad, axis int32) float32 { return rl.GetGamepadAxisMovement(pad, axis) }
+func (raylibDevice) Focused() bool                       { return rl.IsWindowFocused() }
+func (raylibDevice) SetCursorCaptured(captured bool) {
+	if captured {
+		rl.DisableCursor()
+	} else {
+		rl.EnableCursor()
+	}
+}
+
+// axisThreshold is how far an axis has to move to count as pressed.
+const axisThreshold = 0.5
+
+// Input polls the device once per frame and answers action queries.
+type Input struct {
+	device   inputDevice
+	bindings map[Action][]Binding
+	gamepad  int32
+	Gamepad  GamepadSettings
+
+	current  map[Action]float32
+	previous map[Action]float32
+	wheel    float32
+	look     rl.Vector2
+
+	captured  bool
+	swallowed map[Binding]bool
+	rebinding Action
+	lastRaw   map[Binding]bool
+	// menu is set while a screen uses the mouse, clicks do not grab it then
+	menu bool
+}
+
+func NewInput() *Input {
+	return newInput(raylibDevice{})
+}
+
+func newInput(device inputDevice) *Input {
+	return &Input{
+		device:    device,
+		bindings:  defaultBindings(),
+		Gamepad:   DefaultGamepadSettings(),
+		current:   make(map[Action]float32),
+		previous:  make(map[Action]float32),
+		swallowed: make(map[Binding]bool),
+		lastRaw:   make(map[Binding]bool),
+	}
+}
+
+// Update polls the device. It has to be called once per frame before
+// any action is queried.
+func (in *Input) Update() {
+	in.previous, in.current = in.current, in.previous
+	for a := range in.current {
+		delete(in.current, a)
+	}
+	in.wheel = in.device.MouseWheel()
+	in.look = rl.Vector2Zero()
+
+	if !in.device.Focused() {
+		in.setCaptured(false)
+		return
+	}
+
+	in.selectGamepad()
+	if in.rebinding != "" {
+		in.pollRebind()
+		return
+	}
+
+	grab := false
+	for action, bindings := range in.bindings {
+		for _, b := range bindings {
+			v := in.bindingValue(b)
+			// the click that grabs the mouse should not break a block,
+			// it is ignored until the button is released
+			if b.Kind == BindingMouse && (!in.captured || in.swallowed[b]) {
+				if v == 0 {
+					delete(in.swallowed, b)
+				} else if !in.captured && !in.menu {
+					in.swallowed[b] = true
+					grab = true
+				}
+				continue
+			}
+			if v > in.current[action] {
+				in.current[action] = v
+			}
+		}
+	}
+
+	if grab {
+		in.setCaptured(true)
+		return
+	}
+	if in.captured && in.Pressed(ActionMenu) {
+		in.setCaptured(false)
+		return
+	}
+	if in.captured {
+		in.look = in.device.MouseDelta()
+	}
+}
+
+func (in *Input) setCaptured(captured bool) {
+	if in.captured == captured {
+		return
+	}
+	in.captured = captured
+	in.device.SetCursorCaptured(captured)
+}
+
+// Capture grabs or releases the mouse, e.g. when a menu opens.
+func (in *Input) Capture(captured bool) {
+	in.setCaptured(captured)
+}
+
+// SetMenu frees the mouse for a screen while open is set and captures
+// it again afterwards. Mouse bindings do not trigger while a menu is open.
+func (in *Input) SetMenu(open bool) {
+	in.menu = open
+	in.setCaptured(!open)
+	if !open {
+		// the click that closed the menu must not break a block
+		for _, bindings := range in.bindings {
+			for _, b := range bindings {
+				if b.Kind == BindingMouse && in.bindingValue(b) != 0 {
+					in.swallowed[b] = true
+				}
+			}
+		}
+	}
+}
+
+// MenuOpen reports whether a screen uses the mouse.
+func (in *Input) MenuOpen() bool {
+	return in.menu
+}
+
+func (in *Input) Captured() bool {
+	return in.captured
+}
+
+func (in *Input) bindingValue(b Binding) float32 {
+	switch b.Kind {
+	case BindingKey:
+		if in.device.KeyDown(b.Code) {
+			return 1
+		}
+	case BindingMouse:
+		if in.device.MouseButtonDown(b.Code) {
+			return 1
+		}
+	case BindingWheel:
+		if in.wheel*b.Direction > 0 {
+			return 1
+		}
+	case BindingGamepadButton:
+		if in.device.GamepadAvailable(in.gamepad) && in.device.GamepadButtonDown(in.gamepad, b.Code) {
+			return 1
+		}
+	case BindingGamepadAxis:
+		if !in.device.GamepadAvailable(in.gamepad) {
+			return 0
+		}
+		if v := in.gamepadAxis(b.Code) * b.Direction; v > 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+// Value is the analog strength of an action in the range 0..1.
+func (in *Input) Value(a Action) float32 {
+	return in.current[a]
+}
+
+// Axis combines two opposing actions into a value in the range -1..1.
+func (in *Input) Axis(negative, positive Action) float32 {
+	return in.Value(positive) - in.Value(negative)
+}
+
+func (in *Input) Down(a Action) bool {
+	return in.current[a] >= axisThreshold
+}
+
+// Pressed reports whether the action started this frame.
+func (in *Input) Pressed(a Action) bool {
+	return in.current[a] >= axisThreshold && in.previous[a] < axisThreshold
+}
+
+// Released reports whether the action ended this frame.
+func (in *Input) Released(a Action) bool {
+	return in.current[a] < axisThreshold && in.previous[a] >= axisThreshold
+}
+
+// Look is the mouse movement of this frame while the mouse is captured.
+func (in *Input) Look() rl.Vector2 {
+	return in.look
+}
+
+// Bindings returns the bindings of an action.
+func (in *Input) Bindings(a Action) []Binding {
+	return in.bindings[a]
+}
+
+// Bind replaces the bindings of an action.
+func (in *Input) Bind(a Action, bindings ...Binding) {
+	in.bindings[a] = bindings
+}
+
+// Rebind waits for the next key or button and binds it to the action,
+// replacing its previous bindings.
+func (in *Input) Rebind(a Action) {
+	in.rebinding = a
+	in.lastRaw = in.rawState()
+}
+
+// Rebinding returns the action waiting for a new binding.
+func (in *Input) Rebinding() (Action, bool) {
+	return in.rebinding, in.rebinding != ""
+}
+
+func (in *Input) pollRebind() {
+	raw := in.rawState()
+	for b, down := range raw {
+		if !down || in.lastRaw[b] {
+			continue
+		}
+		// escape cancels, so it can not be bound through rebinding
+		if b != (Binding{Kind: BindingKey, Code: rl.KeyEscape}) {
+			in.bindings[in.rebinding] = []Binding{b}
+		}
+		in.rebinding = ""
+		break
+	}
+	in.lastRaw = raw
+}
+
+// rawState returns every bindable input that is currently active.
+func (in *Input) rawState() map[Binding]bool {
+	raw := make(map[Binding]bool)
+	for _, code := range keyNames {
+		raw[Binding{Kind: BindingKey, Code: code}] = in.device.KeyDown(code)
+	}
+	for _, code := range mouseNames {
+		raw[Binding{Kind: BindingMouse, Code: code}] = in.device.MouseButtonDown(code)
+	}
+	if in.wheel != 0 {
+		raw[Binding{Kind: BindingWheel, Direction: sign32(in.wheel)}] = true
+	}
+	if in.device.GamepadAvailable(in.gamepad) {
+		for _, code := range gamepadButtonNames {
+			raw[Binding{Kind: BindingGamepadButton, Code: code}] = in.device.GamepadButtonDown(in.gamepad, code)
+		}
+		for _, code := range gamepadAxisNames {
+			v := in.gamepadAxis(code)
+			raw[Binding{Kind: BindingGamepadAxis, Code: code, Direction: 1}] = v > axisThreshold
+			raw[Binding{Kind: BindingGamepadAxis, Code: code, Direction: -1}] = v < -axisThreshold
+		}
+	}
+	return raw
+}
+
+// BindingsPath is where the user's bindings are stored.
+func BindingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gocraft", "bindings.json"), nil
+}
+
+// LoadBindings reads bindings from a json file mapping action names to
+// lists of bindings like "key:W" or "mouse:left". Actions missing in the
+// file keep their defaults. A missing file is not an error.
+func (in *Input) LoadBindings(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var file map[Action][]string
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	defaults := defaultBindings()
+	for action, names := range file {
+		if _, ok := defaults[action]; !ok {
+			return fmt.Errorf("%s: %w: %s", path, ErrUnknownAction, action)
+		}
+		bindings := make([]Binding, 0, len(names))
+		for _, name := range names {
+			b, err := ParseBinding(name)
+			if err != nil {
+				return fmt.Errorf("%s: %s: %w", path, action, err)
+			}
+			bindings = append(bindings, b)
+		}
+		in.bindings[action] = bindings
+	}
+	return nil
+}
+
+// SaveBindings writes all bindings in the format read by LoadBindings.
+func (in *Input) SaveBindings(path string) error {
+	file := make(map[Action][]string, len(in.bindings))
+	for action, bindings := range in.bindings {
+		names := make([]string, 0, len(bindings))
+		for _, b := range bindings {
+			names = append(names, b.String())
+		}
+		file[action] = names
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// ParseBinding parses the textual form of a binding, e.g. "key:Space",
+// "mouse:right", "wheel:down", "gamepad:A" or "axis:LeftY-".
+func ParseBinding(s string) (Binding, error) {
+	kind, name, ok := strings.Cut(s, ":")
+	if !ok {
+		return Binding{}, fmt.Errorf("%w: %q", ErrUnknownBinding, s)
+	}
+
+	b := Binding{Kind: BindingKind(kind)}
+	var table map[string]int32
+	switch b.Kind {
+	case BindingKey:
+		table = keyNames
+	case BindingMouse:
+		table = mouseNames
+	case BindingGamepadButton:
+		table = gamepadButtonNames
+	case BindingWheel:
+		switch name {
+		case "up":
+			b.Direction = 1
+		case "down":
+			b.Direction = -1
+		default:
+			return Binding{}, fmt.Errorf("%w: %q", ErrUnknownBinding, s)
+		}
+		return b, nil
+	case BindingGamepadAxis:
+		table = gamepadAxisNames
+		b.Direction = 1
+		switch {
+		case strings.HasSuffix(name, "-"):
+			b.Direction = -1
+			name = strings.TrimSuffix(name, "-")
+		case strings.HasSuffix(name, "+"):
+			name = strings.TrimSuffix(name, "+")
+		}
+	default:
+		return Binding{}, fmt.Errorf("%w: %q", ErrUnknownBinding, s)
+	}
+
+	code, ok := table[name]
+	if !ok {
+		return Binding{}, fmt.Errorf("%w: %q", ErrUnknownBinding, s)
+	}
+	b.Code = code
+	return b, nil
+}
+
+func (b Binding) String() string {
+	var table map[string]int32
+	switch b.Kind {
+	case BindingKey:
+		table = keyNames
+	case BindingMouse:
+		table = mouseNames
+	case BindingGamepadButton:
+		table = gamepadButtonNames
+	case BindingWheel:
+		if b.Direction > 0 {
+			return "wheel:up"
+		}
+		return "wheel:down"
+	case BindingGamepadAxis:
+		sign := "+"
+		if b.Direction < 0 {
+			sign = "-"
+		}
+		return fmt.Sprintf("axis:%s%s", nameOf(gamepadAxisNames, b.Code), sign)
+	}
+	return fmt.Sprintf("%s:%s", b.Kind, nameOf(table, b.Code))
+}
+
+func nameOf(table map[string]int32, code int32) string {
+	names := make([]string, 0, 1)
+	for name, c := range table {
+		if c == code {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Sprint(code)
+	}
+	sort.Strings(names)
+	return names[0]
+}
+
+func sign32(v float32) float32 {
+	if v < 0 {
+		return -1
+	}
+	return 1
+}
+
+var keyNames = func() map[string]int32 {
+	names := map[string]int32{
+		"Space": rl.KeySpace, "Escape": rl.KeyEscape, "Enter": rl.KeyEnter,
+		"Tab": rl.KeyTab, "Backspace": rl.KeyBackspace, "Insert": rl.KeyInsert,
+		"Delete": rl.KeyDelete, "Right": rl.KeyRight, "Left": rl.KeyLeft,
+		"Down": rl.KeyDown, "Up": rl.KeyUp, "PageUp": rl.KeyPageUp,
+		"PageDown": rl.KeyPageDown, "Home": rl.KeyHome, "End": rl.KeyEnd,
+		"LeftShift": rl.KeyLeftShift, "LeftControl": rl.KeyLeftControl,
+		"LeftAlt": rl.KeyLeftAlt, "RightShift": rl.KeyRightShift,
+		"RightControl": rl.KeyRightControl, "RightAlt": rl.KeyRightAlt,
+		"Grave": rl.KeyGrave, "Minus": rl.KeyMinus, "Equal": rl.KeyEqual,
+		"Comma": rl.KeyComma, "Period": rl.KeyPeriod, "Slash": rl.KeySlash,
+	}
+	for c := 'A'; c <= 'Z'; c++ {
+		names[string(c)] = rl.KeyA + int32(c-'A')
+	}
+	for i := int32(0); i <= 9; i++ {
+		names[fmt.Sprint(i)] = rl.KeyZero + i
+	}
+	for i := int32(1); i <= 12; i++ {
+		names[fmt.Sprintf("F%d", i)] = rl.KeyF1 + i - 1
+	}
+	return names
+}()
+
+var mouseNames = map[string]int32{
+	"left":   rl.MouseLeftButton,
+	"right":  rl.MouseRightButton,
+	"middle": rl.MouseMiddleButton,
+	"side":   rl.MouseSideButton,
+	"extra":  rl.MouseExtraButton,
+}
+
+var gamepadButtonNames = map[string]int32{
+	"DpadUp": gamepadDpadUp, "DpadRight": gamepadDpadRight,
+	"DpadDown": gamepadDpadDown, "DpadLeft": gamepadDpadLeft,
+	"Y": gamepadY, "B": gamepadB, "A": gamepadA, "X": gamepadX,
+	"LB": gamepadLB, "LT": gamepadLT, "RB": gamepadRB, "RT": gamepadRT,
+	"Select": gamepadSelect, "Home": gamepadHome, "Start": gamepadStart,
+	"LeftThumb": gamepadLeftThumb, "RightThumb": gamepadRightThumb,
+}
+
+var gamepadAxisNames = map[string]int32{
+	"LeftX": gamepadAxisLeftX, "LeftY": gamepadAxisLeftY,
+	"RightX": gamepadAxisRightX, "RightY": gamepadAxisRightY,
+	"LeftTrigger": gamepadAxisLeftTrigger, "RightTrigger": gamepadAxisRightTrigger,
+}
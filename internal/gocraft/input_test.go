@@ -0,0 +1,289 @@
+package gocraft
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// fakeDevice is an inputDevice the tests set by hand. Gamepads are only
+// available when they are in pads.
+type fakeDevice struct {
+	keys    map[int32]bool
+	mouse   map[int32]bool
+	delta   rl.Vector2
+	wheel   float32
+	pads    map[int32]bool
+	buttons map[int32]bool
+	axes    map[int32]float32
+	// unfocused is the zero value so a new device has the focus
+	unfocused bool
+	captured  bool
+}
+
+func newFakeDevice() *fakeDevice {
+	return &fakeDevice{
+		keys:    make(map[int32]bool),
+		mouse:   make(map[int32]bool),
+		pads:    make(map[int32]bool),
+		buttons: make(map[int32]bool),
+		axes:    make(map[int32]float32),
+	}
+}
+
+func (d *fakeDevice) KeyDown(key int32) bool            { return d.keys[key] }
+func (d *fakeDevice) MouseButtonDown(button int32) bool { return d.mouse[button] }
+func (d *fakeDevice) MouseDelta() rl.Vector2            { return d.delta }
+func (d *fakeDevice) MouseWheel() float32               { return d.wheel }
+func (d *fakeDevice) GamepadAvailable(pad int32) bool   { return d.pads[pad] }
+func (d *fakeDevice) GamepadButtonDown(pad, button int32) bool {
+	return d.pads[pad] && d.buttons[button]
+}
+func (d *fakeDevice) GamepadAxis(pad, axis int32) float32 {
+	if !d.pads[pad] {
+		return 0
+	}
+	return d.axes[axis]
+}
+func (d *fakeDevice) Focused() bool                   { return !d.unfocused }
+func (d *fakeDevice) SetCursorCaptured(captured bool) { d.captured = captured }
+
+func TestBindingRoundTrip(t *testing.T) {
+	for action, bindings := range defaultBindings() {
+		for _, b := range bindings {
+			got, err := ParseBinding(b.String())
+			if err != nil {
+				t.Errorf("%s: %v", action, err)
+				continue
+			}
+			if got != b {
+				t.Errorf("%s: %q parsed to %+v, want %+v", action, b.String(), got, b)
+			}
+		}
+	}
+
+	tests := []struct {
+		text string
+		want Binding
+		// name is what String gives back, empty if it is text
+		name string
+	}{
+		{text: "key:Space", want: Binding{Kind: BindingKey, Code: rl.KeySpace}},
+		{text: "key:F12", want: Binding{Kind: BindingKey, Code: rl.KeyF12}},
+		{text: "mouse:middle", want: Binding{Kind: BindingMouse, Code: rl.MouseMiddleButton}},
+		{text: "wheel:up", want: Binding{Kind: BindingWheel, Direction: 1}},
+		{text: "gamepad:RB", want: Binding{Kind: BindingGamepadButton, Code: gamepadRB}},
+		{text: "axis:LeftY-", want: Binding{Kind: BindingGamepadAxis, Code: gamepadAxisLeftY, Direction: -1}},
+		{text: "axis:RightTrigger", want: Binding{Kind: BindingGamepadAxis, Code: gamepadAxisRightTrigger, Direction: 1}, name: "axis:RightTrigger+"},
+	}
+	for _, tt := range tests {
+		got, err := ParseBinding(tt.text)
+		if err != nil {
+			t.Errorf("%q: %v", tt.text, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q parsed to %+v, want %+v", tt.text, got, tt.want)
+		}
+		name := tt.name
+		if name == "" {
+			name = tt.text
+		}
+		if got.String() != name {
+			t.Errorf("%q prints as %q, want %q", tt.text, got.String(), name)
+		}
+	}
+}
+
+func TestParseBindingErrors(t *testing.T) {
+	for _, text := range []string{"", "Space", "key:", "key:Nope", "mouse:fourth", "wheel:left", "gamepad:Z", "axis:LeftZ-", "joystick:A"} {
+		if b, err := ParseBinding(text); !errors.Is(err, ErrUnknownBinding) {
+			t.Errorf("%q parsed to %+v, %v, want %v", text, b, err, ErrUnknownBinding)
+		}
+	}
+}
+
+func TestLoadBindings(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		ok   bool
+		// wantErr is nil for a json syntax error
+		wantErr error
+	}{
+		{name: "overrides", file: `{"jump": ["key:J", "gamepad:X"], "break": ["mouse:middle"]}`, ok: true},
+		{name: "unknown action", file: `{"fly_away": ["key:J"]}`, wantErr: ErrUnknownAction},
+		{name: "bad key", file: `{"jump": ["key:Hyper"]}`, wantErr: ErrUnknownBinding},
+		{name: "no kind", file: `{"jump": ["J"]}`, wantErr: ErrUnknownBinding},
+		{name: "malformed json", file: `{"jump": [`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bindings.json")
+			if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			in := newInput(newFakeDevice())
+			err := in.LoadBindings(path)
+			if !tt.ok {
+				var syntax *json.SyntaxError
+				if tt.wantErr == nil && !errors.As(err, &syntax) {
+					t.Errorf("got %v, want a json syntax error", err)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := []Binding{{Kind: BindingKey, Code: rl.KeyJ}, {Kind: BindingGamepadButton, Code: gamepadX}}
+			if got := in.Bindings(ActionJump); !reflect.DeepEqual(got, want) {
+				t.Errorf("jump bound to %v, want %v", got, want)
+			}
+			// actions missing in the file keep their defaults
+			if got, want := in.Bindings(ActionSneak), defaultBindings()[ActionSneak]; !reflect.DeepEqual(got, want) {
+				t.Errorf("sneak bound to %v, want the default %v", got, want)
+			}
+		})
+	}
+}
+
+func TestSaveBindings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gocraft", "bindings.json")
+	in := newInput(newFakeDevice())
+	in.Bind(ActionJump, Binding{Kind: BindingWheel, Direction: 1}, Binding{Kind: BindingGamepadAxis, Code: gamepadAxisRightY, Direction: -1})
+	if err := in.SaveBindings(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := newInput(newFakeDevice())
+	if err := loaded.LoadBindings(path); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.bindings, in.bindings) {
+		t.Errorf("loaded %v, saved %v", loaded.bindings, in.bindings)
+	}
+	// a missing file keeps the defaults
+	if err := loaded.LoadBindings(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInputActions(t *testing.T) {
+	d := newFakeDevice()
+	in := newInput(d)
+	in.Capture(true)
+
+	d.keys[rl.KeyW] = true
+	in.Update()
+	if !in.Down(ActionMoveForward) || !in.Pressed(ActionMoveForward) || in.Axis(ActionMoveBack, ActionMoveForward) != 1 {
+		t.Error("pressing W does not move forward")
+	}
+	in.Update()
+	if !in.Down(ActionMoveForward) || in.Pressed(ActionMoveForward) {
+		t.Error("W held is pressed again")
+	}
+	d.keys[rl.KeyW] = false
+	in.Update()
+	if in.Down(ActionMoveForward) || !in.Released(ActionMoveForward) {
+		t.Error("W let go is not released")
+	}
+
+	d.wheel = -1
+	in.Update()
+	if !in.Pressed(ActionHotbarNext) || in.Pressed(ActionHotbarPrev) {
+		t.Error("wheel down does not select the next slot")
+	}
+	d.wheel = 0
+
+	// rebinding
+	in.Rebind(ActionJump)
+	d.keys[rl.KeyJ] = true
+	in.Update()
+	if _, waiting := in.Rebinding(); waiting {
+		t.Error("still waiting for a key")
+	}
+	if got := in.Bindings(ActionJump); len(got) != 1 || got[0] != (Binding{Kind: BindingKey, Code: rl.KeyJ}) {
+		t.Errorf("jump bound to %v, want key:J", got)
+	}
+	d.keys[rl.KeyJ] = false
+	in.Rebind(ActionSneak)
+	d.keys[rl.KeyEscape] = true
+	in.Update()
+	if got := in.Bindings(ActionSneak); !reflect.DeepEqual(got, defaultBindings()[ActionSneak]) {
+		t.Errorf("escape rebound sneak to %v", got)
+	}
+}
+
+func TestInputMouseCapture(t *testing.T) {
+	d := newFakeDevice()
+	in := newInput(d)
+	d.delta = rl.NewVector2(3, 4)
+
+	// the click that grabs the mouse does not break a block
+	d.mouse[rl.MouseLeftButton] = true
+	in.Update()
+	if !in.Captured() || !d.captured {
+		t.Fatal("a click does not capture the mouse")
+	}
+	if in.Down(ActionBreak) {
+		t.Error("the grabbing click breaks a block")
+	}
+	in.Update()
+	if in.Down(ActionBreak) {
+		t.Error("holding the grabbing click breaks a block")
+	}
+	if in.Look() != d.delta {
+		t.Errorf("look %v, want the mouse delta %v", in.Look(), d.delta)
+	}
+	d.mouse[rl.MouseLeftButton] = false
+	in.Update()
+	d.mouse[rl.MouseLeftButton] = true
+	in.Update()
+	if !in.Pressed(ActionBreak) {
+		t.Error("the next click does not break a block")
+	}
+	d.mouse[rl.MouseLeftButton] = false
+
+	// escape frees the mouse
+	d.keys[rl.KeyEscape] = true
+	in.Update()
+	d.keys[rl.KeyEscape] = false
+	if in.Captured() || d.captured {
+		t.Error("escape does not free the mouse")
+	}
+	in.Update()
+	if in.Look() != rl.Vector2Zero() {
+		t.Errorf("look %v with a free mouse", in.Look())
+	}
+
+	// a click into a menu neither grabs the mouse nor breaks a block
+	in.SetMenu(true)
+	d.mouse[rl.MouseLeftButton] = true
+	in.Update()
+	if in.Captured() || in.Down(ActionBreak) {
+		t.Error("a click into the menu grabs the mouse or breaks a block")
+	}
+	// the click that closes the menu is swallowed as well
+	in.SetMenu(false)
+	in.Update()
+	if !in.Captured() || in.Down(ActionBreak) {
+		t.Error("the click closing the menu breaks a block")
+	}
+	d.mouse[rl.MouseLeftButton] = false
+
+	// losing the focus frees the mouse
+	d.unfocused = true
+	in.Update()
+	if in.Captured() || d.captured {
+		t.Error("the mouse stays captured without focus")
+	}
+}
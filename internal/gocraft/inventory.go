@@ -0,0 +1,267 @@
+package gocraft
+
+import (
+	"errors"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+var ErrNotInInventory = errors.New("block is not in the inventory")
+
+const (
+	// inventorySize counts all slots, the first hotbarSlots are the hotbar.
+	inventorySize = 36
+	maxStackSize  = 64
+
+	// items can not be picked up right after they were dropped
+	itemPickupDelay float32 = 0.5
+	itemPickupRange float32 = 1
+)
+
+type GameMode int
+
+const (
+	GameModeSurvival GameMode = iota
+	// GameModeCreative places blocks without using them up.
+	GameModeCreative
+)
+
+func (m GameMode) String() string {
+	if m == GameModeCreative {
+		return "creative"
+	}
+	return "survival"
+}
+
+// ParseGameMode is the reverse of GameMode.String.
+func ParseGameMode(s string) (GameMode, bool) {
+	switch s {
+	case "survival":
+		return GameModeSurvival, true
+	case "creative":
+		return GameModeCreative, true
+	}
+	return GameModeSurvival, false
+}
+
+// ItemStack is a number of blocks of one type in a slot.
+type ItemStack struct {
+	Type  BlockType `json:"type"`
+	Count int       `json:"count"`
+}
+
+func (s ItemStack) Empty() bool {
+	return s.Count <= 0
+}
+
+// MaxStackSize is how many blocks of typ fit into one slot.
+func MaxStackSize(typ BlockType) int {
+	return maxStackSize
+}
+
+type Inventory struct {
+	Slots [inventorySize]ItemStack
+	// Selected is the active hotbar slot.
+	Selected int
+
+	changed bool
+}
+
+// Add puts count blocks of typ into the inventory, filling existing
+// stacks first, and returns how many did not fit.
+func (inv *Inventory) Add(typ BlockType, count int) int {
+	for i := range inv.Slots {
+		s := &inv.Slots[i]
+		if count == 0 {
+			break
+		}
+		if s.Empty() || s.Type != typ {
+			continue
+		}
+		n := minInt(count, MaxStackSize(typ)-s.Count)
+		s.Count += n
+		count -= n
+		inv.changed = inv.changed || n > 0
+	}
+	for i := range inv.Slots {
+		s := &inv.Slots[i]
+		if count == 0 {
+			break
+		}
+		if !s.Empty() {
+			continue
+		}
+		n := minInt(count, MaxStackSize(typ))
+		*s = ItemStack{Type: typ, Count: n}
+		count -= n
+		inv.changed = true
+	}
+	return count
+}
+
+// Take removes up to count blocks of typ, the selected slot first, and
+// returns how many were removed.
+func (inv *Inventory) Take(typ BlockType, count int) int {
+	taken := inv.takeFrom(inv.Selected, typ, count)
+	for i := range inv.Slots {
+		if taken == count {
+			break
+		}
+		taken += inv.takeFrom(i, typ, count-taken)
+	}
+	return taken
+}
+
+func (inv *Inventory) takeFrom(slot int, typ BlockType, count int) int {
+	s := &inv.Slots[slot]
+	if s.Empty() || s.Type != typ {
+		return 0
+	}
+	n := minInt(count, s.Count)
+	s.Count -= n
+	if s.Empty() {
+		*s = ItemStack{}
+	}
+	inv.changed = inv.changed || n > 0
+	return n
+}
+
+// Count returns the number of blocks of typ in all slots.
+func (inv *Inventory) Count(typ BlockType) int {
+	n := 0
+	for _, s := range inv.Slots {
+		if !s.Empty() && s.Type == typ {
+			n += s.Count
+		}
+	}
+	return n
+}
+
+// Held is the stack in the selected hotbar slot.
+func (inv *Inventory) Held() ItemStack {
+	return inv.Slots[inv.Selected]
+}
+
+func (inv *Inventory) Select(slot int) {
+	if slot >= 0 && slot < hotbarSlots {
+		inv.Selected = slot
+	}
+}
+
+// Scroll moves the selection by delta slots and wraps around the hotbar.
+func (inv *Inventory) Scroll(delta int) {
+	inv.Selected = ((inv.Selected+delta)%hotbarSlots + hotbarSlots) % hotbarSlots
+}
+
+// takeChanged reports whether the slots changed since the last call.
+func (inv *Inventory) takeChanged() bool {
+	changed := inv.changed
+	inv.changed = false
+	return changed
+}
+
+// creativeBlocks are put into the hotbar when switching to creative mode.
+var creativeBlocks = []BlockType{
+	BlockTypeDirt,
+	BlockTypeGras,
+	BlockTypeSnow,
+	BlockTypeRock,
+	BlockTypeGroud,
+}
+
+// SetGameMode switches the game mode. Creative players get a stack of
+// every block in the hotbar slots that are free.
+func (p *Player) SetGameMode(mode GameMode) {
+	p.GameMode = mode
+	if mode != GameModeCreative {
+		return
+	}
+	for _, typ := range creativeBlocks {
+		if p.Inventory.Count(typ) > 0 {
+			continue
+		}
+		for i := 0; i < hotbarSlots; i++ {
+			if p.Inventory.Slots[i].Empty() {
+				p.Inventory.Slots[i] = ItemStack{Type: typ, Count: MaxStackSize(typ)}
+				p.Inventory.changed = true
+				break
+			}
+		}
+	}
+}
+
+// useBlock takes one block of typ out of the inventory to place it.
+// Creative players only need to have it, it is not used up.
+func (p *Player) useBlock(typ BlockType) bool {
+	if p.GameMode == GameModeCreative {
+		return p.Inventory.Count(typ) > 0
+	}
+	return p.Inventory.Take(typ, 1) == 1
+}
+
+// BreakBlock removes the block at x, y, z. In survival the block goes
+// into the inventory of p, what does not fit is dropped. Without a
+// player the block is always dropped.
+func (w *World) BreakBlock(p *Player, x, y, z int) error {
+	b := w.Chunks.BlockAt(x, y, z)
+	if b == nil || b.carved {
+		return nil
+	}
+	typ := b.blockType
+	if err := w.Chunks.RemoveBlock(x, y, z); err != nil {
+		return err
+	}
+
+	left := 1
+	if p != nil {
+		if p.GameMode == GameModeCreative {
+			return nil
+		}
+		left = p.Inventory.Add(typ, left)
+	}
+	if left == 0 {
+		return nil
+	}
+	return w.DropItem(typ, left, x, y, z)
+}
+
+// PlaceBlock places a block of typ at x, y, z taken from the inventory
+// of p. A nil player places for free.
+func (w *World) PlaceBlock(p *Player, x, y, z int, typ BlockType) error {
+	if p != nil && !p.useBlock(typ) {
+		return ErrNotInInventory
+	}
+	err := w.Chunks.SetBlock(x, y, z, typ)
+	if err != nil && p != nil && p.GameMode != GameModeCreative {
+		p.Inventory.Add(typ, 1)
+	}
+	return err
+}
+
+// collectItems lets players pick up the dropped items they touch.
+func (w *World) collectItems() {
+	for _, e := range w.Entities() {
+		if e.Kind != EntityKindItem || e.removed || e.Age < itemPickupDelay {
+			continue
+		}
+		for _, p := range w.Players {
+			if p.GameMode == GameModeCreative {
+				continue
+			}
+			var (
+				grow  = rl.NewVector3(itemPickupRange, itemPickupRange, itemPickupRange)
+				reach = p.Box()
+			)
+			reach.Min = rl.Vector3Subtract(reach.Min, grow)
+			reach.Max = rl.Vector3Add(reach.Max, grow)
+			if !reach.Intersects(e.Box()) {
+				continue
+			}
+			e.Count = p.Inventory.Add(e.Item, e.Count)
+			if e.Count == 0 {
+				e.Remove()
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package gocraft
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const (
+	// inventoryGridWidth is the size of the crafting grid in the inventory.
+	inventoryGridWidth = 2
+	inventoryPadding   = 12
+	inventoryRows      = inventorySize / hotbarSlots
+)
+
+// InventoryScreen shows all inventory slots and a crafting grid. It is
+// drawn and handles its clicks in the same call, there is no retained
+// widget state besides the stacks it holds.
+type InventoryScreen struct {
+	Open bool
+	Grid *CraftingGrid
+	// OnCraft is called with the grid as it was before every craft, the
+	// client tells the server with it.
+	OnCraft func(grid *CraftingGrid)
+
+	recipes *RecipeBook
+	// cursor is the stack picked up with the mouse.
+	cursor ItemStack
+}
+
+func NewInventoryScreen(recipes *RecipeBook) *InventoryScreen {
+	return &InventoryScreen{
+		Grid:    NewCraftingGrid(inventoryGridWidth),
+		recipes: recipes,
+	}
+}
+
+// Reserved returns the stacks the screen took out of the inventory.
+func (s *InventoryScreen) Reserved() []ItemStack {
+	var stacks []ItemStack
+	for _, slot := range s.Grid.Slots {
+		if !slot.Empty() {
+			stacks = append(stacks, slot)
+		}
+	}
+	if !s.cursor.Empty() {
+		stacks = append(stacks, s.cursor)
+	}
+	return stacks
+}
+
+// Close puts the crafting grid and the cursor back into inv and returns
+// what did not fit.
+func (s *InventoryScreen) Close(inv *Inventory) []ItemStack {
+	var left []ItemStack
+	for _, stack := range s.Reserved() {
+		if n := inv.Add(stack.Type, stack.Count); n > 0 {
+			left = append(left, ItemStack{Type: stack.Type, Count: n})
+		}
+	}
+	for i := range s.Grid.Slots {
+		s.Grid.Slots[i] = ItemStack{}
+	}
+	s.cursor = ItemStack{}
+	s.Open = false
+	return left
+}
+
+// Result is what the crafting grid would craft right now.
+func (s *InventoryScreen) Result() ItemStack {
+	if r := s.recipes.Match(s.Grid); r != nil {
+		return r.Result
+	}
+	return ItemStack{}
+}
+
+// click moves stacks between the cursor and a slot. The left button
+// picks up, puts down, merges or swaps whole stacks, the right button
+// picks up half a stack or puts down a single block.
+func (s *InventoryScreen) click(slot *ItemStack, right bool) {
+	switch {
+	case s.cursor.Empty() && slot.Empty():
+	case s.cursor.Empty():
+		n := slot.Count
+		if right {
+			n = (n + 1) / 2
+		}
+		s.cursor = ItemStack{Type: slot.Type, Count: n}
+		slot.Count -= n
+	case slot.Empty() || slot.Type == s.cursor.Type:
+		n := s.cursor.Count
+		if right {
+			n = 1
+		}
+		if !slot.Empty() {
+			n = minInt(n, MaxStackSize(slot.Type)-slot.Count)
+		}
+		*slot = ItemStack{Type: s.cursor.Type, Count: slot.Count + n}
+		s.cursor.Count -= n
+	default:
+		*slot, s.cursor = s.cursor, *slot
+	}
+	if slot.Empty() {
+		*slot = ItemStack{}
+	}
+	if s.cursor.Empty() {
+		s.cursor = ItemStack{}
+	}
+}
+
+// craft takes the result of the grid into the cursor if it fits there.
+func (s *InventoryScreen) craft() {
+	result := s.Result()
+	if result.Empty() {
+		return
+	}
+	if !s.cursor.Empty() && (s.cursor.Type != result.Type || s.cursor.Count+result.Count > MaxStackSize(result.Type)) {
+		return
+	}
+
+	before := NewCraftingGrid(s.Grid.Width)
+	copy(before.Slots, s.Grid.Slots)
+	if _, ok := s.recipes.Craft(s.Grid); !ok {
+		return
+	}
+	s.cursor = ItemStack{Type: result.Type, Count: s.cursor.Count + result.Count}
+	if s.OnCraft != nil {
+		s.OnCraft(before)
+	}
+}
+
+// Draw draws the screen over the game and handles the mouse.
+func (s *InventoryScreen) Draw(r Renderer, inv *Inventory, mode GameMode) {
+	var (
+		sw, sh = r.ScreenSize()
+		step   = hudSlotSize + hudSlotMargin
+		width  = hotbarSlots*step - hudSlotMargin + 2*inventoryPadding
+		height = (inventoryGridWidth+inventoryRows)*step - 2*hudSlotMargin + 4*inventoryPadding
+		x      = (sw - width) / 2
+		y      = (sh - height) / 2
+		mouse  = rl.GetMousePosition()
+		left   = rl.IsMouseButtonPressed(rl.MouseLeftButton)
+		right  = rl.IsMouseButtonPressed(rl.MouseRightButton)
+	)
+	r.DrawRectangle(screenRect(r), rl.Fade(rl.Black, 0.4))
+	r.DrawRectangle(recti(x, y, width, height), rl.Fade(rl.DarkGray, 0.9))
+
+	// slot draws a slot and reports whether it was clicked
+	slot := func(sx, sy int32, stack ItemStack) bool {
+		drawSlot(r, sx, sy, stack, mode, false)
+		rec := rl.NewRectangle(float32(sx), float32(sy), float32(hudSlotSize), float32(hudSlotSize))
+		return (left || right) && rl.CheckCollisionPointRec(mouse, rec)
+	}
+
+	// crafting grid, an arrow and the result
+	gx, gy := x+inventoryPadding, y+inventoryPadding
+	for i := range s.Grid.Slots {
+		sx := gx + int32(i%s.Grid.Width)*step
+		sy := gy + int32(i/s.Grid.Width)*step
+		if slot(sx, sy, s.Grid.Slots[i]) {
+			s.click(&s.Grid.Slots[i], right)
+		}
+	}
+	var (
+		ax = gx + int32(s.Grid.Width)*step + hudSlotMargin
+		ay = gy + (int32(s.Grid.Width)*step-hudSlotMargin)/2
+	)
+	r.DrawText("->", ax, ay-10, 20, rl.White)
+	if slot(ax+r.MeasureText("->", 20)+2*hudSlotMargin, ay-hudSlotSize/2, s.Result()) && left {
+		s.craft()
+	}
+
+	// the main slots in rows, the hotbar separated below them
+	iy := gy + int32(s.Grid.Width)*step + inventoryPadding
+	for row := 1; row <= inventoryRows; row++ {
+		first := (row % inventoryRows) * hotbarSlots
+		sy := iy + int32(row-1)*step
+		if row == inventoryRows {
+			sy += inventoryPadding - hudSlotMargin
+		}
+		for i := first; i < first+hotbarSlots; i++ {
+			if slot(x+inventoryPadding+int32(i-first)*step, sy, inv.Slots[i]) {
+				s.click(&inv.Slots[i], right)
+			}
+		}
+	}
+
+	if !s.cursor.Empty() {
+		drawStack(r, int32(mouse.X)-hudSlotSize/2, int32(mouse.Y)-hudSlotSize/2, s.cursor, mode)
+	}
+}
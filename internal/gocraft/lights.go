@@ -14,19 +14,15 @@ const (
 )
 
 type Light struct {
-	shader    rl.Shader
+	shader    *ShaderProgram
 	lightType LightType
 	position  rl.Vector3
 	target    rl.Vector3
 	color     rl.Color
 	enabled   int32
 
-	// shader locations
-	enabledLoc int32
-	typeLoc    int32
-	posLoc     int32
-	targetLoc  int32
-	colorLoc   int32
+	// name is the uniform of the light, like lights[0]
+	name string
 }
 
 const maxLightsCount = 4
@@ -37,7 +33,7 @@ func NewLight(
 	lightType LightType,
 	position, target rl.Vector3,
 	color rl.Color,
-	shader rl.Shader) Light {
+	shader *ShaderProgram) Light {
 
 	light := Light{
 		shader: shader,
@@ -50,11 +46,7 @@ func NewLight(
 		light.target = target
 		light.color = color
 
-		light.enabledLoc = rl.GetShaderLocation(shader, fmt.Sprintf("lights[%d].enabled", lightCount))
-		light.typeLoc = rl.GetShaderLocation(shader, fmt.Sprintf("lights[%d].type", lightCount))
-		light.posLoc = rl.GetShaderLocation(shader, fmt.Sprintf("lights[%d].position", lightCount))
-		light.targetLoc = rl.GetShaderLocation(shader, fmt.Sprintf("lights[%d].target", lightCount))
-		light.colorLoc = rl.GetShaderLocation(shader, fmt.Sprintf("lights[%d].color", lightCount))
+		light.name = fmt.Sprintf("lights[%d]", lightCount)
 		light.UpdateValues()
 
 		lightCount++
@@ -65,9 +57,12 @@ func NewLight(
 
 // Send light properties to shader
 func (lt *Light) UpdateValues() {
-	setShaderInt32(lt.shader, lt.enabledLoc, lt.enabled)
-	setShaderInt32(lt.shader, lt.enabledLoc, int32(lt.lightType))
-	setShaderVec3(lt.shader, lt.posLoc, lt.position)
-	setShaderVec3(lt.shader, lt.targetLoc, lt.target)
-	setShaderColor(lt.shader, lt.colorLoc, lt.color)
+	if lt.name == "" {
+		return
+	}
+	lt.shader.SetInt(lt.name+".enabled", lt.enabled)
+	lt.shader.SetInt(lt.name+".type", int32(lt.lightType))
+	lt.shader.SetVec3(lt.name+".position", lt.position)
+	lt.shader.SetVec3(lt.name+".target", lt.target)
+	lt.shader.SetColor(lt.name+".color", lt.color)
 }
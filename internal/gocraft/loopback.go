@@ -0,0 +1,88 @@
+package gocraft
+
+import (
+	"net"
+)
+
+// Loopback runs a server and its clients in one process over in-memory
+// connections. Nothing is ticked in the background, Tick advances the
+// server and lets every client apply what it received so far, which is
+// enough to exercise multiplayer in tests and benchmarks.
+type Loopback struct {
+	Server  *Server
+	Clients []*Client
+	// Conditions are applied to both directions of every connection made
+	// by Connect, they simulate latency and reordering.
+	Conditions LinkConditions
+}
+
+// NewLoopback creates a server with config and connects one client per name.
+func NewLoopback(config ServerConfig, conditions LinkConditions, names ...string) (*Loopback, error) {
+	server, err := NewServer(config)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Loopback{Server: server, Conditions: conditions}
+	for _, name := range names {
+		if _, err := l.Connect(name); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// Connect adds another client to the server.
+func (l *Loopback) Connect(name string) (*Client, error) {
+	serverSide, clientSide := net.Pipe()
+	// every connection gets its own random delays
+	conditions := l.Conditions
+	conditions.Seed += int64(len(l.Clients)) * 2
+	l.Server.serve(serverSide, newConditionedLink(newPacketConn(serverSide), conditions))
+
+	conditions.Seed++
+	client, err := newClient(clientSide, newConditionedLink(newPacketConn(clientSide), conditions), name)
+	if err != nil {
+		return nil, err
+	}
+	l.Clients = append(l.Clients, client)
+	return client, nil
+}
+
+// Disconnect closes the connection of c and stops ticking it.
+func (l *Loopback) Disconnect(c *Client) {
+	for i, other := range l.Clients {
+		if other == c {
+			l.Clients = append(l.Clients[:i], l.Clients[i+1:]...)
+			break
+		}
+	}
+	c.Close()
+}
+
+// Tick ticks every client with the input its player holds, then the
+// server and polls the clients.
+func (l *Loopback) Tick() error {
+	dt := l.Server.ticker.Step()
+	for _, c := range l.Clients {
+		if err := c.Tick(dt); err != nil {
+			return err
+		}
+	}
+	l.Server.Tick()
+	for _, c := range l.Clients {
+		if err := c.Poll(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close disconnects all clients and stops the server.
+func (l *Loopback) Close() {
+	for _, c := range l.Clients {
+		c.Close()
+	}
+	l.Server.Shutdown("loopback closed")
+}
@@ -0,0 +1,445 @@
+package gocraft
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// loopbackTimeout is how long tests wait for packets, chunks are decoded
+// in the background.
+const loopbackTimeout = 10 * time.Second
+
+func newTestLoopback(t *testing.T, conditions LinkConditions, names ...string) *Loopback {
+	t.Helper()
+	l, err := NewLoopback(testServerConfig(), conditions, names...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(l.Close)
+	return l
+}
+
+// tickUntil ticks the loopback until done reports true.
+func tickUntil(t *testing.T, l *Loopback, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(loopbackTimeout)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		if err := l.Tick(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// groundLoaded waits until the client has the chunk its player stands in.
+func groundLoaded(t *testing.T, l *Loopback, c *Client) {
+	t.Helper()
+	tickUntil(t, l, c.Name+" to get its chunk", func() bool {
+		return c.World.Chunks.Loaded(c.Player.Position)
+	})
+}
+
+func TestLoopbackHandshake(t *testing.T) {
+	l := newTestLoopback(t, LinkConditions{}, "alice", "bob")
+	alice, bob := l.Clients[0], l.Clients[1]
+
+	if alice.ID == 0 || bob.ID == 0 || alice.ID == bob.ID {
+		t.Errorf("player ids %d and %d", alice.ID, bob.ID)
+	}
+	if alice.TickRate != DefaultTickRate {
+		t.Errorf("tick rate %d, want %d", alice.TickRate, DefaultTickRate)
+	}
+	if got := alice.World.Chunks.Size(); got != testChunkSize {
+		t.Errorf("chunk size %.0f, want %.0f", got, testChunkSize)
+	}
+	if got := alice.World.Chunks.Terrain(); got != testTerrain() {
+		t.Errorf("terrain %+v, want %+v", got, testTerrain())
+	}
+	tickUntil(t, l, "both players to see each other", func() bool {
+		return alice.Others[bob.ID] != nil && bob.Others[alice.ID] != nil
+	})
+	if name := alice.Others[bob.ID].Name; name != "bob" {
+		t.Errorf("alice sees %q instead of bob", name)
+	}
+}
+
+func TestLoopbackVersionMismatch(t *testing.T) {
+	s, err := NewServer(testServerConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverSide, clientSide := net.Pipe()
+	defer clientSide.Close()
+	s.ServeConn(serverSide)
+
+	pc := newPacketConn(clientSide)
+	clientSide.SetDeadline(time.Now().Add(loopbackTimeout))
+	if err := pc.Write(&HandshakePacket{Version: ProtocolVersion + 1, Name: "old"}); err != nil {
+		t.Fatal(err)
+	}
+	p, err := pc.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bye, ok := p.(*DisconnectPacket)
+	if !ok {
+		t.Fatalf("got %T instead of a disconnect", p)
+	}
+	if !strings.Contains(bye.Reason, ErrVersionMismatch.Error()) {
+		t.Errorf("reason %q does not name the version mismatch", bye.Reason)
+	}
+	s.Shutdown("done")
+	if len(s.clients) != 0 {
+		t.Errorf("%d clients joined", len(s.clients))
+	}
+}
+
+func TestLoopbackStreamsChunks(t *testing.T) {
+	l := newTestLoopback(t, LinkConditions{}, "alice")
+	alice := l.Clients[0]
+	groundLoaded(t, l, alice)
+
+	// the chunk is the one the server generated
+	x, _, z := blockCoord(alice.Player.Position)
+	for y := 0; y < int(testChunkSize); y++ {
+		if got, want := alice.World.Chunks.IsSolid(x, y, z), l.Server.world.Chunks.IsSolid(x, y, z); got != want {
+			t.Fatalf("block %d, %d, %d: client solid %v, server %v", x, y, z, got, want)
+		}
+	}
+	// the player stands on the ground instead of falling through
+	for i := 0; i < 40; i++ {
+		if err := l.Tick(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !alice.Player.OnGround {
+		t.Errorf("player at %v is not on the ground", alice.Player.Position)
+	}
+}
+
+func TestLoopbackBroadcastsBlockChanges(t *testing.T) {
+	l := newTestLoopback(t, LinkConditions{}, "alice", "bob")
+	alice, bob := l.Clients[0], l.Clients[1]
+	groundLoaded(t, l, alice)
+	groundLoaded(t, l, bob)
+
+	// the block alice stands on, both players spawn at the same place
+	x, y, z := blockCoord(alice.Player.Position)
+	y--
+	if !bob.World.Chunks.IsSolid(x, y, z) {
+		t.Fatalf("no block at %d, %d, %d", x, y, z)
+	}
+	if err := alice.BreakBlock(x, y, z); err != nil {
+		t.Fatal(err)
+	}
+	tickUntil(t, l, "bob to see the block break", func() bool {
+		return !bob.World.Chunks.IsSolid(x, y, z)
+	})
+	if l.Server.world.Chunks.IsSolid(x, y, z) {
+		t.Error("the block is still there on the server")
+	}
+}
+
+func TestLoopbackChat(t *testing.T) {
+	l := newTestLoopback(t, LinkConditions{}, "alice", "bob")
+	alice, bob := l.Clients[0], l.Clients[1]
+
+	if err := alice.Say("hello"); err != nil {
+		t.Fatal(err)
+	}
+	tickUntil(t, l, "the chat message", func() bool {
+		return len(alice.Chat) > 0 && len(bob.Chat) > 0
+	})
+	want := ChatMessage{Sender: "alice", Message: "hello"}
+	for _, c := range l.Clients {
+		if len(c.Chat) != 1 || c.Chat[0] != want {
+			t.Errorf("%s got %v, want %v", c.Name, c.Chat, want)
+		}
+	}
+}
+
+func TestLoopbackDisconnect(t *testing.T) {
+	l := newTestLoopback(t, LinkConditions{}, "alice", "bob")
+	alice, bob := l.Clients[0], l.Clients[1]
+	tickUntil(t, l, "alice to see bob", func() bool {
+		return alice.Others[bob.ID] != nil
+	})
+
+	l.Disconnect(bob)
+	tickUntil(t, l, "bob to leave", func() bool {
+		return alice.Others[bob.ID] == nil
+	})
+	s := l.Server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.clients[bob.ID]; ok || len(s.clients) != 1 {
+		t.Errorf("server still has %d clients", len(s.clients))
+	}
+	if len(s.world.Players) != 1 || s.world.Players[0] == nil {
+		t.Errorf("world has %d players, want 1", len(s.world.Players))
+	}
+	if err := bob.Err(); err != ErrDisconnected {
+		t.Errorf("bob ended with %v, want %v", err, ErrDisconnected)
+	}
+}
+
+func TestLoopbackSpawnsOnTheGround(t *testing.T) {
+	l := newTestLoopback(t, LinkConditions{}, "alice")
+	want := rl.NewVector3(0, float32(l.Server.world.Chunks.SurfaceHeight(0, 0)), 0)
+	if got := l.Clients[0].Player.Position; got != want {
+		t.Errorf("spawned at %v, want %v", got, want)
+	}
+}
+
+// laggy delays every packet by 5 to 35 ms. The loopback ticks about once
+// per millisecond, so packets sent in a row arrive out of order.
+var laggy = LinkConditions{Latency: 20 * time.Millisecond, Jitter: 15 * time.Millisecond, Seed: 1}
+
+// serverPlayer returns a copy of the player of c as the server sees it.
+func serverPlayer(l *Loopback, c *Client) Player {
+	s := l.Server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.clients[c.ID].player
+}
+
+// settle stands still until the server acknowledged every input sent so
+// far and the prediction of c agrees with the server.
+func settle(t *testing.T, l *Loopback, c *Client) {
+	t.Helper()
+	c.Player.Input = PlayerInput{}
+	sent := c.sequence
+	tickUntil(t, l, c.Name+" to agree with the server", func() bool {
+		server := serverPlayer(l, c)
+		return c.acked > sent && server.OnGround &&
+			rl.Vector3Distance(c.Player.Position, server.Position) < 0.001
+	})
+}
+
+func TestLoopbackTeleportSnapsBack(t *testing.T) {
+	l := newTestLoopback(t, laggy, "alice")
+	alice := l.Clients[0]
+	groundLoaded(t, l, alice)
+	settle(t, l, alice)
+
+	start := alice.Player.Position
+	alice.Player.Position.X += 10
+	settle(t, l, alice)
+
+	if got := serverPlayer(l, alice).Position; rl.Vector3Distance(got, start) > 0.001 {
+		t.Errorf("server moved the player from %v to %v", start, got)
+	}
+	if got := alice.Player.Position; rl.Vector3Distance(got, start) > 0.001 {
+		t.Errorf("client stayed at %v instead of snapping back to %v", got, start)
+	}
+}
+
+func TestLoopbackSpeedHack(t *testing.T) {
+	const walkTicks = 10
+	tests := []struct {
+		name string
+		hack func(c *Client)
+	}{
+		{"move speed", func(c *Client) {
+			c.Player.MoveSpeed = 3 * playerWalkSpeed
+		}},
+		{"flooded inputs", func(c *Client) {
+			// more inputs than ticks, the extra ones have to be dropped
+			for i := 0; i < 5*maxQueuedInputs; i++ {
+				c.sequence++
+				c.send(&PlayerInputPacket{Sequence: c.sequence, Input: c.Player.Input})
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLoopback(t, laggy, "alice")
+			alice := l.Clients[0]
+			groundLoaded(t, l, alice)
+			settle(t, l, alice)
+			start := alice.Player.Position
+
+			alice.Player.Input = PlayerInput{Forward: 1}
+			tt.hack(alice)
+			for i := 0; i < walkTicks; i++ {
+				if err := l.Tick(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			alice.Player.MoveSpeed = playerWalkSpeed
+			settle(t, l, alice)
+
+			// the walk, the inputs saved up while waiting and a bit for
+			// sliding to a stop
+			limit := float32(walkTicks+maxInputBacklog+5) * playerWalkSpeed * testDT
+			server := serverPlayer(l, alice).Position
+			if d := server.X - start.X; d > limit {
+				t.Errorf("server player walked %.2f blocks, at most %.2f are possible", d, limit)
+			}
+			if got := alice.Player.Position; rl.Vector3Distance(got, server) > 0.001 {
+				t.Errorf("client at %v, server at %v", got, server)
+			}
+		})
+	}
+}
+
+func TestLoopbackNoclipRefused(t *testing.T) {
+	l := newTestLoopback(t, laggy, "alice")
+	alice := l.Clients[0]
+	groundLoaded(t, l, alice)
+
+	alice.Player.SetMode(PlayerModeNoclip)
+	sent := alice.sequence + 1
+	tickUntil(t, l, "the server to refuse noclip", func() bool {
+		return alice.acked > sent && alice.Player.Mode == PlayerModeWalk
+	})
+	if mode := serverPlayer(l, alice).Mode; mode != PlayerModeWalk {
+		t.Errorf("server player is in mode %v", mode)
+	}
+}
+
+func TestLoopbackOutOfReachEdit(t *testing.T) {
+	l := newTestLoopback(t, laggy, "alice", "bob")
+	alice, bob := l.Clients[0], l.Clients[1]
+	groundLoaded(t, l, alice)
+	groundLoaded(t, l, bob)
+
+	// the ground 10 blocks away, still in the spawn chunk
+	x, _, z := blockCoord(alice.Player.Position)
+	x += 10
+	s := l.Server
+	s.mu.Lock()
+	y := s.world.Chunks.SurfaceHeight(x, z) - 1
+	s.mu.Unlock()
+	if !alice.World.Chunks.IsSolid(x, y, z) {
+		t.Fatalf("no block at %d, %d, %d", x, y, z)
+	}
+
+	if err := alice.BreakBlock(x, y, z); err != nil {
+		t.Fatal(err)
+	}
+	if alice.World.Chunks.IsSolid(x, y, z) {
+		t.Fatal("the client did not predict the break")
+	}
+	tickUntil(t, l, "the server to restore the block", func() bool {
+		return alice.World.Chunks.IsSolid(x, y, z)
+	})
+	// give a broadcast the time to reach bob
+	for i := 0; i < 100; i++ {
+		if err := l.Tick(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.mu.Lock()
+	solid := s.world.Chunks.IsSolid(x, y, z)
+	s.mu.Unlock()
+	if !solid || !bob.World.Chunks.IsSolid(x, y, z) {
+		t.Errorf("block broken, server %v, bob %v", !solid, !bob.World.Chunks.IsSolid(x, y, z))
+	}
+}
+
+func TestLoopbackPredictionConverges(t *testing.T) {
+	l := newTestLoopback(t, laggy, "alice")
+	alice := l.Clients[0]
+	groundLoaded(t, l, alice)
+	settle(t, l, alice)
+
+	// walk a small circle with jumps, every snapshot is replayed on top
+	// of a different set of pending inputs
+	for i := 0; i < 60; i++ {
+		alice.Player.Input = PlayerInput{
+			Forward: 1,
+			Strafe:  float32(i%3) - 1,
+			Yaw:     float32(i * 6),
+			Jump:    i%15 == 0,
+		}
+		if err := l.Tick(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if alice.acked >= alice.sequence {
+		t.Fatal("every input was acknowledged, the link did not delay anything")
+	}
+	settle(t, l, alice)
+}
+
+func TestClientKeepsPacketOrder(t *testing.T) {
+	serverSide, clientSide := net.Pipe()
+	pc := newPacketConn(serverSide)
+	serverSide.SetDeadline(time.Now().Add(loopbackTimeout))
+
+	// the fake server answers the handshake, then sends chunks each
+	// followed by a change of one of its blocks, and a chat message last
+	const chunks = 8
+	errs := make(chan error, 1)
+	go func() {
+		if _, err := pc.Read(); err != nil {
+			errs <- err
+			return
+		}
+		login := &LoginPacket{PlayerID: 1, ChunkSize: navChunkSize, TickRate: DefaultTickRate, ViewDistance: 1, Terrain: testTerrain()}
+		if err := pc.Write(login); err != nil {
+			errs <- err
+			return
+		}
+		for i := 0; i < chunks; i++ {
+			chunk := NewChunk(navChunkSize, navChunkSize, navChunkSize)
+			data, err := chunk.encodeBlocks()
+			if err != nil {
+				errs <- err
+				return
+			}
+			// chunk centers are negated, see chunkCenter
+			x := int32(i * navChunkSize)
+			for _, p := range []Packet{
+				&ChunkDataPacket{X: -x, Size: navChunkSize, Height: navChunkSize, Data: data},
+				&BlockChangePacket{X: x, Y: 5, Type: BlockTypeRock},
+			} {
+				if err := pc.Write(p); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}
+		errs <- pc.Write(&ChatPacket{Sender: "server", Message: "done"})
+	}()
+
+	c, err := NewClient(clientSide, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	// nobody reads the disconnect of the client
+	defer serverSide.Close()
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(loopbackTimeout)
+	for len(c.Chat) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the last packet")
+		}
+		if err := c.Poll(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// everything before the message is applied, a change that overtook
+	// its chunk would have been lost
+	for i := 0; i < chunks; i++ {
+		if !c.World.Chunks.Loaded(rl.NewVector3(float32(i*navChunkSize), 0, 0)) {
+			t.Errorf("chunk %d is not loaded", i)
+		} else if b := c.World.Chunks.BlockAt(i*navChunkSize, 5, 0); b == nil || b.blockType != BlockTypeRock {
+			t.Errorf("block of chunk %d is %v, want rock", i, b)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package gocraft
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+var ErrWorldExists = errors.New("a world with this name already exists")
+
+// GameState is the screen the engine is in. Only StatePlaying takes the
+// mouse and moves the player.
+type GameState int
+
+const (
+	StateMainMenu GameState = iota
+	StateWorldSelect
+	StateCreateWorld
+	StatePlaying
+	StatePaused
+	StateSettings
+	StateBindings
+)
+
+func (s GameState) String() string {
+	switch s {
+	case StateMainMenu:
+		return "main menu"
+	case StateWorldSelect:
+		return "world select"
+	case StateCreateWorld:
+		return "create world"
+	case StatePlaying:
+		return "playing"
+	case StatePaused:
+		return "paused"
+	case StateSettings:
+		return "settings"
+	case StateBindings:
+		return "key bindings"
+	}
+	return "unknown"
+}
+
+// inGame reports whether the world is shown behind the screen.
+func (s *engine) inGame() bool {
+	return s.world != nil && s.player != nil
+}
+
+// setState switches the screen and frees or captures the mouse.
+func setState(s *engine, state GameState) {
+	s.state = state
+	s.menu.message = ""
+	s.input.SetMenu(state != StatePlaying || s.inventory.Open)
+}
+
+// menuState is what the menu screens keep between frames.
+type menuState struct {
+	ui      UI
+	message string
+	worlds  []WorldInfo
+	scroll  int
+	// settingsBack is the screen the settings return to.
+	settingsBack GameState
+	newName      string
+	newSeed      string
+}
+
+// back leaves the current screen like its back button.
+func back(s *engine) {
+	switch s.state {
+	case StateWorldSelect:
+		setState(s, StateMainMenu)
+	case StateCreateWorld:
+		openWorldSelect(s)
+	case StatePaused:
+		setState(s, StatePlaying)
+	case StateSettings:
+		saveSettings(s)
+		setState(s, s.menu.settingsBack)
+	case StateBindings:
+		saveSettings(s)
+		setState(s, StateSettings)
+	}
+}
+
+// drawMenu draws the screen of the current state and handles its input.
+func drawMenu(s *engine) {
+	if s.state == StatePlaying {
+		return
+	}
+	if s.inGame() {
+		s.renderer.DrawRectangle(screenRect(s.renderer), rl.Fade(rl.Black, 0.5))
+	}
+
+	_, h := s.renderer.ScreenSize()
+	ui := &s.menu.ui
+	ui.Begin(s.renderer, float32(h)/8)
+	switch s.state {
+	case StateMainMenu:
+		drawMainMenu(s, ui)
+	case StateWorldSelect:
+		drawWorldSelect(s, ui)
+	case StateCreateWorld:
+		drawCreateWorld(s, ui)
+	case StatePaused:
+		drawPauseMenu(s, ui)
+	case StateSettings:
+		drawSettings(s, ui)
+	case StateBindings:
+		drawBindings(s, ui)
+	}
+	if s.menu.message != "" {
+		ui.Space(uiSpacing)
+		ui.Label(s.menu.message)
+	}
+}
+
+func drawMainMenu(s *engine, ui *UI) {
+	ui.Title(s.title)
+	ui.Space(uiWidgetHeight)
+	if ui.Button("Singleplayer") {
+		openWorldSelect(s)
+	}
+	if ui.Button("Settings") {
+		openSettings(s)
+	}
+	if ui.Button("Quit") {
+		s.quit = true
+	}
+}
+
+func openWorldSelect(s *engine) {
+	setState(s, StateWorldSelect)
+	s.menu.scroll = 0
+	dir, err := SavesDir()
+	if err == nil {
+		s.menu.worlds, err = ListWorlds(dir)
+	}
+	if err != nil {
+		s.menu.message = err.Error()
+	}
+}
+
+// menuListRows is how many entries of a list fit on a screen.
+const menuListRows = 6
+
+// scrollList moves the first shown entry of a list with the mouse wheel.
+func scrollList(s *engine, ui *UI, n int) (int, int) {
+	s.menu.scroll -= int(ui.Wheel())
+	s.menu.scroll = int(clamp32(float32(s.menu.scroll), 0, float32(maxInt(n-menuListRows, 0))))
+	return s.menu.scroll, minInt(s.menu.scroll+menuListRows, n)
+}
+
+func drawWorldSelect(s *engine, ui *UI) {
+	ui.Title("Select World")
+	if len(s.menu.worlds) == 0 {
+		ui.Label("no worlds yet")
+	}
+	from, to := scrollList(s, ui, len(s.menu.worlds))
+	for _, w := range s.menu.worlds[from:to] {
+		if ui.Button(fmt.Sprintf("%s (seed %d)", w.Name, w.Meta.Seed)) {
+			if err := startWorld(s, w.Dir, w.Meta.Seed); err != nil {
+				s.menu.message = err.Error()
+			}
+		}
+	}
+	ui.Space(uiSpacing)
+	switch ui.Buttons("Create new", "Back") {
+	case 0:
+		setState(s, StateCreateWorld)
+		s.menu.newName = "New World"
+		s.menu.newSeed = strconv.Itoa(s.seed)
+	case 1:
+		back(s)
+	}
+}
+
+func drawCreateWorld(s *engine, ui *UI) {
+	ui.Title("Create World")
+	ui.TextField("name", "World name", &s.menu.newName, 32)
+	ui.TextField("seed", "Seed (empty for random)", &s.menu.newSeed, 32)
+	ui.Space(uiSpacing)
+	switch ui.Buttons("Create", "Cancel") {
+	case 0:
+		if err := createWorld(s, s.menu.newName, s.menu.newSeed); err != nil {
+			s.menu.message = err.Error()
+		}
+	case 1:
+		back(s)
+	}
+}
+
+// parseSeed turns the text of the seed field into a seed. Numbers are
+// used as they are, other text is hashed, empty text is random.
+func parseSeed(text string) int {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return int(rand.Int31())
+	}
+	if n, err := strconv.Atoi(text); err == nil {
+		return n
+	}
+	h := fnv.New32a()
+	h.Write([]byte(text))
+	return int(int32(h.Sum32()))
+}
+
+func createWorld(s *engine, name, seed string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("the world needs a name")
+	}
+	saves, err := SavesDir()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(saves, safeFileName(name))
+	if _, err := os.Stat(dir); err == nil {
+		return ErrWorldExists
+	}
+	return startWorld(s, dir, parseSeed(seed))
+}
+
+// startWorld opens the world in dir, or creates it with seed, and
+// starts playing.
+func startWorld(s *engine, dir string, seed int) error {
+	store, err := NewWorldStore(dir)
+	if err != nil {
+		return err
+	}
+	terrain := s.config.Terrain
+	world, err := OpenWorld(store, WorldMeta{Seed: seed, ChunkSize: s.config.ChunkSize, Terrain: &terrain})
+	if err != nil {
+		return err
+	}
+	world.ViewDistance = s.settings.RenderDistance
+	s.world = world
+	s.player = world.SpawnSaved(s.name)
+	s.player.MoveSpeed = s.config.MoveSpeed
+	s.camera.Mode = CameraModeFirstPerson
+	if err := world.Save(); err != nil {
+		rl.TraceLog(rl.LogWarning, "world: %s", err.Error())
+	}
+	setState(s, StatePlaying)
+	return nil
+}
+
+// leaveWorld saves and closes the single player world.
+func leaveWorld(s *engine) {
+	if s.world == nil || s.client != nil {
+		return
+	}
+	if s.inventory.Open {
+		toggleInventory(s)
+	}
+	if err := s.world.SavePlayer(s.name, s.player); err != nil {
+		rl.TraceLog(rl.LogWarning, "player: %s", err.Error())
+	}
+	if err := s.world.Save(); err != nil {
+		rl.TraceLog(rl.LogWarning, "world: %s", err.Error())
+	}
+	s.world, s.player = nil, nil
+}
+
+func drawPauseMenu(s *engine, ui *UI) {
+	ui.Title("Game paused")
+	if ui.Button("Back to game") {
+		back(s)
+	}
+	if ui.Button("Settings") {
+		openSettings(s)
+	}
+	if s.client != nil {
+		if ui.Button("Disconnect") {
+			s.quit = true
+		}
+		return
+	}
+	if ui.Button("Save and quit to title") {
+		leaveWorld(s)
+		setState(s, StateMainMenu)
+	}
+}
+
+func openSettings(s *engine) {
+	s.menu.settingsBack = s.state
+	setState(s, StateSettings)
+}
+
+func drawSettings(s *engine, ui *UI) {
+	ui.Title("Settings")
+	var (
+		set      = &s.settings
+		distance = float32(set.RenderDistance)
+		fps      = float32(set.MaxFPS)
+		changed  bool
+	)
+	if s.client == nil {
+		if ui.Slider("Render distance", &distance, minRenderDistance, maxRenderDistance, 1, "%.0f chunks") {
+			set.RenderDistance = int(distance)
+			changed = true
+		}
+	} else {
+		ui.Label(fmt.Sprintf("Render distance: %d chunks (set by the server)", s.world.ViewDistance))
+	}
+	far := float32(set.FarDistance)
+	farFormat := "%.0f chunks"
+	if set.FarDistance == 0 {
+		farFormat = "off (%.0f)"
+	}
+	if ui.Slider("Far terrain", &far, 0, maxFarDistance, 1, farFormat) {
+		set.FarDistance = int(far)
+	}
+	changed = ui.Slider("FOV", &set.FOV, minFOV, maxFOV, 1, "%.0f") || changed
+	changed = ui.Slider("Sensitivity", &set.Sensitivity, minSensitivity, maxSensitivity, 0.05, "%.2f") || changed
+	smoothing := "%.2f s"
+	if set.Smoothing == 0 {
+		smoothing = "off (%.2f)"
+	}
+	changed = ui.Slider("Camera smoothing", &set.Smoothing, 0, maxSmoothing, 0.05, smoothing) || changed
+	format := "%.0f"
+	if set.MaxFPS == 0 {
+		format = "unlimited (%.0f)"
+	}
+	if ui.Slider("FPS cap", &fps, 0, maxFPSCap, 10, format) {
+		set.MaxFPS = int(fps)
+		changed = true
+	}
+	changed = ui.Toggle("VSync", &set.VSync) || changed
+	ui.Toggle("Cave culling", &set.CaveCulling)
+	ui.Toggle("Minimap", &set.Minimap)
+	ui.Toggle("Rotate minimap", &set.MinimapRotate)
+	if changed {
+		applySettings(s)
+	}
+
+	ui.Space(uiSpacing)
+	if ui.Button("Key bindings") {
+		setState(s, StateBindings)
+		s.menu.scroll = 0
+	}
+	if ui.Button("Done") {
+		back(s)
+	}
+}
+
+func drawBindings(s *engine, ui *UI) {
+	ui.Title("Key bindings")
+
+	actions := make([]Action, 0, len(s.input.bindings))
+	for a := range s.input.bindings {
+		actions = append(actions, a)
+	}
+	sort.Slice(actions, func(i, j int) bool { return actions[i] < actions[j] })
+
+	waiting, rebinding := s.input.Rebinding()
+	from, to := scrollList(s, ui, len(actions))
+	for _, a := range actions[from:to] {
+		label := fmt.Sprintf("%s: %s", a, bindingNames(s.input.Bindings(a)))
+		if rebinding && a == waiting {
+			label = fmt.Sprintf("%s: press a key (escape cancels)", a)
+		}
+		if ui.Button(label) && !rebinding {
+			s.input.Rebind(a)
+		}
+	}
+	ui.Label(fmt.Sprintf("%d-%d of %d, scroll for more", from+1, to, len(actions)))
+
+	ui.Space(uiSpacing)
+	switch ui.Buttons("Reset all", "Done") {
+	case 0:
+		s.input.bindings = defaultBindings()
+	case 1:
+		back(s)
+	}
+}
+
+func bindingNames(bindings []Binding) string {
+	if len(bindings) == 0 {
+		return "none"
+	}
+	names := make([]string, len(bindings))
+	for i, b := range bindings {
+		names[i] = b.String()
+	}
+	return strings.Join(names, ", ")
+}
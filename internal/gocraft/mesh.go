@@ -0,0 +1,180 @@
+package gocraft
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+var ErrInvalidOBJ = errors.New("invalid obj file")
+
+// MeshData is a list of triangles, every three vertices make one. The
+// texture coordinates have their origin at the top left of the texture
+// like in raylib.
+type MeshData struct {
+	Positions []rl.Vector3
+	Normals   []rl.Vector3
+	Texcoords []rl.Vector2
+}
+
+// Triangles is the number of triangles of the mesh.
+func (m *MeshData) Triangles() int {
+	return len(m.Positions) / 3
+}
+
+// Bounds is the box around all vertices.
+func (m *MeshData) Bounds() AABB {
+	if len(m.Positions) == 0 {
+		return AABB{}
+	}
+	box := AABB{Min: m.Positions[0], Max: m.Positions[0]}
+	for _, p := range m.Positions[1:] {
+		box.Min = rl.Vector3Min(box.Min, p)
+		box.Max = rl.Vector3Max(box.Max, p)
+	}
+	return box
+}
+
+// ParseOBJ reads the geometry of a wavefront obj file. Faces with more
+// than three vertices are split into triangles, materials and groups are
+// ignored.
+func ParseOBJ(r io.Reader) (*MeshData, error) {
+	var (
+		positions []rl.Vector3
+		normals   []rl.Vector3
+		texcoords []rl.Vector2
+		mesh      = &MeshData{}
+		scanner   = bufio.NewScanner(r)
+		line      = 0
+	)
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: line %d: %s", ErrInvalidOBJ, line, fmt.Sprintf(format, args...))
+	}
+	floats := func(fields []string, n int) ([]float32, error) {
+		if len(fields) < n {
+			return nil, invalid("%d values expected", n)
+		}
+		v := make([]float32, n)
+		for i := range v {
+			f, err := strconv.ParseFloat(fields[i], 32)
+			if err != nil {
+				return nil, invalid("%s", err.Error())
+			}
+			v[i] = float32(f)
+		}
+		return v, nil
+	}
+	// index resolves a 1 based or negative relative index
+	index := func(s string, n int) (int, error) {
+		i, err := strconv.Atoi(s)
+		switch {
+		case err != nil:
+			return 0, invalid("%s", err.Error())
+		case i < 0:
+			i += n
+		default:
+			i--
+		}
+		if i < 0 || i >= n {
+			return 0, invalid("index %s out of range", s)
+		}
+		return i, nil
+	}
+
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "v":
+			v, err := floats(fields[1:], 3)
+			if err != nil {
+				return nil, err
+			}
+			positions = append(positions, rl.NewVector3(v[0], v[1], v[2]))
+		case "vn":
+			v, err := floats(fields[1:], 3)
+			if err != nil {
+				return nil, err
+			}
+			normals = append(normals, rl.NewVector3(v[0], v[1], v[2]))
+		case "vt":
+			v, err := floats(fields[1:], 2)
+			if err != nil {
+				return nil, err
+			}
+			// obj has the origin at the bottom left
+			texcoords = append(texcoords, rl.NewVector2(v[0], 1-v[1]))
+		case "f":
+			if len(fields) < 4 {
+				return nil, invalid("a face needs three vertices")
+			}
+			type vertex struct {
+				p, t, n int
+			}
+			var face []vertex
+			for _, f := range fields[1:] {
+				parts := strings.Split(f, "/")
+				v := vertex{t: -1, n: -1}
+				var err error
+				if v.p, err = index(parts[0], len(positions)); err != nil {
+					return nil, err
+				}
+				if len(parts) > 1 && parts[1] != "" {
+					if v.t, err = index(parts[1], len(texcoords)); err != nil {
+						return nil, err
+					}
+				}
+				if len(parts) > 2 && parts[2] != "" {
+					if v.n, err = index(parts[2], len(normals)); err != nil {
+						return nil, err
+					}
+				}
+				face = append(face, v)
+			}
+			for i := 1; i+1 < len(face); i++ {
+				for _, v := range []vertex{face[0], face[i], face[i+1]} {
+					mesh.Positions = append(mesh.Positions, positions[v.p])
+					var (
+						n rl.Vector3
+						t rl.Vector2
+					)
+					if v.n >= 0 {
+						n = normals[v.n]
+					}
+					if v.t >= 0 {
+						t = texcoords[v.t]
+					}
+					mesh.Normals = append(mesh.Normals, n)
+					mesh.Texcoords = append(mesh.Texcoords, t)
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return mesh, nil
+}
+
+// WriteOBJ writes the mesh as obj file, with one vertex of each kind per
+// triangle corner.
+func (m *MeshData) WriteOBJ(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for i, p := range m.Positions {
+		fmt.Fprintf(bw, "v %g %g %g\n", p.X, p.Y, p.Z)
+		fmt.Fprintf(bw, "vt %g %g\n", m.Texcoords[i].X, 1-m.Texcoords[i].Y)
+		fmt.Fprintf(bw, "vn %g %g %g\n", m.Normals[i].X, m.Normals[i].Y, m.Normals[i].Z)
+	}
+	for i := 1; i+2 <= len(m.Positions); i += 3 {
+		fmt.Fprintf(bw, "f %d/%d/%d %d/%d/%d %d/%d/%d\n", i, i, i, i+1, i+1, i+1, i+2, i+2, i+2)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,148 @@
+package gocraft
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const (
+	// minimapSize is the number of blocks shown across the minimap, one
+	// pixel each.
+	minimapSize         = 128
+	minimapMargin int32 = 8
+	// minimapRefresh is how often the minimap is redrawn in seconds while
+	// the player stands still, so chunks that load show up.
+	minimapRefresh float32 = 0.5
+	// minimapTurn is how many degrees the player turns before a rotating
+	// minimap is redrawn.
+	minimapTurn = 1
+)
+
+var minimapBackground = rl.NewColor(20, 20, 20, 255)
+
+// Minimap shows the map around the player in the top right corner of the
+// HUD. The player is in the middle, north is up unless the map rotates
+// with the player.
+type Minimap struct {
+	Visible bool
+	// Rotate turns the map so that the player always looks up.
+	Rotate bool
+	// Map is drawn from, the minimap is hidden without one.
+	Map *MapRenderer
+
+	image   *image.RGBA
+	texture Texture
+	loaded  bool
+
+	// yaw is where the player looks, the image was drawn at x, z for
+	// mapYaw
+	yaw     float32
+	x, z    int
+	mapYaw  float32
+	rotated bool
+	age     float32
+}
+
+func NewMinimap() *Minimap {
+	return &Minimap{
+		Visible: true,
+		Rotate:  true,
+		image:   image.NewRGBA(image.Rect(0, 0, minimapSize, minimapSize)),
+	}
+}
+
+// Update redraws the minimap for the player at pos looking along yaw when
+// the player moved or turned.
+func (m *Minimap) Update(r Renderer, pos rl.Vector3, yaw, dt float32) {
+	if !m.Visible || m.Map == nil {
+		return
+	}
+	x, _, z := blockCoord(pos)
+	m.yaw = yaw
+	m.age += dt
+	turned := m.Rotate && math.Abs(float64(shortestAngle(m.mapYaw, yaw))) >= minimapTurn
+	if m.loaded && x == m.x && z == m.z && !turned && m.Rotate == m.rotated && m.age < minimapRefresh {
+		return
+	}
+	m.x, m.z, m.mapYaw, m.rotated, m.age = x, z, yaw, m.Rotate, 0
+
+	m.Map.Prune()
+	front, right := m.axes()
+	for py := 0; py < minimapSize; py++ {
+		for px := 0; px < minimapSize; px++ {
+			// offset from the player, up on the map is front
+			u := float32(px - minimapSize/2)
+			v := float32(minimapSize/2 - py)
+			wx := x + int(floor32(u*right.X+v*front.X+0.5))
+			wz := z + int(floor32(u*right.Y+v*front.Y+0.5))
+			c, ok := m.Map.ColumnColor(wx, wz)
+			if !ok || c.A == 0 {
+				c = minimapBackground
+			}
+			m.image.SetRGBA(px, py, c)
+		}
+	}
+	if !m.loaded {
+		m.texture = r.LoadTexture(m.image)
+		m.loaded = true
+	} else {
+		r.UpdateTexture(m.texture, m.image)
+	}
+}
+
+// axes are the directions in world x, z that are up and right on the
+// map.
+func (m *Minimap) axes() (front, right rl.Vector2) {
+	if !m.Rotate {
+		return rl.NewVector2(0, -1), rl.NewVector2(1, 0)
+	}
+	front = rl.NewVector2(cos32(radians(m.mapYaw)), sin32(radians(m.mapYaw)))
+	return front, rl.NewVector2(-front.Y, front.X)
+}
+
+// onMap is the direction on the map, y down, of a direction in world
+// x, z.
+func (m *Minimap) onMap(dir rl.Vector2) rl.Vector2 {
+	front, right := m.axes()
+	return rl.NewVector2(rl.Vector2DotProduct(dir, right), -rl.Vector2DotProduct(dir, front))
+}
+
+// Draw draws the minimap with the player marker, the north marker and
+// the position of the player.
+func (m *Minimap) Draw(r Renderer) {
+	if !m.Visible || m.Map == nil || !m.loaded {
+		return
+	}
+	var (
+		sw, _  = r.ScreenSize()
+		size   = int32(minimapSize)
+		x      = sw - size - minimapMargin
+		y      = minimapMargin
+		center = rl.NewVector2(float32(x+size/2), float32(y+size/2))
+	)
+	r.DrawRectangle(recti(x-2, y-2, size+4, size+4), rl.Fade(rl.Black, 0.6))
+	r.DrawTexture(m.texture, recti(0, 0, size, size), recti(x, y, size, size), rl.White)
+	r.DrawRectangleLines(recti(x-2, y-2, size+4, size+4), 1, rl.Gray)
+
+	// the marker points where the player looks
+	look := m.onMap(rl.NewVector2(cos32(radians(m.yaw)), sin32(radians(m.yaw))))
+	r.DrawCircle(center, 4, rl.Black)
+	r.DrawCircle(center, 3, rl.White)
+	r.DrawLine(center, rl.Vector2Add(center, rl.Vector2Scale(look, 9)), rl.White)
+	r.DrawLine(rl.Vector2Add(center, rl.NewVector2(-look.Y, look.X)), rl.Vector2Add(center, rl.Vector2Scale(look, 8)), rl.White)
+	r.DrawLine(rl.Vector2Add(center, rl.NewVector2(look.Y, -look.X)), rl.Vector2Add(center, rl.Vector2Scale(look, 8)), rl.White)
+
+	// N sits on the edge in the direction of north
+	north := m.onMap(rl.NewVector2(0, -1))
+	edge := float32(size/2-8) / float32(math.Max(math.Abs(float64(north.X)), math.Abs(float64(north.Y))))
+	n := rl.Vector2Add(center, rl.Vector2Scale(north, edge))
+	r.DrawCircle(n, 7, rl.Fade(rl.Black, 0.6))
+	textW := r.MeasureText("N", 10)
+	r.DrawText("N", int32(n.X)-textW/2, int32(n.Y)-5, 10, rl.White)
+
+	label := fmt.Sprintf("%d, %d", m.x, m.z)
+	r.DrawText(label, x+size-r.MeasureText(label, 10), y+size+4, 10, rl.White)
+}
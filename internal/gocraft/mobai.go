@@ -0,0 +1,264 @@
+package gocraft
+
+import (
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const (
+	// navReachDistance is how close a waypoint has to be to count as reached.
+	navReachDistance float32 = 0.3
+	// navStuckTime drops a path when the entity did not get closer to its
+	// next waypoint for this long.
+	navStuckTime float32 = 2
+
+	mobWanderRadius  = 8
+	mobFollowRange   = 12
+	mobFollowStop    = 2
+	mobScareRange    = 6
+	mobFleeDistance  = 10
+	mobFleeTime      = 4
+	mobReplanTime    = 1
+	mobReplanMove    = 2
+	mobWanderIdleMin = 2
+	mobWanderIdleMax = 6
+)
+
+// Navigator walks an entity along paths found by PathSearch. The search
+// takes nodes from the budget of the world, so many mobs looking for a
+// path at once only slow down each other instead of the tick.
+type Navigator struct {
+	Profile NavProfile
+	Speed   float32
+
+	search *PathSearch
+	path   []rl.Vector3
+	next   int
+
+	stuck    float32
+	bestDist float32
+}
+
+// MoveTo starts searching a path from the entity to target.
+func (n *Navigator) MoveTo(w *World, e *Entity, target rl.Vector3) {
+	n.search = NewPathSearch(w.Chunks, n.Profile, e.Position, target)
+	n.path = nil
+	n.next = 0
+}
+
+func (n *Navigator) Stop() {
+	n.search = nil
+	n.path = nil
+}
+
+// Idle reports whether the navigator neither searches nor walks.
+func (n *Navigator) Idle() bool {
+	return n.search == nil && n.next >= len(n.path)
+}
+
+// Update continues the search and steers the entity towards the next
+// waypoint. Paths that only lead close to the target are walked as well.
+func (n *Navigator) Update(w *World, e *Entity, dt float32) {
+	if n.search != nil {
+		budget := w.pathBudget
+		w.pathBudget -= n.search.Step(budget)
+		if !n.search.Done() {
+			e.Velocity.X, e.Velocity.Z = 0, 0
+			return
+		}
+		n.path, _ = n.search.Path()
+		n.search = nil
+		n.next = 0
+		n.stuck = 0
+		n.bestDist = float32(math.Inf(1))
+	}
+
+	if n.next >= len(n.path) {
+		e.Velocity.X, e.Velocity.Z = 0, 0
+		return
+	}
+
+	wp := n.path[n.next]
+	to := rl.NewVector3(wp.X-e.Position.X, 0, wp.Z-e.Position.Z)
+	dist := rl.Vector3Length(to)
+	if dist < navReachDistance && math.Abs(float64(wp.Y-e.Position.Y)) < 1 {
+		n.next++
+		n.stuck = 0
+		n.bestDist = float32(math.Inf(1))
+		return
+	}
+
+	if dist < n.bestDist-0.05 {
+		n.bestDist = dist
+		n.stuck = 0
+	} else if n.stuck += dt; n.stuck > navStuckTime {
+		n.Stop()
+		return
+	}
+
+	speed := n.Speed
+	if dist < speed*dt {
+		speed = dist / dt
+	}
+	dir := rl.Vector3Normalize(to)
+	e.Velocity.X = dir.X * speed
+	e.Velocity.Z = dir.Z * speed
+	e.Yaw = degrees(float32(math.Atan2(float64(dir.Z), float64(dir.X))))
+
+	if e.OnGround && (wp.Y > e.Position.Y+0.5 || e.Blocked) {
+		e.Velocity.Y = mobJumpVelocity
+	}
+}
+
+type MobState int
+
+const (
+	MobStateWander MobState = iota
+	MobStateFollow
+	MobStateFlee
+)
+
+func (s MobState) String() string {
+	switch s {
+	case MobStateFollow:
+		return "follow"
+	case MobStateFlee:
+		return "flee"
+	}
+	return "wander"
+}
+
+// MobBehaviour is the brain of a simple animal. It wanders around, follows
+// players that sneak nearby and runs away from sprinting players.
+type MobBehaviour struct {
+	State MobState
+	Nav   Navigator
+
+	timer  float32
+	target rl.Vector3
+}
+
+func NewMobBehaviour() *MobBehaviour {
+	return &MobBehaviour{
+		Nav: Navigator{
+			Profile: NavProfile{
+				Width:      mobWidth,
+				Height:     mobHeight,
+				JumpHeight: 1,
+				MaxFall:    3,
+			},
+			Speed: mobWalkSpeed,
+		},
+	}
+}
+
+func (b *MobBehaviour) Update(e *Entity, w *World, dt float32) {
+	b.timer -= dt
+	player, dist := nearestPlayer(w, e.Position)
+
+	switch {
+	case player != nil && player.Sprinting && dist < mobScareRange:
+		b.flee(e, w, player)
+	case b.State == MobStateFlee && b.timer > 0:
+	case player != nil && player.Sneaking && dist < mobFollowRange:
+		b.follow(e, w, player, dist)
+	default:
+		b.wander(e, w)
+	}
+	b.Nav.Update(w, e, dt)
+}
+
+func (b *MobBehaviour) setState(state MobState) {
+	if b.State != state {
+		b.State = state
+		b.Nav.Stop()
+		b.timer = 0
+	}
+}
+
+func (b *MobBehaviour) wander(e *Entity, w *World) {
+	b.setState(MobStateWander)
+	b.Nav.Speed = mobWalkSpeed
+	if !b.Nav.Idle() || b.timer > 0 {
+		return
+	}
+	b.timer = mobWanderIdleMin + w.rng.Float32()*(mobWanderIdleMax-mobWanderIdleMin)
+
+	var (
+		x, y, z = blockCoord(e.Position)
+		tx      = x + w.rng.Intn(2*mobWanderRadius+1) - mobWanderRadius
+		tz      = z + w.rng.Intn(2*mobWanderRadius+1) - mobWanderRadius
+	)
+	if ty, ok := findGround(w.Chunks, b.Nav.Profile, tx, y, tz); ok {
+		b.Nav.MoveTo(w, e, rl.NewVector3(float32(tx), float32(ty), float32(tz)))
+	}
+}
+
+func (b *MobBehaviour) follow(e *Entity, w *World, p *Player, dist float32) {
+	b.setState(MobStateFollow)
+	b.Nav.Speed = mobWalkSpeed
+	if dist < mobFollowStop {
+		b.Nav.Stop()
+		return
+	}
+	moved := rl.Vector3Distance(b.target, p.Position) > mobReplanMove
+	if b.Nav.Idle() || b.timer <= 0 && moved {
+		b.timer = mobReplanTime
+		b.target = p.Position
+		b.Nav.MoveTo(w, e, p.Position)
+	}
+}
+
+func (b *MobBehaviour) flee(e *Entity, w *World, p *Player) {
+	if b.State == MobStateFlee && !b.Nav.Idle() {
+		b.timer = mobFleeTime
+		return
+	}
+	b.setState(MobStateFlee)
+	b.Nav.Speed = mobRunSpeed
+	b.timer = mobFleeTime
+
+	away := rl.Vector3Subtract(e.Position, p.Position)
+	away.Y = 0
+	if rl.Vector3Length(away) == 0 {
+		away = rl.NewVector3(1, 0, 0)
+	}
+	away = rl.Vector3Scale(rl.Vector3Normalize(away), mobFleeDistance)
+
+	var (
+		_, y, _   = blockCoord(e.Position)
+		tx, _, tz = blockCoord(rl.Vector3Add(e.Position, away))
+	)
+	if ty, ok := findGround(w.Chunks, b.Nav.Profile, tx, y, tz); ok {
+		b.Nav.MoveTo(w, e, rl.NewVector3(float32(tx), float32(ty), float32(tz)))
+	}
+}
+
+// nearestPlayer returns the closest player and its distance.
+func nearestPlayer(w *World, pos rl.Vector3) (*Player, float32) {
+	var (
+		nearest *Player
+		best    = float32(math.Inf(1))
+	)
+	for _, p := range w.Players {
+		if d := rl.Vector3Distance(p.Position, pos); d < best {
+			nearest, best = p, d
+		}
+	}
+	return nearest, best
+}
+
+// findGround searches the column x, z around y for a block the profile
+// can stand on.
+func findGround(world VoxelWorld, profile NavProfile, x, y, z int) (int, bool) {
+	for dy := 0; dy <= mobWanderRadius; dy++ {
+		if profile.canStand(world, x, y+dy, z) {
+			return y + dy, true
+		}
+		if profile.canStand(world, x, y-dy, z) {
+			return y - dy, true
+		}
+	}
+	return 0, false
+}
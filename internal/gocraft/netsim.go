@@ -0,0 +1,84 @@
+package gocraft
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// LinkConditions make a connection behave like a bad network. Every
+// packet is delayed by Latency plus a random value in -Jitter..Jitter,
+// so packets sent closer together than the jitter arrive out of order.
+type LinkConditions struct {
+	Latency time.Duration
+	Jitter  time.Duration
+	// Seed makes the random delays reproducible.
+	Seed int64
+}
+
+// Ideal reports whether packets go through without delay.
+func (lc LinkConditions) Ideal() bool {
+	return lc.Latency <= 0 && lc.Jitter <= 0
+}
+
+// conditionedLink delays the packets written to link. Reads are passed
+// through, conditioning both ends of a connection affects both directions.
+type conditionedLink struct {
+	link       packetLink
+	conditions LinkConditions
+
+	mu      sync.Mutex
+	rng     *rand.Rand
+	err     error
+	started bool
+}
+
+func newConditionedLink(link packetLink, conditions LinkConditions) packetLink {
+	if conditions.Ideal() {
+		return link
+	}
+	return &conditionedLink{
+		link:       link,
+		conditions: conditions,
+		rng:        rand.New(rand.NewSource(conditions.Seed)),
+	}
+}
+
+func (l *conditionedLink) Read() (Packet, error) {
+	return l.link.Read()
+}
+
+// Write schedules p and returns right away, only the first packet is
+// written at once. Errors of the delayed writes are returned by the next
+// call.
+func (l *conditionedLink) Write(p Packet) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return l.err
+	}
+	// the handshake and the login have to arrive before anything else,
+	// like on a real connection
+	if !l.started {
+		l.started = true
+		l.err = l.link.Write(p)
+		return l.err
+	}
+
+	delay := l.conditions.Latency
+	if l.conditions.Jitter > 0 {
+		delay += time.Duration(l.rng.Int63n(int64(2*l.conditions.Jitter))) - l.conditions.Jitter
+	}
+	if delay < 0 {
+		delay = 0
+	}
+
+	time.AfterFunc(delay, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.err == nil {
+			l.err = l.link.Write(p)
+		}
+	})
+	return nil
+}
@@ -0,0 +1,76 @@
+package gocraft
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingLink remembers the packets written to it.
+type recordingLink struct {
+	mu      sync.Mutex
+	written []Packet
+}
+
+func (l *recordingLink) Read() (Packet, error) {
+	select {}
+}
+
+func (l *recordingLink) Write(p Packet) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.written = append(l.written, p)
+	return nil
+}
+
+func (l *recordingLink) nonces() []uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var nonces []uint64
+	for _, p := range l.written {
+		nonces = append(nonces, p.(*KeepAlivePacket).Nonce)
+	}
+	return nonces
+}
+
+func TestConditionedLinkReorders(t *testing.T) {
+	const n = 50
+	rec := &recordingLink{}
+	link := newConditionedLink(rec, LinkConditions{Latency: 10 * time.Millisecond, Jitter: 10 * time.Millisecond, Seed: 1})
+	for i := 0; i < n; i++ {
+		if err := link.Write(&KeepAlivePacket{Nonce: uint64(i)}); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Microsecond)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(rec.nonces()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of %d packets arrived", len(rec.nonces()), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	nonces := rec.nonces()
+	seen := make(map[uint64]bool)
+	reordered := false
+	for i, nonce := range nonces {
+		seen[nonce] = true
+		if i > 0 && nonce < nonces[i-1] {
+			reordered = true
+		}
+	}
+	if len(seen) != n {
+		t.Errorf("%d different packets arrived, want %d", len(seen), n)
+	}
+	if !reordered {
+		t.Errorf("packets arrived in order: %v", nonces)
+	}
+}
+
+func TestIdealLinkIsNotWrapped(t *testing.T) {
+	rec := &recordingLink{}
+	if link := newConditionedLink(rec, LinkConditions{}); link != packetLink(rec) {
+		t.Errorf("ideal conditions wrapped the link in %T", link)
+	}
+}
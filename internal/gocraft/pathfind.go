@@ -0,0 +1,266 @@
+package gocraft
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+var ErrNoPath = errors.New("no path found")
+
+const (
+	// pathNodesPerTick is shared by all searches of a world in one tick.
+	pathNodesPerTick = 400
+	// pathMaxNodes ends a search that did not reach its goal, the path
+	// then leads to the closest node found.
+	pathMaxNodes = 2000
+	pathJumpCost = 0.5
+	pathFallCost = 0.2
+)
+
+// NavProfile describes how an entity can move through the voxel grid.
+type NavProfile struct {
+	Width, Height float32
+	// JumpHeight is how many blocks the entity can climb in one move.
+	JumpHeight int
+	// MaxFall is how many blocks it is willing to drop down.
+	MaxFall int
+}
+
+// footprint returns the number of blocks the entity covers horizontally
+// and vertically.
+func (p NavProfile) footprint() (int, int) {
+	return int(math.Ceil(float64(p.Width))), int(math.Ceil(float64(p.Height)))
+}
+
+// clear reports whether the body of the entity fits with its feet in
+// block x, y, z. Wide entities occupy x..x+n-1 and z..z+n-1.
+func (p NavProfile) clear(world VoxelWorld, x, y, z int) bool {
+	n, h := p.footprint()
+	for dx := 0; dx < n; dx++ {
+		for dz := 0; dz < n; dz++ {
+			for dy := 0; dy < h; dy++ {
+				if world.IsSolid(x+dx, y+dy, z+dz) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// canStand reports whether the entity fits at x, y, z and has ground below.
+func (p NavProfile) canStand(world VoxelWorld, x, y, z int) bool {
+	return world.IsSolid(x, y-1, z) && p.clear(world, x, y, z)
+}
+
+type pathNode struct {
+	pos    [3]int
+	g, f   float32
+	parent *pathNode
+	index  int
+	closed bool
+}
+
+type pathHeap []*pathNode
+
+func (h pathHeap) Len() int           { return len(h) }
+func (h pathHeap) Less(i, j int) bool { return h[i].f < h[j].f }
+func (h pathHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *pathHeap) Push(x interface{}) {
+	n := x.(*pathNode)
+	n.index = len(*h)
+	*h = append(*h, n)
+}
+func (h *pathHeap) Pop() interface{} {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
+
+// PathSearch is an A* search over the voxel grid that can be spread over
+// several ticks. It works across chunk borders, blocks of chunks that are
+// not loaded are not solid and therefore never walkable.
+type PathSearch struct {
+	world   VoxelWorld
+	profile NavProfile
+	goal    [3]int
+
+	open     pathHeap
+	nodes    map[[3]int]*pathNode
+	best     *pathNode
+	expanded int
+
+	done bool
+	path []rl.Vector3
+	err  error
+}
+
+func NewPathSearch(world VoxelWorld, profile NavProfile, from, to rl.Vector3) *PathSearch {
+	s := &PathSearch{
+		world:   world,
+		profile: profile,
+		nodes:   make(map[[3]int]*pathNode),
+	}
+	sx, sy, sz := blockCoord(from)
+	gx, gy, gz := blockCoord(to)
+	s.goal = [3]int{gx, gy, gz}
+
+	start := &pathNode{pos: [3]int{sx, sy, sz}}
+	start.f = s.heuristic(start.pos)
+	s.nodes[start.pos] = start
+	s.best = start
+	heap.Push(&s.open, start)
+	return s
+}
+
+// FindPath runs a whole search at once.
+func FindPath(world VoxelWorld, profile NavProfile, from, to rl.Vector3) ([]rl.Vector3, error) {
+	s := NewPathSearch(world, profile, from, to)
+	for !s.Done() {
+		s.Step(pathMaxNodes)
+	}
+	return s.Path()
+}
+
+func (s *PathSearch) Done() bool {
+	return s.done
+}
+
+// Path returns the feet positions from the start to the goal, without
+// the start. It is only valid once the search is done. If the goal could
+// not be reached the path leads to the closest reachable node and the
+// error is ErrNoPath.
+func (s *PathSearch) Path() ([]rl.Vector3, error) {
+	return s.path, s.err
+}
+
+// Step expands at most budget nodes and returns how many it expanded.
+func (s *PathSearch) Step(budget int) int {
+	used := 0
+	for used < budget && !s.done {
+		if s.open.Len() == 0 || s.expanded >= pathMaxNodes {
+			s.finish(s.best, ErrNoPath)
+			break
+		}
+
+		node := heap.Pop(&s.open).(*pathNode)
+		node.closed = true
+		s.expanded++
+		used++
+
+		if node.pos == s.goal {
+			s.finish(node, nil)
+			break
+		}
+		if s.heuristic(node.pos) < s.heuristic(s.best.pos) {
+			s.best = node
+		}
+		s.expand(node)
+	}
+	return used
+}
+
+func (s *PathSearch) finish(end *pathNode, err error) {
+	s.done = true
+	s.err = err
+	for n := end; n != nil && n.parent != nil; n = n.parent {
+		s.path = append(s.path, rl.NewVector3(float32(n.pos[0]), float32(n.pos[1]), float32(n.pos[2])))
+	}
+	for i, j := 0, len(s.path)-1; i < j; i, j = i+1, j-1 {
+		s.path[i], s.path[j] = s.path[j], s.path[i]
+	}
+}
+
+var pathDirections = [8][2]int{
+	{1, 0}, {-1, 0}, {0, 1}, {0, -1},
+	{1, 1}, {1, -1}, {-1, 1}, {-1, -1},
+}
+
+// expand adds all moves from node: walking on the same level, climbing
+// up to JumpHeight blocks and dropping up to MaxFall blocks. Diagonal
+// moves stay on one level and must not cut corners.
+func (s *PathSearch) expand(node *pathNode) {
+	var (
+		w       = s.world
+		p       = s.profile
+		x, y, z = node.pos[0], node.pos[1], node.pos[2]
+	)
+	for _, d := range pathDirections {
+		nx, nz := x+d[0], z+d[1]
+
+		if d[0] != 0 && d[1] != 0 {
+			if p.clear(w, x+d[0], y, z) && p.clear(w, x, y, z+d[1]) && p.canStand(w, nx, y, nz) {
+				s.visit(node, [3]int{nx, y, nz}, math.Sqrt2)
+			}
+			continue
+		}
+
+		if p.canStand(w, nx, y, nz) {
+			s.visit(node, [3]int{nx, y, nz}, 1)
+			continue
+		}
+
+		if !p.clear(w, nx, y, nz) {
+			// a wall, try to jump on it
+			for h := 1; h <= p.JumpHeight; h++ {
+				if !p.clear(w, x, y+h, z) {
+					break
+				}
+				if p.canStand(w, nx, y+h, nz) {
+					s.visit(node, [3]int{nx, y + h, nz}, 1+pathJumpCost*float32(h))
+					break
+				}
+			}
+			continue
+		}
+
+		// a drop, fall until there is ground
+		for h := 1; h <= p.MaxFall; h++ {
+			if !p.clear(w, nx, y-h, nz) {
+				break
+			}
+			if p.canStand(w, nx, y-h, nz) {
+				s.visit(node, [3]int{nx, y - h, nz}, 1+pathFallCost*float32(h))
+				break
+			}
+		}
+	}
+}
+
+func (s *PathSearch) visit(from *pathNode, pos [3]int, cost float32) {
+	g := from.g + cost
+	n, ok := s.nodes[pos]
+	if !ok {
+		n = &pathNode{pos: pos, g: g, parent: from}
+		n.f = g + s.heuristic(pos)
+		s.nodes[pos] = n
+		heap.Push(&s.open, n)
+		return
+	}
+	if n.closed || g >= n.g {
+		return
+	}
+	n.g = g
+	n.f = g + s.heuristic(pos)
+	n.parent = from
+	heap.Fix(&s.open, n.index)
+}
+
+// heuristic is the octile distance on the ground plus the height
+// difference at the cheaper of jumping and falling. A block of height
+// never costs less than that, so the heuristic does not overestimate and
+// the paths stay the shortest ones.
+func (s *PathSearch) heuristic(pos [3]int) float32 {
+	dx := math.Abs(float64(pos[0] - s.goal[0]))
+	dz := math.Abs(float64(pos[2] - s.goal[2]))
+	dy := math.Abs(float64(pos[1] - s.goal[1]))
+	return float32(math.Max(dx, dz) + (math.Sqrt2-1)*math.Min(dx, dz) + math.Min(pathFallCost, pathJumpCost)*dy)
+}
@@ -0,0 +1,242 @@
+package gocraft
+
+import (
+	"math"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const navChunkSize = 16
+
+var testProfile = NavProfile{Width: mobWidth, Height: mobHeight, JumpHeight: 1, MaxFall: 3}
+
+// navWorld builds empty chunks of 16 blocks for the x coordinates given,
+// together with a floor at y 0 whose top is at y 1. The chunk of x 0
+// spans x -8 to 7 and z -8 to 7, the next one starts at x 8.
+func navWorld(t *testing.T, xs ...int) *ChunkManager {
+	t.Helper()
+	cm := NewRemoteChunkManager(navChunkSize)
+	for _, x := range xs {
+		chunk := NewChunk(navChunkSize, navChunkSize, navChunkSize)
+		chunk.center = cm.chunkCenter(x, 0)
+		cm.insertChunk(chunk)
+		setBlocks(t, cm, [3]int{x - 8, 0, -8}, [3]int{x + 7, 0, 7})
+	}
+	return cm
+}
+
+// setBlocks fills the blocks from min to max, both included, with dirt.
+func setBlocks(t *testing.T, cm *ChunkManager, min, max [3]int) {
+	t.Helper()
+	for x := min[0]; x <= max[0]; x++ {
+		for y := min[1]; y <= max[1]; y++ {
+			for z := min[2]; z <= max[2]; z++ {
+				if err := cm.SetBlock(x, y, z, BlockTypeDirt); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+}
+
+func feet(x, y, z int) rl.Vector3 {
+	return rl.NewVector3(float32(x), float32(y), float32(z))
+}
+
+// pathCost adds up what the search pays for the moves of path.
+func pathCost(from rl.Vector3, path []rl.Vector3) float32 {
+	var cost float32
+	for _, to := range path {
+		dx := math.Abs(float64(to.X - from.X))
+		dz := math.Abs(float64(to.Z - from.Z))
+		cost += float32(math.Max(dx, dz) + (math.Sqrt2-1)*math.Min(dx, dz))
+		if dy := to.Y - from.Y; dy > 0 {
+			cost += pathJumpCost * dy
+		} else {
+			cost -= pathFallCost * dy
+		}
+		from = to
+	}
+	return cost
+}
+
+func TestFindPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    func(t *testing.T, cm *ChunkManager)
+		profile  NavProfile
+		from, to rl.Vector3
+		// via is a position the path has to pass, wantErr means the goal
+		// can not be reached
+		via     *rl.Vector3
+		wantErr bool
+	}{
+		{
+			name:    "walk",
+			profile: testProfile,
+			from:    feet(-4, 1, 0), to: feet(4, 1, 0),
+		},
+		{
+			name: "step up and down",
+			build: func(t *testing.T, cm *ChunkManager) {
+				setBlocks(t, cm, [3]int{0, 1, -8}, [3]int{0, 1, 7})
+			},
+			profile: testProfile,
+			from:    feet(-4, 1, 0), to: feet(4, 1, 0),
+			via: &rl.Vector3{X: 0, Y: 2, Z: 0},
+		},
+		{
+			name: "wall too high to jump",
+			build: func(t *testing.T, cm *ChunkManager) {
+				setBlocks(t, cm, [3]int{0, 1, -8}, [3]int{0, 2, 7})
+			},
+			profile: testProfile,
+			from:    feet(-4, 1, 0), to: feet(4, 1, 0),
+			wantErr: true,
+		},
+		{
+			name: "jump on a ledge",
+			build: func(t *testing.T, cm *ChunkManager) {
+				setBlocks(t, cm, [3]int{2, 1, -8}, [3]int{7, 1, 7})
+			},
+			profile: testProfile,
+			from:    feet(-4, 1, 0), to: feet(4, 2, 0),
+		},
+		{
+			name: "fall from a tower",
+			build: func(t *testing.T, cm *ChunkManager) {
+				setBlocks(t, cm, [3]int{-4, 1, 0}, [3]int{-4, 3, 0})
+			},
+			profile: testProfile,
+			from:    feet(-4, 4, 0), to: feet(4, 1, 0),
+		},
+		{
+			name: "tower higher than MaxFall",
+			build: func(t *testing.T, cm *ChunkManager) {
+				setBlocks(t, cm, [3]int{-4, 1, 0}, [3]int{-4, 3, 0})
+			},
+			profile: NavProfile{Width: mobWidth, Height: mobHeight, JumpHeight: 1, MaxFall: 2},
+			from:    feet(-4, 4, 0), to: feet(4, 1, 0),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := navWorld(t, 0)
+			if tt.build != nil {
+				tt.build(t, cm)
+			}
+			path, err := FindPath(cm, tt.profile, tt.from, tt.to)
+			if tt.wantErr {
+				if err != ErrNoPath {
+					t.Errorf("got %v with path %v, want %v", err, path, ErrNoPath)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(path) == 0 || path[len(path)-1] != tt.to {
+				t.Fatalf("path %v does not end at %v", path, tt.to)
+			}
+			prev := tt.from
+			for _, p := range path {
+				x, y, z := int(p.X), int(p.Y), int(p.Z)
+				if !tt.profile.canStand(cm, x, y, z) {
+					t.Errorf("path %v goes through %v where the entity can not stand", path, p)
+				}
+				if p.Y-prev.Y > float32(tt.profile.JumpHeight) || prev.Y-p.Y > float32(tt.profile.MaxFall) {
+					t.Errorf("path %v moves from %v to %v", path, prev, p)
+				}
+				prev = p
+			}
+			if tt.via != nil {
+				found := false
+				for _, p := range path {
+					found = found || p == *tt.via
+				}
+				if !found {
+					t.Errorf("path %v does not pass %v", path, *tt.via)
+				}
+			}
+
+			s := NewPathSearch(cm, tt.profile, tt.from, tt.to)
+			if h, cost := s.heuristic([3]int{int(tt.from.X), int(tt.from.Y), int(tt.from.Z)}), pathCost(tt.from, path); h > cost {
+				t.Errorf("heuristic %.2f overestimates the path cost %.2f", h, cost)
+			}
+		})
+	}
+}
+
+func TestFindPathAcrossChunks(t *testing.T) {
+	tests := []struct {
+		name    string
+		chunks  []int
+		wantErr bool
+	}{
+		{"both loaded", []int{0, 16}, false},
+		// the blocks of a missing chunk are not solid, so nothing to stand on
+		{"next chunk missing", []int{0}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := navWorld(t, tt.chunks...)
+			from, to := feet(0, 1, 0), feet(12, 1, 0)
+			path, err := FindPath(cm, testProfile, from, to)
+			if tt.wantErr {
+				if err != ErrNoPath {
+					t.Fatalf("got %v, want %v", err, ErrNoPath)
+				}
+				// the closest node is the last one in the loaded chunk
+				if last := path[len(path)-1]; last != feet(7, 1, 0) {
+					t.Errorf("path ends at %v, want the chunk border at x 7", last)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(path) != 12 || path[len(path)-1] != to {
+				t.Errorf("path %v, want 12 steps to %v", path, to)
+			}
+		})
+	}
+}
+
+func TestPathSearchResumes(t *testing.T) {
+	const budget = 5
+	cm := navWorld(t, 0)
+	// a wall with a gap at z 6 makes the search expand many nodes
+	setBlocks(t, cm, [3]int{0, 1, -8}, [3]int{0, 2, 5})
+	from, to := feet(-4, 1, 0), feet(4, 1, 0)
+
+	s := NewPathSearch(cm, testProfile, from, to)
+	steps, expanded := 0, 0
+	for !s.Done() {
+		n := s.Step(budget)
+		if n > budget {
+			t.Fatalf("step expanded %d nodes with a budget of %d", n, budget)
+		}
+		expanded += n
+		steps++
+	}
+	if steps < 2 {
+		t.Fatalf("search finished in %d step, it did not have to resume", steps)
+	}
+	if n := s.Step(budget); n != 0 {
+		t.Errorf("finished search expanded %d more nodes", n)
+	}
+
+	path, err := s.Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := FindPath(cm, testProfile, from, to)
+	if len(path) != len(want) || pathCost(from, path) != pathCost(from, want) {
+		t.Errorf("resumed search found %v, in one go %v", path, want)
+	}
+	if expanded != s.expanded {
+		t.Errorf("steps reported %d nodes, the search expanded %d", expanded, s.expanded)
+	}
+}
@@ -0,0 +1,324 @@
+package gocraft
+
+import (
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// VoxelWorld is the part of the world the physics step needs. It is
+// implemented by ChunkManager and can be faked for tests.
+type VoxelWorld interface {
+	IsSolid(x, y, z int) bool
+}
+
+type PlayerMode int
+
+const (
+	PlayerModeWalk PlayerMode = iota
+	PlayerModeFly
+	PlayerModeNoclip
+)
+
+func (m PlayerMode) String() string {
+	switch m {
+	case PlayerModeFly:
+		return "fly"
+	case PlayerModeNoclip:
+		return "noclip"
+	}
+	return "walk"
+}
+
+const (
+	playerWidth        float32 = 0.6
+	playerHeight       float32 = 1.8
+	playerEyeHeight    float32 = 1.62
+	playerSneakEye     float32 = 1.32
+	playerWalkSpeed    float32 = 4.3
+	playerSprintSpeed  float32 = 5.6
+	playerSneakSpeed   float32 = 1.3
+	playerFlySpeed     float32 = 10.9
+	playerJumpVelocity float32 = 8.4
+	playerGravity      float32 = 28
+	playerMaxFallSpeed float32 = 78.4
+	// how fast horizontal velocity follows the wished direction
+	playerGroundAccel float32 = 20
+	playerAirAccel    float32 = 4
+	// distance checked below the feet to keep sneaking players on edges
+	playerSneakProbe float32 = 0.1
+	// movement per axis is split into steps so sneak probing stays exact
+	playerSneakStep float32 = 0.05
+	// playerReach is how far away blocks can be broken or placed
+	playerReach float32 = 5
+)
+
+// PlayerInput is everything the physics step needs from the controls.
+// Forward and Strafe are in the range -1..1, Yaw is in degrees.
+type PlayerInput struct {
+	Forward float32
+	Strafe  float32
+	Yaw     float32
+	Jump    bool
+	Sneak   bool
+	Sprint  bool
+}
+
+type Player struct {
+	Position  rl.Vector3 // feet position
+	Velocity  rl.Vector3
+	Mode      PlayerMode
+	OnGround  bool
+	Sneaking  bool
+	Sprinting bool
+	GameMode  GameMode
+	Inventory Inventory
+	// Input is applied on every world tick until it is replaced.
+	Input PlayerInput
+	// Remote players are moved by the server from their inputs, the world
+	// does not simulate them on its own.
+	Remote bool
+	// MoveSpeed is the walking speed in blocks per second, sneaking and
+	// sprinting scale with it. Clients must keep the server's speed or
+	// their prediction is corrected all the time.
+	MoveSpeed float32
+
+	prevPosition rl.Vector3
+}
+
+func NewPlayer(position rl.Vector3) *Player {
+	return &Player{
+		Position:     position,
+		MoveSpeed:    playerWalkSpeed,
+		prevPosition: position,
+	}
+}
+
+func (p *Player) Box() AABB {
+	return NewAABB(p.Position, playerWidth, playerHeight)
+}
+
+func (p *Player) EyePosition() rl.Vector3 {
+	eye := playerEyeHeight
+	if p.Sneaking && p.Mode == PlayerModeWalk {
+		eye = playerSneakEye
+	}
+	return rl.NewVector3(p.Position.X, p.Position.Y+eye, p.Position.Z)
+}
+
+// InterpolatedEye is the eye position between the previous and the
+// current tick, alpha 0 is the previous tick.
+func (p *Player) InterpolatedEye(alpha float32) rl.Vector3 {
+	eye := p.EyePosition()
+	return rl.Vector3Add(
+		rl.Vector3Lerp(p.prevPosition, p.Position, alpha),
+		rl.Vector3Subtract(eye, p.Position),
+	)
+}
+
+func (p *Player) save() PlayerSave {
+	return PlayerSave{
+		Position:  [3]float32{p.Position.X, p.Position.Y, p.Position.Z},
+		Yaw:       p.Input.Yaw,
+		Mode:      p.Mode.String(),
+		GameMode:  p.GameMode.String(),
+		Selected:  p.Inventory.Selected,
+		Inventory: p.Inventory.Slots[:],
+	}
+}
+
+func (p *Player) restore(save PlayerSave) {
+	p.Position = rl.NewVector3(save.Position[0], save.Position[1], save.Position[2])
+	p.prevPosition = p.Position
+	p.Input.Yaw = save.Yaw
+	p.Mode = PlayerModeWalk
+	if save.Mode == PlayerModeFly.String() {
+		p.Mode = PlayerModeFly
+	}
+	p.GameMode, _ = ParseGameMode(save.GameMode)
+	p.Inventory = Inventory{changed: true}
+	p.Inventory.Select(save.Selected)
+	copy(p.Inventory.Slots[:], save.Inventory)
+}
+
+// ToggleMode switches between walking and the given mode.
+func (p *Player) ToggleMode(mode PlayerMode) {
+	if p.Mode == mode {
+		p.SetMode(PlayerModeWalk)
+	} else {
+		p.SetMode(mode)
+	}
+}
+
+// SetMode changes the movement mode, the player stops when it changes.
+func (p *Player) SetMode(mode PlayerMode) {
+	if p.Mode == mode {
+		return
+	}
+	p.Mode = mode
+	p.Velocity = rl.Vector3Zero()
+	p.OnGround = false
+}
+
+// Step advances the player by dt seconds. It only depends on its
+// arguments, so equal inputs always produce equal results.
+func (p *Player) Step(world VoxelWorld, in PlayerInput, dt float32) {
+	p.Sneaking = in.Sneak
+	p.Sprinting = in.Sprint && in.Forward > 0 && !in.Sneak
+
+	wish := wishDirection(in)
+	switch p.Mode {
+	case PlayerModeFly, PlayerModeNoclip:
+		speed := playerFlySpeed
+		if p.Sprinting {
+			speed *= 2
+		}
+		p.Velocity.X = wish.X * speed
+		p.Velocity.Z = wish.Z * speed
+		p.Velocity.Y = 0
+		if in.Jump {
+			p.Velocity.Y += speed
+		}
+		if in.Sneak {
+			p.Velocity.Y -= speed
+		}
+	default:
+		speed := p.MoveSpeed
+		switch {
+		case p.Sneaking:
+			speed *= playerSneakSpeed / playerWalkSpeed
+		case p.Sprinting:
+			speed *= playerSprintSpeed / playerWalkSpeed
+		}
+		accel := playerAirAccel
+		if p.OnGround {
+			accel = playerGroundAccel
+		}
+		blend := float32(math.Min(1, float64(accel*dt)))
+		p.Velocity.X += (wish.X*speed - p.Velocity.X) * blend
+		p.Velocity.Z += (wish.Z*speed - p.Velocity.Z) * blend
+
+		if in.Jump && p.OnGround {
+			p.Velocity.Y = playerJumpVelocity
+		}
+		p.Velocity.Y -= playerGravity * dt
+		if p.Velocity.Y < -playerMaxFallSpeed {
+			p.Velocity.Y = -playerMaxFallSpeed
+		}
+	}
+
+	delta := rl.Vector3Scale(p.Velocity, dt)
+	if p.Mode == PlayerModeNoclip {
+		p.Position = rl.Vector3Add(p.Position, delta)
+		p.OnGround = false
+		return
+	}
+
+	p.move(world, delta)
+}
+
+// move resolves delta against solid voxels one axis at a time, y first,
+// so the player slides along walls instead of sticking to them.
+func (p *Player) move(world VoxelWorld, delta rl.Vector3) {
+	box := p.Box()
+
+	dy := sweepAxis(world, box, axisY, delta.Y)
+	box = box.Offset(rl.NewVector3(0, dy, 0))
+	p.OnGround = delta.Y < 0 && dy != delta.Y
+	if dy != delta.Y {
+		p.Velocity.Y = 0
+	}
+
+	guard := p.Mode == PlayerModeWalk && p.Sneaking && p.OnGround
+	for _, axis := range []int{axisX, axisZ} {
+		want := vecAxis(delta, axis)
+		if guard {
+			want = clampToEdge(world, box, axis, want)
+		}
+		d := sweepAxis(world, box, axis, want)
+		var offset rl.Vector3
+		setVecAxis(&offset, axis, d)
+		box = box.Offset(offset)
+		if d != vecAxis(delta, axis) {
+			setVecAxis(&p.Velocity, axis, 0)
+		}
+	}
+
+	p.Position = rl.NewVector3(
+		(box.Min.X+box.Max.X)/2,
+		box.Min.Y,
+		(box.Min.Z+box.Max.Z)/2,
+	)
+}
+
+// sweepAxis returns how far box can move along axis before hitting a solid voxel.
+func sweepAxis(world VoxelWorld, box AABB, axis int, d float32) float32 {
+	if d == 0 {
+		return 0
+	}
+
+	var sweep rl.Vector3
+	setVecAxis(&sweep, axis, d)
+	minX, minY, minZ, maxX, maxY, maxZ := box.Expand(sweep).blockRange()
+	for x := minX; x <= maxX; x++ {
+		for y := minY; y <= maxY; y++ {
+			for z := minZ; z <= maxZ; z++ {
+				if world.IsSolid(x, y, z) {
+					d = box.clipAxis(blockAABB(x, y, z), axis, d)
+				}
+			}
+		}
+	}
+	return d
+}
+
+// clampToEdge shortens d until the box would still stand on a solid voxel.
+func clampToEdge(world VoxelWorld, box AABB, axis int, d float32) float32 {
+	for d != 0 {
+		var offset rl.Vector3
+		setVecAxis(&offset, axis, d)
+		offset.Y = -playerSneakProbe
+		if hasSolid(world, box.Offset(offset)) {
+			break
+		}
+		switch {
+		case d > playerSneakStep:
+			d -= playerSneakStep
+		case d < -playerSneakStep:
+			d += playerSneakStep
+		default:
+			d = 0
+		}
+	}
+	return d
+}
+
+func hasSolid(world VoxelWorld, box AABB) bool {
+	minX, minY, minZ, maxX, maxY, maxZ := box.blockRange()
+	for x := minX; x <= maxX; x++ {
+		for y := minY; y <= maxY; y++ {
+			for z := minZ; z <= maxZ; z++ {
+				if world.IsSolid(x, y, z) && box.Intersects(blockAABB(x, y, z)) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// wishDirection turns the input axes into a horizontal world direction.
+func wishDirection(in PlayerInput) rl.Vector3 {
+	var (
+		front = rl.NewVector3(cos32(radians(in.Yaw)), 0, sin32(radians(in.Yaw)))
+		right = rl.NewVector3(-front.Z, 0, front.X)
+		wish  = rl.Vector3Add(
+			rl.Vector3Scale(front, in.Forward),
+			rl.Vector3Scale(right, in.Strafe),
+		)
+	)
+	if rl.Vector3Length(wish) > 1 {
+		wish = rl.Vector3Normalize(wish)
+	}
+	return wish
+}
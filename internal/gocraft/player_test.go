@@ -0,0 +1,153 @@
+package gocraft
+
+import (
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const testDT float32 = 1.0 / DefaultTickRate
+
+// fakeWorld is a set of solid voxels.
+type fakeWorld map[[3]int]bool
+
+func (w fakeWorld) IsSolid(x, y, z int) bool {
+	return w[[3]int{x, y, z}]
+}
+
+// fill makes the voxels from min to max, both included, solid.
+func (w fakeWorld) fill(min, max [3]int) fakeWorld {
+	for x := min[0]; x <= max[0]; x++ {
+		for y := min[1]; y <= max[1]; y++ {
+			for z := min[2]; z <= max[2]; z++ {
+				w[[3]int{x, y, z}] = true
+			}
+		}
+	}
+	return w
+}
+
+// floorWorld has a floor at y 0 whose top is at y 1.
+func floorWorld() fakeWorld {
+	return fakeWorld{}.fill([3]int{-8, 0, -8}, [3]int{8, 0, 8})
+}
+
+func steps(p *Player, world VoxelWorld, in PlayerInput, n int) {
+	for i := 0; i < n; i++ {
+		p.Step(world, in, testDT)
+	}
+}
+
+func near(a, b, eps float32) bool {
+	return a-b <= eps && b-a <= eps
+}
+
+func TestPlayerLands(t *testing.T) {
+	p := NewPlayer(rl.NewVector3(0, 4, 0))
+	steps(p, floorWorld(), PlayerInput{}, 40)
+
+	if !p.OnGround {
+		t.Fatalf("player at %v is not on the ground", p.Position)
+	}
+	if !near(p.Position.Y, 1, 0.01) {
+		t.Errorf("feet at y %.3f, want 1", p.Position.Y)
+	}
+	if p.Velocity.Y != 0 {
+		t.Errorf("vertical velocity %.3f on the ground", p.Velocity.Y)
+	}
+}
+
+func TestPlayerSlidesAlongWall(t *testing.T) {
+	// a wall at x 2 across the floor
+	world := floorWorld().fill([3]int{2, 1, -8}, [3]int{2, 3, 8})
+	p := NewPlayer(rl.NewVector3(0, 1, 0))
+	// 45 degrees walks to +x and +z
+	steps(p, world, PlayerInput{Forward: 1, Yaw: 45}, 30)
+
+	if limit := 1.5 - playerWidth/2; p.Position.X > limit {
+		t.Errorf("player at x %.3f went into the wall at %.3f", p.Position.X, limit)
+	}
+	if !near(p.Position.X, 1.5-playerWidth/2, 0.01) {
+		t.Errorf("player at x %.3f does not touch the wall", p.Position.X)
+	}
+	if p.Position.Z < 2 {
+		t.Errorf("player at z %.3f did not slide along the wall", p.Position.Z)
+	}
+}
+
+func TestPlayerHitsCeiling(t *testing.T) {
+	// the ceiling leaves 0.4 blocks above the head
+	world := floorWorld().fill([3]int{-1, 3, -1}, [3]int{1, 3, 1})
+	p := NewPlayer(rl.NewVector3(0, 1, 0))
+	steps(p, world, PlayerInput{}, 2)
+
+	top := float32(1)
+	for i := 0; i < 20; i++ {
+		p.Step(world, PlayerInput{Jump: i == 0}, testDT)
+		if p.Position.Y > top {
+			top = p.Position.Y
+		}
+	}
+	if limit := 3 - playerHeight; top > limit {
+		t.Errorf("head went through the ceiling, feet at %.3f over %.3f", top, limit)
+	}
+	if top < 3-playerHeight-0.01 {
+		t.Errorf("jump stopped at %.3f below the ceiling", top)
+	}
+	if !p.OnGround || !near(p.Position.Y, 1, 0.01) {
+		t.Errorf("player at %v did not land again", p.Position)
+	}
+}
+
+func TestPlayerSneakStaysOnEdge(t *testing.T) {
+	// the floor ends at x 0.5
+	world := fakeWorld{}.fill([3]int{-8, 0, -8}, [3]int{0, 0, 8})
+	tests := []struct {
+		name  string
+		sneak bool
+		fall  bool
+	}{
+		{"walking falls off", false, true},
+		{"sneaking stops", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPlayer(rl.NewVector3(-1, 1, 0))
+			steps(p, world, PlayerInput{}, 2)
+			steps(p, world, PlayerInput{Forward: 1, Sneak: tt.sneak}, 60)
+
+			if fell := p.Position.Y < 0.9; fell != tt.fall {
+				t.Fatalf("player at %v fell %v, want %v", p.Position, fell, tt.fall)
+			}
+			if !tt.fall && (p.Position.X-playerWidth/2 >= 0.5 || p.Position.X < 0.5) {
+				t.Errorf("player at x %.3f is not at the edge at 0.5", p.Position.X)
+			}
+		})
+	}
+}
+
+func TestPlayerFlyAndNoclip(t *testing.T) {
+	world := floorWorld().fill([3]int{2, 1, -8}, [3]int{2, 3, 8})
+	tests := []struct {
+		mode    PlayerMode
+		through bool
+	}{
+		{PlayerModeFly, false},
+		{PlayerModeNoclip, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			p := NewPlayer(rl.NewVector3(0, 1.5, 0))
+			p.SetMode(tt.mode)
+			steps(p, world, PlayerInput{Forward: 1}, 20)
+
+			if through := p.Position.X > 2.5; through != tt.through {
+				t.Errorf("player at x %.3f went through the wall %v, want %v", p.Position.X, through, tt.through)
+			}
+			// flying does not fall
+			if !near(p.Position.Y, 1.5, 0.001) {
+				t.Errorf("flying player moved to y %.3f", p.Position.Y)
+			}
+		})
+	}
+}
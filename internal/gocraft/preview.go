@@ -0,0 +1,342 @@
+package gocraft
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	fastnoise "github.com/tinogoehlert/gocraft/pkg/go-fastnoiselite"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	// previewHistogramHeight is the height of the histogram images.
+	previewHistogramHeight = 100
+)
+
+var (
+	previewAir    = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	previewSolid  = color.RGBA{R: 128, G: 128, B: 128, A: 255}
+	previewCarved = color.RGBA{A: 255}
+)
+
+// TerrainPreview samples the terrain noise of a seed over an area without
+// generating chunks, to tune the terrain quickly. The pixels of its
+// images are Scale blocks apart, x to the right and z down.
+type TerrainPreview struct {
+	Terrain TerrainConfig
+	// Area is in world x and z.
+	Area  image.Rectangle
+	Scale int
+
+	noise *fastnoise.NoiseState
+	// chunkHeight limits the height like in the chunks
+	chunkHeight int
+	width       int
+	length      int
+	// tops are the heights of the highest blocks before caves are
+	// carved, per pixel
+	tops []int
+}
+
+func NewTerrainPreview(seed int, chunkSize float32, terrain TerrainConfig, area image.Rectangle, scale int) *TerrainPreview {
+	cm := NewChunkManager(chunkSize)
+	cm.SetSeed(seed)
+	cm.SetTerrain(terrain)
+	p := &TerrainPreview{
+		Terrain:     terrain,
+		Area:        area,
+		Scale:       scale,
+		noise:       cm.newTerrainNoise(),
+		chunkHeight: int(chunkSize),
+		width:       (area.Dx() + scale - 1) / scale,
+		length:      (area.Dy() + scale - 1) / scale,
+	}
+	p.tops = make([]int, p.width*p.length)
+	for pz := 0; pz < p.length; pz++ {
+		for px := 0; px < p.width; px++ {
+			x, z := p.block(px, pz)
+			// blocks fill up to the height, the floor is always there
+			h := int(terrainHeight(p.noise, terrain, float32(x), float32(z)))
+			p.tops[pz*p.width+px] = minInt(maxInt(h-1, 0), p.chunkHeight-1)
+		}
+	}
+	return p
+}
+
+// block is the world position of a pixel.
+func (p *TerrainPreview) block(px, pz int) (x, z int) {
+	return p.Area.Min.X + px*p.Scale, p.Area.Min.Y + pz*p.Scale
+}
+
+// HeightImage shows the height of the terrain from black at 0 to white
+// at the highest possible terrain.
+func (p *TerrainPreview) HeightImage() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, p.width, p.length))
+	for i, y := range p.tops {
+		img.Pix[i] = uint8(clamp32(float32(y)/p.Terrain.Height*255, 0, 255))
+	}
+	return img
+}
+
+// BiomeImage shows the block on top of every column in its color.
+func (p *TerrainPreview) BiomeImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, p.width, p.length))
+	for i, y := range p.tops {
+		img.SetRGBA(i%p.width, i/p.width, blockColors[previewSurface(y)])
+	}
+	return img
+}
+
+// previewSurface is the top block of a column, caves left out.
+func previewSurface(y int) BlockType {
+	if y == 0 {
+		return BlockTypeGroud
+	}
+	return surfaceBlock(y)
+}
+
+// CaveSlice cuts the terrain at height y. Carved blocks are black, solid
+// ones gray and air above the terrain white. It also counts the pixels
+// of each.
+func (p *TerrainPreview) CaveSlice(y int) (img *image.RGBA, air, solid, carved int) {
+	img = image.NewRGBA(image.Rect(0, 0, p.width, p.length))
+	for pz := 0; pz < p.length; pz++ {
+		for px := 0; px < p.width; px++ {
+			x, z := p.block(px, pz)
+			c := previewSolid
+			switch {
+			case y > p.tops[pz*p.width+px]:
+				c = previewAir
+				air++
+			case y > 0 && caveNoise(p.noise, p.Terrain, float32(x), float32(y), float32(z)) < p.Terrain.CaveThreshold:
+				c = previewCarved
+				carved++
+			default:
+				solid++
+			}
+			img.SetRGBA(px, pz, c)
+		}
+	}
+	return img, air, solid, carved
+}
+
+// HeightHistogram counts the columns by the height of their top block.
+func (p *TerrainPreview) HeightHistogram() []int {
+	counts := make([]int, p.chunkHeight)
+	for _, y := range p.tops {
+		counts[y]++
+	}
+	return counts
+}
+
+// BiomeHistogram counts the columns by their top block.
+func (p *TerrainPreview) BiomeHistogram() map[BlockType]int {
+	counts := make(map[BlockType]int)
+	for _, y := range p.tops {
+		counts[previewSurface(y)]++
+	}
+	return counts
+}
+
+// HeightStats are the lowest, highest and mean height of the top blocks.
+func (p *TerrainPreview) HeightStats() (low, high int, mean float64) {
+	low = p.chunkHeight
+	for _, y := range p.tops {
+		low, high = minInt(low, y), maxInt(high, y)
+		mean += float64(y)
+	}
+	return low, high, mean / float64(len(p.tops))
+}
+
+// histogramImage draws counts as bars, one pixel wide each.
+func histogramImage(counts []int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, len(counts), previewHistogramHeight))
+	most := 1
+	for _, n := range counts {
+		most = maxInt(most, n)
+	}
+	for x, n := range counts {
+		bar := n * previewHistogramHeight / most
+		if n > 0 && bar == 0 {
+			bar = 1
+		}
+		for y := previewHistogramHeight - bar; y < previewHistogramHeight; y++ {
+			img.Pix[y*img.Stride+x] = 255
+		}
+	}
+	return img
+}
+
+// writeCSV writes the rows after the header, the last column is the
+// share of total in percent.
+func writeCSV(w io.Writer, header []string, rows [][]string, counts []int, total int) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append(header, "percent")); err != nil {
+		return err
+	}
+	for i, row := range rows {
+		share := strconv.FormatFloat(float64(counts[i])*100/float64(total), 'f', 3, 64)
+		if err := cw.Write(append(row, strconv.Itoa(counts[i]), share)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeCSVFile(path string, header []string, rows [][]string, counts []int, total int) error {
+	var buf strings.Builder
+	if err := writeCSV(&buf, header, rows, counts, total); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, []byte(buf.String()))
+}
+
+// setTerrainField sets a field of the terrain by its json name from a
+// key=value pair.
+func setTerrainField(terrain *TerrainConfig, pair string) error {
+	key, value, ok := strings.Cut(pair, "=")
+	if !ok {
+		return fmt.Errorf("%q is not key=value", pair)
+	}
+	v := reflect.ValueOf(terrain).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if tag, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ","); tag == key {
+			if err := setField(v.Field(i), value); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not a terrain setting", key)
+}
+
+// previewTerrain is the terrain of the config changed by the --terrain
+// file and the --set pairs.
+func previewTerrain(ctx *cli.Context, config Config) (TerrainConfig, error) {
+	terrain := config.Terrain
+	if path := ctx.String("terrain"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return terrain, err
+		}
+		if err := json.Unmarshal(data, &terrain); err != nil {
+			return terrain, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	for _, pair := range ctx.StringSlice("set") {
+		if err := setTerrainField(&terrain, pair); err != nil {
+			return terrain, err
+		}
+	}
+	if err := terrain.Validate(config.ChunkSize); err != nil {
+		return terrain, fmt.Errorf("%w: terrain: %s", ErrInvalidConfig, err.Error())
+	}
+	return terrain, nil
+}
+
+// RunPreview is the action of the preview command. It writes height,
+// biome and cave images and histograms of an area of the terrain into
+// a directory, without a window and without generating chunks.
+func RunPreview(ctx *cli.Context) error {
+	config, _, err := ResolveConfig(ctx)
+	if err != nil {
+		return err
+	}
+	terrain, err := previewTerrain(ctx, config)
+	if err != nil {
+		return err
+	}
+	size, scale := ctx.Int("size"), ctx.Int("scale")
+	if size < 1 || scale < 1 {
+		return fmt.Errorf("size %d and scale %d have to be positive", size, scale)
+	}
+	slices := ctx.IntSlice("cave-y")
+	for _, y := range slices {
+		if y < 0 || y >= int(config.ChunkSize) {
+			return fmt.Errorf("cave slice at y %d is outside of the chunks, 0 to %.0f", y, config.ChunkSize-1)
+		}
+	}
+
+	// block colors of resource packs show in the biome map
+	resources, err := NewResources(config.ResourcePacks)
+	if err != nil {
+		return err
+	}
+	defer resources.Close()
+	if err := loadBlockColors(resources); err != nil {
+		return err
+	}
+
+	dir := ctx.String("out")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	p := NewTerrainPreview(config.Seed, config.ChunkSize, terrain, mapArea(ctx.Int("x"), ctx.Int("z"), size), scale)
+	out := ctx.App.Writer
+
+	if err := writePNG(filepath.Join(dir, "height.png"), p.HeightImage()); err != nil {
+		return err
+	}
+	heights := p.HeightHistogram()
+	rows := make([][]string, len(heights))
+	for y := range heights {
+		rows[y] = []string{strconv.Itoa(y)}
+	}
+	if err := writeCSVFile(filepath.Join(dir, "height.csv"), []string{"height", "columns"}, rows, heights, len(p.tops)); err != nil {
+		return err
+	}
+	if err := writePNG(filepath.Join(dir, "height_histogram.png"), histogramImage(heights)); err != nil {
+		return err
+	}
+	low, high, mean := p.HeightStats()
+	fmt.Fprintf(out, "seed %d, %dx%d columns, height %d to %d, mean %.1f\n", config.Seed, p.width, p.length, low, high, mean)
+
+	if err := writePNG(filepath.Join(dir, "biomes.png"), p.BiomeImage()); err != nil {
+		return err
+	}
+	biomes := p.BiomeHistogram()
+	var counts []int
+	rows = nil
+	for _, typ := range []BlockType{BlockTypeGroud, BlockTypeGras, BlockTypeSnow} {
+		rows = append(rows, []string{typ.String()})
+		counts = append(counts, biomes[typ])
+		fmt.Fprintf(out, "%s: %.1f%%\n", typ, float64(biomes[typ])*100/float64(len(p.tops)))
+	}
+	if err := writeCSVFile(filepath.Join(dir, "biomes.csv"), []string{"block", "columns"}, rows, counts, len(p.tops)); err != nil {
+		return err
+	}
+
+	rows, counts = nil, nil
+	for _, y := range slices {
+		img, air, solid, carved := p.CaveSlice(y)
+		if err := writePNG(filepath.Join(dir, fmt.Sprintf("caves_y%d.png", y)), img); err != nil {
+			return err
+		}
+		for _, kind := range []struct {
+			name  string
+			count int
+		}{{"air", air}, {"solid", solid}, {"carved", carved}} {
+			rows = append(rows, []string{strconv.Itoa(y), kind.name})
+			counts = append(counts, kind.count)
+		}
+		fmt.Fprintf(out, "y %d: %.1f%% carved, %.1f%% solid, %.1f%% air\n", y,
+			float64(carved)*100/float64(len(p.tops)), float64(solid)*100/float64(len(p.tops)), float64(air)*100/float64(len(p.tops)))
+	}
+	if len(slices) > 0 {
+		if err := writeCSVFile(filepath.Join(dir, "caves.csv"), []string{"y", "kind", "columns"}, rows, counts, len(p.tops)); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(out, "wrote %s\n", dir)
+	return nil
+}
@@ -0,0 +1,613 @@
+package gocraft
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ProtocolVersion has to match between client and server. Bump it on
+// every change to the packet layout.
+const ProtocolVersion = 5
+
+const (
+	protocolMagic = "GOCR"
+	// maxPacketSize protects against garbage length prefixes.
+	maxPacketSize = 8 << 20
+	maxStringLen  = 256
+)
+
+var (
+	ErrPacketTooLarge  = errors.New("packet too large")
+	ErrUnknownPacket   = errors.New("unknown packet")
+	ErrBadMagic        = errors.New("not a gocraft connection")
+	ErrVersionMismatch = errors.New("protocol version mismatch")
+	ErrStringTooLong   = errors.New("string too long")
+)
+
+type PacketID uint8
+
+const (
+	PacketHandshake PacketID = iota + 1
+	PacketLogin
+	PacketDisconnect
+	PacketKeepAlive
+	PacketChunkData
+	PacketBlockAction
+	PacketBlockChange
+	PacketPlayerInput
+	PacketPlayerJoin
+	PacketPlayerLeave
+	PacketPlayerMove
+	PacketChat
+	PacketPlayerSnapshot
+	PacketInventory
+	PacketCraft
+)
+
+// Packet is a message of the network protocol. On the wire every packet
+// is framed as: uint32 length, uint8 id, payload (little endian).
+type Packet interface {
+	ID() PacketID
+	encode(w *packetWriter)
+	decode(r *packetReader)
+}
+
+func newPacket(id PacketID) (Packet, error) {
+	switch id {
+	case PacketHandshake:
+		return &HandshakePacket{}, nil
+	case PacketLogin:
+		return &LoginPacket{}, nil
+	case PacketDisconnect:
+		return &DisconnectPacket{}, nil
+	case PacketKeepAlive:
+		return &KeepAlivePacket{}, nil
+	case PacketChunkData:
+		return &ChunkDataPacket{}, nil
+	case PacketBlockAction:
+		return &BlockActionPacket{}, nil
+	case PacketBlockChange:
+		return &BlockChangePacket{}, nil
+	case PacketPlayerInput:
+		return &PlayerInputPacket{}, nil
+	case PacketPlayerJoin:
+		return &PlayerJoinPacket{}, nil
+	case PacketPlayerLeave:
+		return &PlayerLeavePacket{}, nil
+	case PacketPlayerMove:
+		return &PlayerMovePacket{}, nil
+	case PacketChat:
+		return &ChatPacket{}, nil
+	case PacketPlayerSnapshot:
+		return &PlayerSnapshotPacket{}, nil
+	case PacketInventory:
+		return &InventoryPacket{}, nil
+	case PacketCraft:
+		return &CraftPacket{}, nil
+	}
+	return nil, fmt.Errorf("%w: %d", ErrUnknownPacket, id)
+}
+
+// WritePacket frames and writes a single packet.
+func WritePacket(w io.Writer, p Packet) error {
+	pw := &packetWriter{buf: make([]byte, 5, 64)}
+	pw.buf[4] = byte(p.ID())
+	p.encode(pw)
+	if pw.err != nil {
+		return pw.err
+	}
+	if len(pw.buf)-4 > maxPacketSize {
+		return ErrPacketTooLarge
+	}
+	binary.LittleEndian.PutUint32(pw.buf, uint32(len(pw.buf)-4))
+	_, err := w.Write(pw.buf)
+	return err
+}
+
+// ReadPacket reads the next packet. r should be buffered.
+func ReadPacket(r io.Reader) (Packet, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:4]); err != nil {
+		return nil, err
+	}
+	size := binary.LittleEndian.Uint32(header[:4])
+	if size == 0 || size > maxPacketSize {
+		return nil, ErrPacketTooLarge
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	p, err := newPacket(PacketID(body[0]))
+	if err != nil {
+		return nil, err
+	}
+	pr := &packetReader{buf: body[1:]}
+	p.decode(pr)
+	if pr.err != nil {
+		return nil, fmt.Errorf("packet %d: %w", body[0], pr.err)
+	}
+	return p, nil
+}
+
+// HandshakePacket is the first packet a client sends.
+type HandshakePacket struct {
+	Version uint16
+	Name    string
+}
+
+func (*HandshakePacket) ID() PacketID { return PacketHandshake }
+func (p *HandshakePacket) encode(w *packetWriter) {
+	w.raw([]byte(protocolMagic))
+	w.u16(p.Version)
+	w.str(p.Name)
+}
+func (p *HandshakePacket) decode(r *packetReader) {
+	if string(r.raw(len(protocolMagic))) != protocolMagic && r.err == nil {
+		r.err = ErrBadMagic
+	}
+	p.Version = r.u16()
+	p.Name = r.str()
+}
+
+// LoginPacket accepts a client and tells it about the world.
+type LoginPacket struct {
+	PlayerID     uint32
+	Seed         int32
+	ChunkSize    uint16
+	TickRate     uint16
+	ViewDistance uint8
+	Position     rl.Vector3
+	// Terrain lets the client draw the far terrain the server would
+	// generate.
+	Terrain TerrainConfig
+}
+
+func (*LoginPacket) ID() PacketID { return PacketLogin }
+func (p *LoginPacket) encode(w *packetWriter) {
+	w.u32(p.PlayerID)
+	w.u32(uint32(p.Seed))
+	w.u16(p.ChunkSize)
+	w.u16(p.TickRate)
+	w.u8(p.ViewDistance)
+	w.vec3(p.Position)
+	w.f32(p.Terrain.Frequency)
+	w.u8(uint8(p.Terrain.Octaves))
+	w.f32(p.Terrain.Height)
+	w.u8(uint8(p.Terrain.CaveOctaves))
+	w.f32(p.Terrain.CaveThreshold)
+}
+func (p *LoginPacket) decode(r *packetReader) {
+	p.PlayerID = r.u32()
+	p.Seed = int32(r.u32())
+	p.ChunkSize = r.u16()
+	p.TickRate = r.u16()
+	p.ViewDistance = r.u8()
+	p.Position = r.vec3()
+	p.Terrain.Frequency = r.f32()
+	p.Terrain.Octaves = int(r.u8())
+	p.Terrain.Height = r.f32()
+	p.Terrain.CaveOctaves = int(r.u8())
+	p.Terrain.CaveThreshold = r.f32()
+}
+
+// DisconnectPacket is sent by either side before closing the connection.
+type DisconnectPacket struct {
+	Reason string
+}
+
+func (*DisconnectPacket) ID() PacketID             { return PacketDisconnect }
+func (p *DisconnectPacket) encode(w *packetWriter) { w.str(p.Reason) }
+func (p *DisconnectPacket) decode(r *packetReader) { p.Reason = r.str() }
+
+// KeepAlivePacket is sent by the server and echoed by the client.
+type KeepAlivePacket struct {
+	Nonce uint64
+}
+
+func (*KeepAlivePacket) ID() PacketID             { return PacketKeepAlive }
+func (p *KeepAlivePacket) encode(w *packetWriter) { w.u64(p.Nonce) }
+func (p *KeepAlivePacket) decode(r *packetReader) { p.Nonce = r.u64() }
+
+// ChunkDataPacket carries a whole chunk, see Chunk.encodeBlocks.
+type ChunkDataPacket struct {
+	X, Z   int32
+	Size   uint16
+	Height uint16
+	Data   []byte
+}
+
+func (*ChunkDataPacket) ID() PacketID { return PacketChunkData }
+func (p *ChunkDataPacket) encode(w *packetWriter) {
+	w.u32(uint32(p.X))
+	w.u32(uint32(p.Z))
+	w.u16(p.Size)
+	w.u16(p.Height)
+	w.bytes(p.Data)
+}
+func (p *ChunkDataPacket) decode(r *packetReader) {
+	p.X = int32(r.u32())
+	p.Z = int32(r.u32())
+	p.Size = r.u16()
+	p.Height = r.u16()
+	p.Data = r.bytes()
+}
+
+type BlockActionKind uint8
+
+const (
+	BlockActionBreak BlockActionKind = iota
+	BlockActionPlace
+)
+
+// BlockActionPacket asks the server to break or place a block.
+type BlockActionPacket struct {
+	Action  BlockActionKind
+	X, Y, Z int32
+	Type    BlockType
+}
+
+func (*BlockActionPacket) ID() PacketID { return PacketBlockAction }
+func (p *BlockActionPacket) encode(w *packetWriter) {
+	w.u8(uint8(p.Action))
+	w.i32x3(p.X, p.Y, p.Z)
+	w.u8(uint8(p.Type))
+}
+func (p *BlockActionPacket) decode(r *packetReader) {
+	p.Action = BlockActionKind(r.u8())
+	p.X, p.Y, p.Z = r.i32x3()
+	p.Type = BlockType(r.u8())
+}
+
+// BlockChangePacket is broadcast when a block changed.
+type BlockChangePacket struct {
+	X, Y, Z int32
+	Type    BlockType
+	Removed bool
+}
+
+func (*BlockChangePacket) ID() PacketID { return PacketBlockChange }
+func (p *BlockChangePacket) encode(w *packetWriter) {
+	w.i32x3(p.X, p.Y, p.Z)
+	w.u8(uint8(p.Type))
+	w.bool(p.Removed)
+}
+func (p *BlockChangePacket) decode(r *packetReader) {
+	p.X, p.Y, p.Z = r.i32x3()
+	p.Type = BlockType(r.u8())
+	p.Removed = r.bool()
+}
+
+// PlayerInputPacket is the input of one client tick. The server moves
+// the player with it, Sequence increases by one per tick.
+type PlayerInputPacket struct {
+	Sequence uint32
+	Input    PlayerInput
+	Mode     PlayerMode
+	Pitch    float32
+}
+
+func (*PlayerInputPacket) ID() PacketID { return PacketPlayerInput }
+func (p *PlayerInputPacket) encode(w *packetWriter) {
+	w.u32(p.Sequence)
+	w.f32(p.Input.Forward)
+	w.f32(p.Input.Strafe)
+	w.f32(p.Input.Yaw)
+	w.bool(p.Input.Jump)
+	w.bool(p.Input.Sneak)
+	w.bool(p.Input.Sprint)
+	w.u8(uint8(p.Mode))
+	w.f32(p.Pitch)
+}
+func (p *PlayerInputPacket) decode(r *packetReader) {
+	p.Sequence = r.u32()
+	p.Input.Forward = r.f32()
+	p.Input.Strafe = r.f32()
+	p.Input.Yaw = r.f32()
+	p.Input.Jump = r.bool()
+	p.Input.Sneak = r.bool()
+	p.Input.Sprint = r.bool()
+	p.Mode = PlayerMode(r.u8())
+	p.Pitch = r.f32()
+}
+
+// PlayerSnapshotPacket is the authoritative state of the receiving
+// client's player after the server applied all inputs up to Sequence.
+type PlayerSnapshotPacket struct {
+	Sequence uint32
+	Position rl.Vector3
+	Velocity rl.Vector3
+	Mode     PlayerMode
+	OnGround bool
+}
+
+func (*PlayerSnapshotPacket) ID() PacketID { return PacketPlayerSnapshot }
+func (p *PlayerSnapshotPacket) encode(w *packetWriter) {
+	w.u32(p.Sequence)
+	w.vec3(p.Position)
+	w.vec3(p.Velocity)
+	w.u8(uint8(p.Mode))
+	w.bool(p.OnGround)
+}
+func (p *PlayerSnapshotPacket) decode(r *packetReader) {
+	p.Sequence = r.u32()
+	p.Position = r.vec3()
+	p.Velocity = r.vec3()
+	p.Mode = PlayerMode(r.u8())
+	p.OnGround = r.bool()
+}
+
+// InventoryPacket replaces the inventory of the receiving client.
+type InventoryPacket struct {
+	GameMode GameMode
+	Slots    []ItemStack
+}
+
+func (*InventoryPacket) ID() PacketID { return PacketInventory }
+func (p *InventoryPacket) encode(w *packetWriter) {
+	w.u8(uint8(p.GameMode))
+	w.u8(uint8(len(p.Slots)))
+	for _, s := range p.Slots {
+		w.u8(uint8(s.Type))
+		w.u8(uint8(s.Count))
+	}
+}
+func (p *InventoryPacket) decode(r *packetReader) {
+	p.GameMode = GameMode(r.u8())
+	p.Slots = make([]ItemStack, r.u8())
+	for i := range p.Slots {
+		p.Slots[i].Type = BlockType(r.u8())
+		p.Slots[i].Count = int(r.u8())
+	}
+}
+
+// CraftPacket asks the server to craft once with the blocks laid out in
+// a crafting grid. The blocks in the grid are still part of the inventory
+// on the server, the client only holds them back in its view.
+type CraftPacket struct {
+	Grid CraftingGrid
+}
+
+func (*CraftPacket) ID() PacketID { return PacketCraft }
+func (p *CraftPacket) encode(w *packetWriter) {
+	w.u8(uint8(p.Grid.Width))
+	for _, s := range p.Grid.Slots {
+		w.u8(uint8(s.Type))
+		w.u8(uint8(s.Count))
+	}
+}
+func (p *CraftPacket) decode(r *packetReader) {
+	width := int(r.u8())
+	if width < 1 || width > maxCraftingWidth {
+		if r.err == nil {
+			r.err = fmt.Errorf("crafting grid of width %d", width)
+		}
+		return
+	}
+	p.Grid = *NewCraftingGrid(width)
+	filled := false
+	for i := range p.Grid.Slots {
+		p.Grid.Slots[i].Type = BlockType(r.u8())
+		p.Grid.Slots[i].Count = int(r.u8())
+		filled = filled || !p.Grid.Slots[i].Empty()
+	}
+	if !filled && r.err == nil {
+		r.err = errors.New("empty crafting grid")
+	}
+}
+
+// PlayerJoinPacket announces another player.
+type PlayerJoinPacket struct {
+	PlayerID uint32
+	Name     string
+}
+
+func (*PlayerJoinPacket) ID() PacketID { return PacketPlayerJoin }
+func (p *PlayerJoinPacket) encode(w *packetWriter) {
+	w.u32(p.PlayerID)
+	w.str(p.Name)
+}
+func (p *PlayerJoinPacket) decode(r *packetReader) {
+	p.PlayerID = r.u32()
+	p.Name = r.str()
+}
+
+type PlayerLeavePacket struct {
+	PlayerID uint32
+}
+
+func (*PlayerLeavePacket) ID() PacketID             { return PacketPlayerLeave }
+func (p *PlayerLeavePacket) encode(w *packetWriter) { w.u32(p.PlayerID) }
+func (p *PlayerLeavePacket) decode(r *packetReader) { p.PlayerID = r.u32() }
+
+// PlayerMovePacket is the position and view of another player.
+type PlayerMovePacket struct {
+	PlayerID   uint32
+	Position   rl.Vector3
+	Yaw, Pitch float32
+}
+
+func (*PlayerMovePacket) ID() PacketID { return PacketPlayerMove }
+func (p *PlayerMovePacket) encode(w *packetWriter) {
+	w.u32(p.PlayerID)
+	w.vec3(p.Position)
+	w.f32(p.Yaw)
+	w.f32(p.Pitch)
+}
+func (p *PlayerMovePacket) decode(r *packetReader) {
+	p.PlayerID = r.u32()
+	p.Position = r.vec3()
+	p.Yaw = r.f32()
+	p.Pitch = r.f32()
+}
+
+// ChatPacket is a chat line. Clients leave Sender empty, the server fills it in.
+type ChatPacket struct {
+	Sender  string
+	Message string
+}
+
+func (*ChatPacket) ID() PacketID { return PacketChat }
+func (p *ChatPacket) encode(w *packetWriter) {
+	w.str(p.Sender)
+	w.str(p.Message)
+}
+func (p *ChatPacket) decode(r *packetReader) {
+	p.Sender = r.str()
+	p.Message = r.str()
+}
+
+type packetWriter struct {
+	buf []byte
+	err error
+}
+
+func (w *packetWriter) raw(b []byte) { w.buf = append(w.buf, b...) }
+func (w *packetWriter) u8(v uint8)   { w.buf = append(w.buf, v) }
+func (w *packetWriter) u16(v uint16) { w.buf = binary.LittleEndian.AppendUint16(w.buf, v) }
+func (w *packetWriter) u32(v uint32) { w.buf = binary.LittleEndian.AppendUint32(w.buf, v) }
+func (w *packetWriter) u64(v uint64) { w.buf = binary.LittleEndian.AppendUint64(w.buf, v) }
+func (w *packetWriter) f32(v float32) {
+	w.u32(math.Float32bits(v))
+}
+func (w *packetWriter) bool(v bool) {
+	if v {
+		w.u8(1)
+	} else {
+		w.u8(0)
+	}
+}
+func (w *packetWriter) vec3(v rl.Vector3) {
+	w.f32(v.X)
+	w.f32(v.Y)
+	w.f32(v.Z)
+}
+func (w *packetWriter) i32x3(x, y, z int32) {
+	w.u32(uint32(x))
+	w.u32(uint32(y))
+	w.u32(uint32(z))
+}
+func (w *packetWriter) str(s string) {
+	if len(s) > maxStringLen {
+		w.err = ErrStringTooLong
+		return
+	}
+	w.u16(uint16(len(s)))
+	w.raw([]byte(s))
+}
+func (w *packetWriter) bytes(b []byte) {
+	w.u32(uint32(len(b)))
+	w.raw(b)
+}
+
+// packetReader keeps the first error and returns zero values after it,
+// so decoders do not have to check every field.
+type packetReader struct {
+	buf []byte
+	err error
+}
+
+func (r *packetReader) raw(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.buf) {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *packetReader) u8() uint8 {
+	if b := r.raw(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *packetReader) u16() uint16 {
+	if b := r.raw(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *packetReader) u32() uint32 {
+	if b := r.raw(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *packetReader) u64() uint64 {
+	if b := r.raw(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *packetReader) f32() float32 { return math.Float32frombits(r.u32()) }
+func (r *packetReader) bool() bool   { return r.u8() != 0 }
+
+func (r *packetReader) vec3() rl.Vector3 {
+	return rl.NewVector3(r.f32(), r.f32(), r.f32())
+}
+
+func (r *packetReader) i32x3() (int32, int32, int32) {
+	return int32(r.u32()), int32(r.u32()), int32(r.u32())
+}
+
+func (r *packetReader) str() string {
+	n := int(r.u16())
+	if n > maxStringLen && r.err == nil {
+		r.err = ErrStringTooLong
+	}
+	return string(r.raw(n))
+}
+
+func (r *packetReader) bytes() []byte {
+	n := int(r.u32())
+	b := r.raw(n)
+	return append([]byte(nil), b...)
+}
+
+// packetLink is a bidirectional stream of packets. It is implemented by
+// packetConn and by conditionedLink, which adds latency for testing.
+type packetLink interface {
+	Read() (Packet, error)
+	Write(p Packet) error
+}
+
+// packetConn reads and writes packets on a connection. Writes are
+// buffered and flushed per packet.
+type packetConn struct {
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+func newPacketConn(rw io.ReadWriter) *packetConn {
+	return &packetConn{
+		r: bufio.NewReader(rw),
+		w: bufio.NewWriter(rw),
+	}
+}
+
+func (c *packetConn) Read() (Packet, error) {
+	return ReadPacket(c.r)
+}
+
+func (c *packetConn) Write(p Packet) error {
+	if err := WritePacket(c.w, p); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
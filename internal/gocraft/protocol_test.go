@@ -0,0 +1,52 @@
+package gocraft
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReadCraftPacket(t *testing.T) {
+	valid := &CraftPacket{Grid: *grid("d.", "d.")}
+	var buf bytes.Buffer
+	if err := WritePacket(&buf, valid); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{"valid", buf.Bytes(), false},
+		{"width 0", []byte{2, 0, 0, 0, byte(PacketCraft), 0}, true},
+		{"too wide", []byte{2, 0, 0, 0, byte(PacketCraft), maxCraftingWidth + 1}, true},
+		{"empty grid", []byte{4, 0, 0, 0, byte(PacketCraft), 1, 0, 0}, true},
+		{"slots missing", []byte{4, 0, 0, 0, byte(PacketCraft), 2, byte(BlockTypeDirt), 1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ReadPacket(bytes.NewReader(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("read %+v, want an error", p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			craft, ok := p.(*CraftPacket)
+			if !ok {
+				t.Fatalf("read %T", p)
+			}
+			if craft.Grid.Width != valid.Grid.Width || len(craft.Grid.Slots) != len(valid.Grid.Slots) {
+				t.Fatalf("read a grid of width %d, want %d", craft.Grid.Width, valid.Grid.Width)
+			}
+			for i, s := range craft.Grid.Slots {
+				if s != valid.Grid.Slots[i] {
+					t.Errorf("slot %d holds %v, want %v", i, s, valid.Grid.Slots[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package gocraft
+
+import (
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// RayHit is the first solid voxel hit by a ray. Normal points out of the
+// face that was hit, so X+Normal[0] etc. is the free voxel in front of it.
+type RayHit struct {
+	X, Y, Z  int
+	Normal   [3]int
+	Distance float32
+}
+
+// Raycast walks the voxel grid from origin along dir (Amanatides & Woo)
+// and returns the first solid voxel closer than maxDist.
+func Raycast(world VoxelWorld, origin, dir rl.Vector3, maxDist float32) (RayHit, bool) {
+	dir = rl.Vector3Normalize(dir)
+	if rl.Vector3Length(dir) == 0 {
+		return RayHit{}, false
+	}
+
+	// voxels are centered on x and z, shift them onto the unit grid
+	var (
+		start  = [3]float64{float64(origin.X) + 0.5, float64(origin.Y), float64(origin.Z) + 0.5}
+		d      = [3]float64{float64(dir.X), float64(dir.Y), float64(dir.Z)}
+		cell   [3]int
+		step   [3]int
+		tMax   [3]float64
+		tDelta [3]float64
+	)
+	for i := 0; i < 3; i++ {
+		cell[i] = int(math.Floor(start[i]))
+		switch {
+		case d[i] > 0:
+			step[i] = 1
+			tMax[i] = (float64(cell[i]+1) - start[i]) / d[i]
+			tDelta[i] = 1 / d[i]
+		case d[i] < 0:
+			step[i] = -1
+			tMax[i] = (start[i] - float64(cell[i])) / -d[i]
+			tDelta[i] = 1 / -d[i]
+		default:
+			tMax[i] = math.Inf(1)
+			tDelta[i] = math.Inf(1)
+		}
+	}
+
+	var (
+		hit RayHit
+		t   float64
+	)
+	for t <= float64(maxDist) {
+		if world.IsSolid(cell[0], cell[1], cell[2]) {
+			hit.X, hit.Y, hit.Z = cell[0], cell[1], cell[2]
+			hit.Distance = float32(t)
+			return hit, true
+		}
+
+		axis := axisX
+		if tMax[axisY] < tMax[axis] {
+			axis = axisY
+		}
+		if tMax[axisZ] < tMax[axis] {
+			axis = axisZ
+		}
+		t = tMax[axis]
+		tMax[axis] += tDelta[axis]
+		cell[axis] += step[axis]
+		hit.Normal = [3]int{}
+		hit.Normal[axis] = -step[axis]
+	}
+	return RayHit{}, false
+}
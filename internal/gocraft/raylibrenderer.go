@@ -0,0 +1,184 @@
+package gocraft
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// RaylibRenderer draws into the raylib window, which has to be open.
+type RaylibRenderer struct {
+	// Shader draws the meshes, it is read on every draw so a reloaded
+	// shader is used right away.
+	Shader *ShaderProgram
+
+	models   []rl.Model
+	textures []rl.Texture2D
+	// materials by texture id, 0 is raylib's white default texture
+	materials map[int]rl.Material
+	cube      rl.Model
+	cubeMat   rl.Material
+}
+
+func NewRaylibRenderer() *RaylibRenderer {
+	return &RaylibRenderer{materials: make(map[int]rl.Material)}
+}
+
+func (r *RaylibRenderer) ScreenSize() (int32, int32) {
+	return int32(rl.GetScreenWidth()), int32(rl.GetScreenHeight())
+}
+
+// LoadMesh uploads the mesh. This raylib version can not upload a mesh
+// from memory, so it is written to a temporary obj file and loaded from
+// there.
+func (r *RaylibRenderer) LoadMesh(data *MeshData) (Mesh, error) {
+	dir, err := os.MkdirTemp("", "gocraft")
+	if err != nil {
+		return Mesh{}, err
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "mesh.obj")
+	f, err := os.Create(file)
+	if err != nil {
+		return Mesh{}, err
+	}
+	if err := data.WriteOBJ(f); err != nil {
+		f.Close()
+		return Mesh{}, err
+	}
+	if err := f.Close(); err != nil {
+		return Mesh{}, err
+	}
+
+	r.models = append(r.models, rl.LoadModel(file))
+	return Mesh{ID: len(r.models) - 1}, nil
+}
+
+func (r *RaylibRenderer) LoadTexture(img image.Image) Texture {
+	rimg := rl.NewImageFromImage(img)
+	defer rl.UnloadImage(rimg)
+	tex := rl.LoadTextureFromImage(rimg)
+	r.textures = append(r.textures, tex)
+	return Texture{ID: len(r.textures), Width: tex.Width, Height: tex.Height}
+}
+
+func (r *RaylibRenderer) UpdateTexture(tex Texture, img image.Image) {
+	size := img.Bounds().Size()
+	if tex.ID == 0 || int32(size.X) != tex.Width || int32(size.Y) != tex.Height {
+		return
+	}
+	pixels := make([]color.RGBA, 0, size.X*size.Y)
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			pixels = append(pixels, color.RGBAModel.Convert(img.At(x, y)).(color.RGBA))
+		}
+	}
+	rl.UpdateTexture(r.textures[tex.ID-1], pixels)
+}
+
+// Unload frees the meshes and textures.
+func (r *RaylibRenderer) Unload() {
+	for _, m := range r.models {
+		rl.UnloadModel(m)
+	}
+	for _, t := range r.textures {
+		rl.UnloadTexture(t)
+	}
+	if r.cube.MeshCount > 0 {
+		rl.UnloadModel(r.cube)
+	}
+	r.models, r.textures = nil, nil
+	r.materials = make(map[int]rl.Material)
+}
+
+func (r *RaylibRenderer) BeginFrame(clear rl.Color) {
+	rl.BeginDrawing()
+	rl.ClearBackground(clear)
+}
+
+func (r *RaylibRenderer) EndFrame() {
+	rl.EndDrawing()
+}
+
+func (r *RaylibRenderer) Begin3D(cam rl.Camera3D) {
+	rl.BeginMode3D(cam)
+}
+
+func (r *RaylibRenderer) End3D() {
+	rl.EndMode3D()
+}
+
+// material returns the raylib material of the texture, they are created
+// once and only the color changes between draws.
+func (r *RaylibRenderer) material(tex Texture) rl.Material {
+	m, ok := r.materials[tex.ID]
+	if !ok {
+		m = rl.LoadMaterialDefault()
+		if tex.ID > 0 {
+			m.Maps.Texture = r.textures[tex.ID-1]
+		}
+		r.materials[tex.ID] = m
+	}
+	return m
+}
+
+func (r *RaylibRenderer) DrawMeshInstanced(mesh Mesh, material Material, transforms []rl.Matrix) {
+	m := r.material(material.Texture)
+	if r.Shader != nil {
+		m.Shader = r.Shader.Shader
+	}
+	m.Maps.Color = material.Color
+	rl.DrawMeshInstanced(*r.models[mesh.ID].Meshes, m, transforms, len(transforms))
+}
+
+func (r *RaylibRenderer) DrawCube(transform rl.Matrix, color rl.Color) {
+	if r.cube.MeshCount == 0 {
+		r.cube = rl.LoadModelFromMesh(rl.GenMeshCube(1, 1, 1))
+		r.cubeMat = rl.LoadMaterialDefault()
+	}
+	r.cubeMat.Maps.Color = color
+	rl.DrawMesh(*r.cube.Meshes, r.cubeMat, transform)
+}
+
+func (r *RaylibRenderer) DrawGrid(slices int32, spacing float32) {
+	rl.DrawGrid(slices, spacing)
+}
+
+func (r *RaylibRenderer) DrawRectangle(rect rl.Rectangle, color rl.Color) {
+	rl.DrawRectangleRec(rect, color)
+}
+
+func (r *RaylibRenderer) DrawRectangleLines(rect rl.Rectangle, thickness float32, color rl.Color) {
+	rl.DrawRectangleLinesEx(rect, thickness, color)
+}
+
+func (r *RaylibRenderer) DrawRectangleGradient(rect rl.Rectangle, top, bottom rl.Color) {
+	rl.DrawRectangleGradientV(int32(rect.X), int32(rect.Y), int32(rect.Width), int32(rect.Height), top, bottom)
+}
+
+func (r *RaylibRenderer) DrawLine(from, to rl.Vector2, color rl.Color) {
+	rl.DrawLineV(from, to, color)
+}
+
+func (r *RaylibRenderer) DrawCircle(center rl.Vector2, radius float32, color rl.Color) {
+	rl.DrawCircleV(center, radius, color)
+}
+
+func (r *RaylibRenderer) DrawTexture(tex Texture, src, dst rl.Rectangle, tint rl.Color) {
+	if tex.ID == 0 {
+		rl.DrawRectangleRec(dst, tint)
+		return
+	}
+	rl.DrawTexturePro(r.textures[tex.ID-1], src, dst, rl.Vector2Zero(), 0, tint)
+}
+
+func (r *RaylibRenderer) DrawText(text string, x, y, size int32, color rl.Color) {
+	rl.DrawText(text, x, y, size, color)
+}
+
+func (r *RaylibRenderer) MeasureText(text string, size int32) int32 {
+	return rl.MeasureText(text, size)
+}
@@ -0,0 +1,360 @@
+package gocraft
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidRecipe = errors.New("invalid recipe")
+
+const (
+	// maxCraftingWidth is the size of the largest crafting grid.
+	maxCraftingWidth = 3
+	// noBlock marks an empty cell of a shaped pattern.
+	noBlock BlockType = -1
+)
+
+// Recipe turns the blocks in a crafting grid into a new stack. Shaped
+// recipes need their pattern anywhere in the grid, in any of the four
+// rotations, shapeless recipes only need the right ingredients.
+type Recipe struct {
+	Name      string
+	Shapeless bool
+	// Patterns holds the distinct rotations of a shaped recipe.
+	Patterns [][][]BlockType
+	// Ingredients of a shapeless recipe, sorted.
+	Ingredients []BlockType
+	Result      ItemStack
+}
+
+// recipeFile is the json layout of a recipe file:
+//
+//	{"recipes": [
+//	  {"name": "ground", "pattern": ["##", "##"], "key": {"#": "dirt"},
+//	   "result": {"item": "ground", "count": 4}},
+//	  {"name": "gras", "ingredients": ["dirt", "snow"],
+//	   "result": {"item": "gras"}}
+//	]}
+//
+// Recipes with a pattern are shaped, the others shapeless. Spaces in a
+// pattern are empty cells. Items are named by their block id, the game
+// has no tools or other items that are not blocks yet.
+type recipeFile struct {
+	Recipes []struct {
+		Name        string            `json:"name"`
+		Pattern     []string          `json:"pattern"`
+		Key         map[string]string `json:"key"`
+		Ingredients []string          `json:"ingredients"`
+		Result      struct {
+			Item  string `json:"item"`
+			Count int    `json:"count"`
+		} `json:"result"`
+	} `json:"recipes"`
+}
+
+// RecipeBook holds all known recipes.
+type RecipeBook struct {
+	Recipes []*Recipe
+}
+
+// LoadRecipes reads all json files in dir of fsys. It does not need a
+// window, so it works for the server and in tests.
+func LoadRecipes(fsys fs.FS, dir string) (*RecipeBook, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	book := &RecipeBook{}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		if err := book.parse(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return book, nil
+}
+
+func (b *RecipeBook) parse(data []byte) error {
+	var file recipeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+
+	for i, r := range file.Recipes {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		invalid := func(format string, args ...interface{}) error {
+			return fmt.Errorf("%w %s: %s", ErrInvalidRecipe, name, fmt.Sprintf(format, args...))
+		}
+
+		result, ok := ParseBlockType(r.Result.Item)
+		if !ok {
+			return invalid("unknown result %q", r.Result.Item)
+		}
+		count := r.Result.Count
+		if count == 0 {
+			count = 1
+		}
+		if count < 0 || count > MaxStackSize(result) {
+			return invalid("result count %d out of range", count)
+		}
+		recipe := &Recipe{Name: name, Result: ItemStack{Type: result, Count: count}}
+
+		switch {
+		case len(r.Pattern) > 0 && len(r.Ingredients) > 0:
+			return invalid("has both pattern and ingredients")
+		case len(r.Pattern) > 0:
+			pattern, err := parsePattern(r.Pattern, r.Key)
+			if err != nil {
+				return invalid("%s", err.Error())
+			}
+			recipe.Patterns = rotations(pattern)
+		case len(r.Ingredients) > 0:
+			if len(r.Ingredients) > maxCraftingWidth*maxCraftingWidth {
+				return invalid("more than %d ingredients", maxCraftingWidth*maxCraftingWidth)
+			}
+			recipe.Shapeless = true
+			for _, id := range r.Ingredients {
+				typ, ok := ParseBlockType(id)
+				if !ok {
+					return invalid("unknown ingredient %q", id)
+				}
+				recipe.Ingredients = append(recipe.Ingredients, typ)
+			}
+			sortBlocks(recipe.Ingredients)
+		default:
+			return invalid("needs a pattern or ingredients")
+		}
+		b.Recipes = append(b.Recipes, recipe)
+	}
+	return nil
+}
+
+func parsePattern(rows []string, key map[string]string) ([][]BlockType, error) {
+	if len(rows) > maxCraftingWidth {
+		return nil, fmt.Errorf("pattern has more than %d rows", maxCraftingWidth)
+	}
+	width := len([]rune(rows[0]))
+	pattern := make([][]BlockType, len(rows))
+	for y, row := range rows {
+		cells := []rune(row)
+		if len(cells) != width {
+			return nil, fmt.Errorf("pattern rows differ in length")
+		}
+		if width == 0 || width > maxCraftingWidth {
+			return nil, fmt.Errorf("pattern must be 1 to %d wide", maxCraftingWidth)
+		}
+		pattern[y] = make([]BlockType, width)
+		for x, c := range cells {
+			if c == ' ' {
+				pattern[y][x] = noBlock
+				continue
+			}
+			id, ok := key[string(c)]
+			if !ok {
+				return nil, fmt.Errorf("%q is not in the key", c)
+			}
+			typ, ok := ParseBlockType(id)
+			if !ok {
+				return nil, fmt.Errorf("unknown ingredient %q", id)
+			}
+			pattern[y][x] = typ
+		}
+	}
+	pattern = trimPattern(pattern)
+	if len(pattern) == 0 {
+		return nil, fmt.Errorf("pattern is empty")
+	}
+	return pattern, nil
+}
+
+// rotations returns the pattern turned by 0, 90, 180 and 270 degrees
+// without duplicates.
+func rotations(pattern [][]BlockType) [][][]BlockType {
+	var (
+		result [][][]BlockType
+		seen   = map[string]bool{}
+	)
+	for i := 0; i < 4; i++ {
+		if key := patternKey(pattern); !seen[key] {
+			seen[key] = true
+			result = append(result, pattern)
+		}
+		pattern = rotate(pattern)
+	}
+	return result
+}
+
+// rotate turns a pattern clockwise.
+func rotate(pattern [][]BlockType) [][]BlockType {
+	var (
+		h = len(pattern)
+		w = len(pattern[0])
+		r = make([][]BlockType, w)
+	)
+	for y := range r {
+		r[y] = make([]BlockType, h)
+		for x := range r[y] {
+			r[y][x] = pattern[h-1-x][y]
+		}
+	}
+	return r
+}
+
+func patternKey(pattern [][]BlockType) string {
+	var sb strings.Builder
+	for _, row := range pattern {
+		for _, c := range row {
+			fmt.Fprintf(&sb, "%d,", c)
+		}
+		sb.WriteByte('/')
+	}
+	return sb.String()
+}
+
+// trimPattern cuts off empty rows and columns around the filled cells,
+// a pattern without any is nil.
+func trimPattern(pattern [][]BlockType) [][]BlockType {
+	if len(pattern) == 0 || len(pattern[0]) == 0 {
+		return nil
+	}
+	minX, minY, maxX, maxY := len(pattern[0]), len(pattern), -1, -1
+	for y, row := range pattern {
+		for x, c := range row {
+			if c == noBlock {
+				continue
+			}
+			if x < minX {
+				minX = x
+			}
+			if x > maxX {
+				maxX = x
+			}
+			if y < minY {
+				minY = y
+			}
+			if y > maxY {
+				maxY = y
+			}
+		}
+	}
+	if maxX < 0 {
+		return nil
+	}
+	trimmed := make([][]BlockType, 0, maxY-minY+1)
+	for y := minY; y <= maxY; y++ {
+		trimmed = append(trimmed, pattern[y][minX:maxX+1])
+	}
+	return trimmed
+}
+
+func sortBlocks(blocks []BlockType) {
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i] < blocks[j] })
+}
+
+// CraftingGrid is a square grid of slots, row by row.
+type CraftingGrid struct {
+	Width int
+	Slots []ItemStack
+}
+
+func NewCraftingGrid(width int) *CraftingGrid {
+	return &CraftingGrid{Width: width, Slots: make([]ItemStack, width*width)}
+}
+
+func (g *CraftingGrid) pattern() [][]BlockType {
+	if g.Width < 1 || len(g.Slots) < g.Width*g.Width {
+		return nil
+	}
+	pattern := make([][]BlockType, g.Width)
+	for y := range pattern {
+		pattern[y] = make([]BlockType, g.Width)
+		for x := range pattern[y] {
+			pattern[y][x] = noBlock
+			if s := g.Slots[y*g.Width+x]; !s.Empty() {
+				pattern[y][x] = s.Type
+			}
+		}
+	}
+	return trimPattern(pattern)
+}
+
+func (g *CraftingGrid) ingredients() []BlockType {
+	var blocks []BlockType
+	for _, s := range g.Slots {
+		if !s.Empty() {
+			blocks = append(blocks, s.Type)
+		}
+	}
+	sortBlocks(blocks)
+	return blocks
+}
+
+// Match returns the recipe the grid is laid out for, or nil.
+func (b *RecipeBook) Match(g *CraftingGrid) *Recipe {
+	pattern := g.pattern()
+	if pattern == nil {
+		return nil
+	}
+	var (
+		key         = patternKey(pattern)
+		ingredients = g.ingredients()
+	)
+	for _, r := range b.Recipes {
+		if r.Shapeless {
+			if equalBlocks(r.Ingredients, ingredients) {
+				return r
+			}
+			continue
+		}
+		for _, p := range r.Patterns {
+			if patternKey(p) == key {
+				return r
+			}
+		}
+	}
+	return nil
+}
+
+// Craft uses one block of every filled slot and returns the result of
+// the matching recipe.
+func (b *RecipeBook) Craft(g *CraftingGrid) (ItemStack, bool) {
+	r := b.Match(g)
+	if r == nil {
+		return ItemStack{}, false
+	}
+	for i := range g.Slots {
+		if g.Slots[i].Empty() {
+			continue
+		}
+		g.Slots[i].Count--
+		if g.Slots[i].Empty() {
+			g.Slots[i] = ItemStack{}
+		}
+	}
+	return r.Result, true
+}
+
+func equalBlocks(a, b []BlockType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,283 @@
+package gocraft
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// testRecipes is the recipe file shipped with the game.
+func testRecipes(t *testing.T) *RecipeBook {
+	t.Helper()
+	resources, err := NewResources(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resources.Close()
+	book, err := LoadRecipes(resources, "recipes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return book
+}
+
+// grid lays out rows of a crafting grid of len(rows) width, one block of
+// a type per cell. The letters are the first letter of the block id, a
+// space or dot is empty.
+func grid(rows ...string) *CraftingGrid {
+	g := NewCraftingGrid(len(rows))
+	for y, row := range rows {
+		for x, c := range row {
+			var typ BlockType
+			switch c {
+			case 'd':
+				typ = BlockTypeDirt
+			case 'g':
+				typ = BlockTypeGras
+			case 's':
+				typ = BlockTypeSnow
+			case 'r':
+				typ = BlockTypeRock
+			case 'G':
+				typ = BlockTypeGroud
+			default:
+				continue
+			}
+			g.Slots[y*g.Width+x] = ItemStack{Type: typ, Count: 1}
+		}
+	}
+	return g
+}
+
+func TestLoadRecipes(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		// want is the number of recipes, or -1 with an error
+		want    int
+		wantErr error
+	}{
+		{"shaped and shapeless", `{"recipes": [
+			{"pattern": ["##"], "key": {"#": "dirt"}, "result": {"item": "rock"}},
+			{"ingredients": ["dirt", "rock"], "result": {"item": "gras", "count": 2}}
+		]}`, 2, nil},
+		{"empty file", `{}`, 0, nil},
+		{"malformed json", `{"recipes": [`, -1, nil},
+		{"unknown result", `{"recipes": [{"ingredients": ["dirt"], "result": {"item": "diamond"}}]}`, -1, ErrInvalidRecipe},
+		{"unknown ingredient", `{"recipes": [{"ingredients": ["diamond"], "result": {"item": "dirt"}}]}`, -1, ErrInvalidRecipe},
+		{"unknown item in key", `{"recipes": [{"pattern": ["#"], "key": {"#": "diamond"}, "result": {"item": "dirt"}}]}`, -1, ErrInvalidRecipe},
+		{"cell not in key", `{"recipes": [{"pattern": ["#x"], "key": {"#": "dirt"}, "result": {"item": "dirt"}}]}`, -1, ErrInvalidRecipe},
+		{"rows of different length", `{"recipes": [{"pattern": ["##", "#"], "key": {"#": "dirt"}, "result": {"item": "dirt"}}]}`, -1, ErrInvalidRecipe},
+		{"pattern too wide", `{"recipes": [{"pattern": ["####"], "key": {"#": "dirt"}, "result": {"item": "dirt"}}]}`, -1, ErrInvalidRecipe},
+		{"pattern only spaces", `{"recipes": [{"pattern": ["  "], "result": {"item": "dirt"}}]}`, -1, ErrInvalidRecipe},
+		{"pattern and ingredients", `{"recipes": [{"pattern": ["#"], "key": {"#": "dirt"}, "ingredients": ["dirt"], "result": {"item": "dirt"}}]}`, -1, ErrInvalidRecipe},
+		{"neither", `{"recipes": [{"result": {"item": "dirt"}}]}`, -1, ErrInvalidRecipe},
+		{"count too large", `{"recipes": [{"ingredients": ["dirt"], "result": {"item": "dirt", "count": 1000}}]}`, -1, ErrInvalidRecipe},
+		{"too many ingredients", `{"recipes": [{"ingredients": ["dirt", "dirt", "dirt", "dirt", "dirt", "dirt", "dirt", "dirt", "dirt", "dirt"], "result": {"item": "dirt"}}]}`, -1, ErrInvalidRecipe},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{
+				"recipes/test.json":  {Data: []byte(tt.file)},
+				"recipes/readme.txt": {Data: []byte("not a recipe")},
+			}
+			book, err := LoadRecipes(fsys, "recipes")
+			if tt.want >= 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(book.Recipes) != tt.want {
+					t.Errorf("%d recipes, want %d", len(book.Recipes), tt.want)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if !strings.Contains(err.Error(), "recipes/test.json") {
+				t.Errorf("error %q does not name the file", err)
+			}
+			var syntax *json.SyntaxError
+			if tt.wantErr == nil && !errors.As(err, &syntax) {
+				t.Errorf("got %v, want a json syntax error", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestShapedRecipeMatches(t *testing.T) {
+	book := testRecipes(t)
+	tests := []struct {
+		recipe  string
+		pattern []string
+	}{
+		{"ground", []string{"dd", "dd"}},
+		{"rock", []string{"G ", "GG"}},
+		{"snow", []string{"rrr", "r r", "rrr"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.recipe, func(t *testing.T) {
+			pattern := tt.pattern
+			for turn := 0; turn < 4; turn++ {
+				h, w := len(pattern), len(pattern[0])
+				for oy := 0; oy+h <= maxCraftingWidth; oy++ {
+					for ox := 0; ox+w <= maxCraftingWidth; ox++ {
+						rows := []string{"...", "...", "..."}
+						for y, row := range pattern {
+							rows[oy+y] = rows[oy+y][:ox] + row + rows[oy+y][ox+w:]
+						}
+						r := book.Match(grid(rows...))
+						if r == nil || r.Name != tt.recipe {
+							t.Errorf("%v matched %v, want %s", rows, r, tt.recipe)
+						}
+					}
+				}
+				pattern = rotateRows(pattern)
+			}
+		})
+	}
+}
+
+// rotateRows turns a pattern given as rows clockwise.
+func rotateRows(rows []string) []string {
+	turned := make([]string, len(rows[0]))
+	for y := range turned {
+		for x := len(rows) - 1; x >= 0; x-- {
+			turned[y] += string(rows[x][y])
+		}
+	}
+	return turned
+}
+
+func TestShapelessRecipeMatches(t *testing.T) {
+	book := testRecipes(t)
+	for _, rows := range [][]string{
+		{"ds.", "...", "..."},
+		{"s..", "...", "..d"},
+		{"...", ".d.", "s.."},
+		{"sd", ".."},
+	} {
+		if r := book.Match(grid(rows...)); r == nil || r.Name != "gras" {
+			t.Errorf("%v matched %v, want gras", rows, r)
+		}
+	}
+}
+
+func TestRecipeDoesNotMatch(t *testing.T) {
+	book := testRecipes(t)
+	tests := []struct {
+		name string
+		rows []string
+	}{
+		{"empty", []string{"...", "...", "..."}},
+		{"no slots", nil},
+		{"one block short", []string{"dd.", "d..", "..."}},
+		{"apart", []string{"d.d", "...", "d.d"}},
+		{"wrong block", []string{"dd.", "dr.", "..."}},
+		{"ring with a hole", []string{"rrr", "r r", "rr."}},
+		{"square of the wrong block", []string{"rr", "rr"}},
+		{"shapeless with an extra block", []string{"dsd", "...", "..."}},
+		{"shapeless missing a block", []string{"d..", "...", "..."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if r := book.Match(grid(tt.rows...)); r != nil {
+				t.Errorf("%v matched %s", tt.rows, r.Name)
+			}
+		})
+	}
+}
+
+func TestCraftUsesOneOfEverySlot(t *testing.T) {
+	book := testRecipes(t)
+	g := grid("dd.", "dd.", "...")
+	g.Slots[0].Count = 3
+
+	result, ok := book.Craft(g)
+	if !ok || result != (ItemStack{Type: BlockTypeGroud, Count: 4}) {
+		t.Fatalf("crafted %v, %v", result, ok)
+	}
+	if g.Slots[0] != (ItemStack{Type: BlockTypeDirt, Count: 2}) {
+		t.Errorf("first slot holds %v, want 2 dirt", g.Slots[0])
+	}
+	for _, i := range []int{1, 3, 4} {
+		if !g.Slots[i].Empty() {
+			t.Errorf("slot %d still holds %v", i, g.Slots[i])
+		}
+	}
+	if _, ok := book.Craft(g); ok {
+		t.Error("crafted again with only one slot filled")
+	}
+}
+
+func TestServerApplyCraft(t *testing.T) {
+	tests := []struct {
+		name  string
+		have  []ItemStack
+		grid  *CraftingGrid
+		after map[BlockType]int
+	}{
+		{
+			name:  "crafts",
+			have:  []ItemStack{{Type: BlockTypeDirt, Count: 5}},
+			grid:  grid("dd.", "dd.", "..."),
+			after: map[BlockType]int{BlockTypeDirt: 1, BlockTypeGroud: 4},
+		},
+		{
+			name:  "shapeless",
+			have:  []ItemStack{{Type: BlockTypeDirt, Count: 1}, {Type: BlockTypeSnow, Count: 1}},
+			grid:  grid("s.", ".d"),
+			after: map[BlockType]int{BlockTypeDirt: 0, BlockTypeSnow: 0, BlockTypeGras: 2},
+		},
+		{
+			name:  "not enough blocks",
+			have:  []ItemStack{{Type: BlockTypeDirt, Count: 3}},
+			grid:  grid("dd.", "dd.", "..."),
+			after: map[BlockType]int{BlockTypeDirt: 3, BlockTypeGroud: 0},
+		},
+		{
+			name:  "no recipe",
+			have:  []ItemStack{{Type: BlockTypeDirt, Count: 3}},
+			grid:  grid("ddd", "...", "..."),
+			after: map[BlockType]int{BlockTypeDirt: 3, BlockTypeGroud: 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewServer(testServerConfig())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Shutdown("done")
+			conn, _ := net.Pipe()
+			defer conn.Close()
+			c, err := s.join(conn, "alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			inv := &c.player.Inventory
+			inv.Slots = [inventorySize]ItemStack{}
+			for _, stack := range tt.have {
+				inv.Add(stack.Type, stack.Count)
+			}
+			inv.changed = false
+
+			s.applyCraft(c, &CraftPacket{Grid: *tt.grid})
+			for typ, want := range tt.after {
+				if got := inv.Count(typ); got != want {
+					t.Errorf("%d %s in the inventory, want %d", got, typ, want)
+				}
+			}
+			if !inv.changed {
+				t.Error("the inventory is not sent back to the client")
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package gocraft
+
+import (
+	"image"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// DrawOp is the kind of a recorded draw call.
+type DrawOp int
+
+const (
+	DrawOpMesh DrawOp = iota
+	DrawOpCube
+	DrawOpGrid
+	DrawOpRectangle
+	DrawOpRectangleLines
+	DrawOpGradient
+	DrawOpLine
+	DrawOpCircle
+	DrawOpTexture
+	DrawOpText
+)
+
+// DrawCall is a call recorded by the RecordingRenderer. Only the fields
+// of its Op are set.
+type DrawCall struct {
+	Op DrawOp
+	// In3D is set for calls between Begin3D and End3D.
+	In3D bool
+
+	Mesh       Mesh
+	Material   Material
+	Transforms []rl.Matrix
+	Texture    Texture
+	Rect       rl.Rectangle
+	Color      rl.Color
+	Text       string
+}
+
+// RecordingRenderer keeps the draw calls instead of drawing them, for
+// tests and for running without a window. It draws nothing, so it is
+// also the null renderer.
+type RecordingRenderer struct {
+	Width, Height int32
+	// Calls of the current frame, BeginFrame clears them.
+	Calls []DrawCall
+	// Meshes and Textures are the loaded ones by id.
+	Meshes   []*MeshData
+	Textures []image.Image
+	// Frames counts the finished frames.
+	Frames int
+
+	in3D bool
+}
+
+func NewRecordingRenderer(width, height int32) *RecordingRenderer {
+	return &RecordingRenderer{Width: width, Height: height}
+}
+
+func (r *RecordingRenderer) ScreenSize() (int32, int32) {
+	return r.Width, r.Height
+}
+
+func (r *RecordingRenderer) LoadMesh(data *MeshData) (Mesh, error) {
+	r.Meshes = append(r.Meshes, data)
+	return Mesh{ID: len(r.Meshes) - 1}, nil
+}
+
+func (r *RecordingRenderer) LoadTexture(img image.Image) Texture {
+	r.Textures = append(r.Textures, img)
+	size := img.Bounds().Size()
+	return Texture{ID: len(r.Textures), Width: int32(size.X), Height: int32(size.Y)}
+}
+
+func (r *RecordingRenderer) UpdateTexture(tex Texture, img image.Image) {
+	if tex.ID > 0 {
+		r.Textures[tex.ID-1] = img
+	}
+}
+
+func (r *RecordingRenderer) BeginFrame(clear rl.Color) {
+	r.Calls = r.Calls[:0]
+}
+
+func (r *RecordingRenderer) EndFrame() {
+	r.Frames++
+}
+
+func (r *RecordingRenderer) Begin3D(cam rl.Camera3D) {
+	r.in3D = true
+}
+
+func (r *RecordingRenderer) End3D() {
+	r.in3D = false
+}
+
+func (r *RecordingRenderer) record(call DrawCall) {
+	call.In3D = r.in3D
+	r.Calls = append(r.Calls, call)
+}
+
+func (r *RecordingRenderer) DrawMeshInstanced(mesh Mesh, material Material, transforms []rl.Matrix) {
+	r.record(DrawCall{Op: DrawOpMesh, Mesh: mesh, Material: material, Transforms: transforms})
+}
+
+func (r *RecordingRenderer) DrawCube(transform rl.Matrix, color rl.Color) {
+	r.record(DrawCall{Op: DrawOpCube, Transforms: []rl.Matrix{transform}, Color: color})
+}
+
+func (r *RecordingRenderer) DrawGrid(slices int32, spacing float32) {
+	r.record(DrawCall{Op: DrawOpGrid})
+}
+
+func (r *RecordingRenderer) DrawRectangle(rect rl.Rectangle, color rl.Color) {
+	r.record(DrawCall{Op: DrawOpRectangle, Rect: rect, Color: color})
+}
+
+func (r *RecordingRenderer) DrawRectangleLines(rect rl.Rectangle, thickness float32, color rl.Color) {
+	r.record(DrawCall{Op: DrawOpRectangleLines, Rect: rect, Color: color})
+}
+
+func (r *RecordingRenderer) DrawRectangleGradient(rect rl.Rectangle, top, bottom rl.Color) {
+	r.record(DrawCall{Op: DrawOpGradient, Rect: rect, Color: top})
+}
+
+func (r *RecordingRenderer) DrawLine(from, to rl.Vector2, color rl.Color) {
+	r.record(DrawCall{Op: DrawOpLine, Rect: rl.NewRectangle(from.X, from.Y, to.X-from.X, to.Y-from.Y), Color: color})
+}
+
+func (r *RecordingRenderer) DrawCircle(center rl.Vector2, radius float32, color rl.Color) {
+	r.record(DrawCall{Op: DrawOpCircle, Rect: rl.NewRectangle(center.X-radius, center.Y-radius, 2*radius, 2*radius), Color: color})
+}
+
+func (r *RecordingRenderer) DrawTexture(tex Texture, src, dst rl.Rectangle, tint rl.Color) {
+	r.record(DrawCall{Op: DrawOpTexture, Texture: tex, Rect: dst, Color: tint})
+}
+
+func (r *RecordingRenderer) DrawText(text string, x, y, size int32, color rl.Color) {
+	r.record(DrawCall{Op: DrawOpText, Text: text, Rect: recti(x, y, r.MeasureText(text, size), size), Color: color})
+}
+
+// MeasureText pretends every character is 0.6 times the size wide,
+// which is about the average of the raylib font.
+func (r *RecordingRenderer) MeasureText(text string, size int32) int32 {
+	return int32(len(text)) * size * 3 / 5
+}
+
+// Count is the number of recorded calls of op.
+func (r *RecordingRenderer) Count(op DrawOp) int {
+	n := 0
+	for _, c := range r.Calls {
+		if c.Op == op {
+			n++
+		}
+	}
+	return n
+}
+
+// Texts are the recorded texts in drawing order.
+func (r *RecordingRenderer) Texts() []string {
+	var texts []string
+	for _, c := range r.Calls {
+		if c.Op == DrawOpText {
+			texts = append(texts, c.Text)
+		}
+	}
+	return texts
+}
@@ -0,0 +1,232 @@
+package gocraft
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/urfave/cli/v2"
+)
+
+var ErrImageMismatch = errors.New("images differ")
+
+// renderEyeHeight is how far above the surface the render command looks
+// from when no height is given.
+const renderEyeHeight = 12
+
+// View is where the world is rendered from by RenderWorld.
+type View struct {
+	Position   rl.Vector3
+	Yaw, Pitch float32
+	FOV        float32
+	// Time of day in days, 0 is midnight and 0.5 noon.
+	Time float32
+}
+
+// Camera3D is the raylib camera of the view.
+func (v View) Camera3D() rl.Camera3D {
+	cam := NewCamera(v.FOV, 0)
+	cam.Turn(v.Yaw, v.Pitch)
+	cam.Update(v.Position, nil, 0)
+	return cam.Camera3D()
+}
+
+// RenderWorld draws the loaded chunks of the world as seen from view with
+// the software renderer. The blocks have to be loaded into r before.
+func RenderWorld(r *SoftwareRenderer, world *World, block Mesh, sky *Sky, view View, ambient float32) *image.RGBA {
+	var (
+		w, h     = r.ScreenSize()
+		cam      = view.Camera3D()
+		frustum  = NewFrustum(cam, float32(w)/float32(h))
+		stats    RenderStats
+		sections = world.Chunks.cullSections(world.Chunks.Chunks(), cam.Position, frustum, true, &stats)
+	)
+	r.LightDirection = sky.LightDirection()
+	r.Ambient = ambient * sky.LightLevel()
+
+	r.BeginFrame(sky.FogColor())
+	sky.DrawBackground(r, cam)
+	r.Begin3D(cam)
+	for _, s := range sections {
+		s.render(r, block, &stats)
+	}
+	r.End3D()
+	r.EndFrame()
+	return r.Image
+}
+
+// skyTicks are the world ticks at which the sky shows the time of day.
+func skyTicks(config Config, time float32) uint64 {
+	// a new world starts in the morning at 0.3
+	days := float64(time) - 0.3
+	if days < 0 {
+		days++
+	}
+	return uint64(days * float64(config.Sky.DayLength) * float64(config.TickRate))
+}
+
+// ImageDiff is the result of comparing two images of the same size.
+type ImageDiff struct {
+	// Pixels is the number of pixels differing by more than the
+	// threshold in any channel.
+	Pixels int
+	// Fraction is Pixels of all pixels.
+	Fraction float64
+	// MaxDelta is the largest difference of a channel.
+	MaxDelta uint8
+	// Image shows differing pixels in red over the dimmed reference.
+	Image *image.RGBA
+}
+
+// CompareImages compares got against the reference want. Channels may
+// differ by threshold before the pixel counts as different.
+func CompareImages(got, want image.Image, threshold uint8) (ImageDiff, error) {
+	if got.Bounds().Size() != want.Bounds().Size() {
+		return ImageDiff{}, fmt.Errorf("%w: size %v, expected %v", ErrImageMismatch, got.Bounds().Size(), want.Bounds().Size())
+	}
+	var (
+		size = got.Bounds().Size()
+		diff = ImageDiff{Image: image.NewRGBA(image.Rect(0, 0, size.X, size.Y))}
+	)
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			a := color.RGBAModel.Convert(got.At(got.Bounds().Min.X+x, got.Bounds().Min.Y+y)).(color.RGBA)
+			b := color.RGBAModel.Convert(want.At(want.Bounds().Min.X+x, want.Bounds().Min.Y+y)).(color.RGBA)
+			delta := maxDelta(a, b)
+			if delta > diff.MaxDelta {
+				diff.MaxDelta = delta
+			}
+			if delta > threshold {
+				diff.Pixels++
+				diff.Image.SetRGBA(x, y, color.RGBA{R: 255, A: 255})
+				continue
+			}
+			diff.Image.SetRGBA(x, y, color.RGBA{R: b.R / 3, G: b.G / 3, B: b.B / 3, A: 255})
+		}
+	}
+	diff.Fraction = float64(diff.Pixels) / float64(size.X*size.Y)
+	return diff, nil
+}
+
+func maxDelta(a, b color.RGBA) uint8 {
+	var d uint8
+	for _, c := range [][2]uint8{{a.R, b.R}, {a.G, b.G}, {a.B, b.B}, {a.A, b.A}} {
+		v := c[0] - c[1]
+		if c[1] > c[0] {
+			v = c[1] - c[0]
+		}
+		if v > d {
+			d = v
+		}
+	}
+	return d
+}
+
+func loadPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return img, nil
+}
+
+func writePNG(path string, img image.Image) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// RunRender is the action of the render command. It renders a view of a
+// new world of the seed without a window and writes it as png. With
+// --compare the image is checked against a reference image instead and
+// the command fails when too many pixels differ.
+func RunRender(ctx *cli.Context) error {
+	config, _, err := ResolveConfig(ctx)
+	if err != nil {
+		return err
+	}
+	resources, err := NewResources(config.ResourcePacks)
+	if err != nil {
+		return err
+	}
+	defer resources.Close()
+
+	// the image has the size of the window
+	r := NewSoftwareRenderer(config.Width, config.Height)
+	blockData, err := resources.LoadMesh("models/brick.obj")
+	if err != nil {
+		return err
+	}
+	block, err := r.LoadMesh(blockData)
+	if err != nil {
+		return err
+	}
+	defs, err := resources.LoadBlockDefinitions()
+	if err != nil {
+		return err
+	}
+	loadBlocks(r, resources, defs)
+
+	view := View{
+		Position: rl.NewVector3(float32(ctx.Float64("x")), float32(ctx.Float64("y")), float32(ctx.Float64("z"))),
+		Yaw:      float32(ctx.Float64("yaw")),
+		Pitch:    float32(ctx.Float64("pitch")),
+		FOV:      config.Settings.FOV,
+		Time:     float32(ctx.Float64("time")),
+	}
+	world := NewWorld(config.ChunkSize, config.Seed, nil, config.Terrain)
+	world.Chunks.LoadAround(view.Position, ctx.Int("view-distance"))
+	if !ctx.IsSet("y") {
+		x, _, z := blockCoord(view.Position)
+		view.Position.Y = float32(world.Chunks.SurfaceHeight(x, z) + renderEyeHeight)
+	}
+	sky := NewSky(config.Sky, config.Seed)
+	sky.Update(skyTicks(config, view.Time), config.TickRate)
+	img := RenderWorld(r, world, block, sky, view, config.Light.Ambient)
+
+	if ref := ctx.String("compare"); ref != "" {
+		return compareReference(ctx, img, ref)
+	}
+	out := ctx.String("out")
+	if err := writePNG(out, img); err != nil {
+		return err
+	}
+	fmt.Fprintf(ctx.App.Writer, "wrote %s (seed %d, %.1f %.1f %.1f, yaw %.1f, pitch %.1f)\n",
+		out, config.Seed, view.Position.X, view.Position.Y, view.Position.Z, view.Yaw, view.Pitch)
+	return nil
+}
+
+// compareReference checks img against the reference png. The difference
+// image is written to --diff when the check fails.
+func compareReference(ctx *cli.Context, img image.Image, ref string) error {
+	want, err := loadPNG(ref)
+	if err != nil {
+		return err
+	}
+	diff, err := CompareImages(img, want, uint8(ctx.Uint("threshold")))
+	if err != nil {
+		return fmt.Errorf("%s: %w", ref, err)
+	}
+	fmt.Fprintf(ctx.App.Writer, "%s: %d pixels differ (%.3f%%), max delta %d\n", ref, diff.Pixels, diff.Fraction*100, diff.MaxDelta)
+	if diff.Fraction <= ctx.Float64("tolerance") {
+		return nil
+	}
+	if path := ctx.String("diff"); path != "" {
+		if err := writePNG(path, diff.Image); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("%s: %w: %.3f%% of the pixels, %.3f%% allowed", ref, ErrImageMismatch, diff.Fraction*100, ctx.Float64("tolerance")*100)
+}
@@ -0,0 +1,83 @@
+package gocraft
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const goldenDir = "../../testdata/golden"
+
+// TestRenderGolden renders the view of the README like
+//
+//	gocraft --seed 1337 --width 320 --height 180 render
+//
+// and compares it with the reference image. Update the reference with
+// that command when the change of the image is intended.
+func TestRenderGolden(t *testing.T) {
+	if testing.Short() {
+		t.Skip("generating the chunks takes seconds")
+	}
+	const (
+		// the defaults of the render command
+		threshold = 8
+		tolerance = 0.001
+	)
+	config := DefaultConfig()
+	config.Seed = 1337
+	config.Width, config.Height = 320, 180
+
+	resources, err := NewResources(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resources.Close()
+	r := NewSoftwareRenderer(config.Width, config.Height)
+	blockData, err := resources.LoadMesh("models/brick.obj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := r.LoadMesh(blockData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, err := resources.LoadBlockDefinitions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loadBlocks(r, resources, defs)
+
+	view := View{Pitch: -20, FOV: config.Settings.FOV, Time: 0.5}
+	world := NewWorld(config.ChunkSize, config.Seed, nil, config.Terrain)
+	world.Chunks.LoadAround(view.Position, 1)
+	view.Position.Y = float32(world.Chunks.SurfaceHeight(0, 0) + renderEyeHeight)
+	sky := NewSky(config.Sky, config.Seed)
+	sky.Update(skyTicks(config, view.Time), config.TickRate)
+	img := RenderWorld(r, world, block, sky, view, config.Light.Ambient)
+
+	want, err := loadPNG(filepath.Join(goldenDir, "seed1337.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := CompareImages(img, want, threshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Fraction <= tolerance {
+		return
+	}
+
+	// the test directory is removed, the images have to outlive the test
+	dir, err := os.MkdirTemp("", "gocraft-render-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writePNG(filepath.Join(dir, "got.png"), img); err != nil {
+		t.Error(err)
+	}
+	if err := writePNG(filepath.Join(dir, "diff.png"), diff.Image); err != nil {
+		t.Error(err)
+	}
+	t.Errorf("%d pixels differ (%.3f%%, %.3f%% allowed), max delta %d, see %s",
+		diff.Pixels, diff.Fraction*100, tolerance*100, diff.MaxDelta, dir)
+}
@@ -0,0 +1,78 @@
+package gocraft
+
+import (
+	"image"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Texture is a texture loaded by a Renderer. The zero Texture is plain
+// white.
+type Texture struct {
+	ID            int
+	Width, Height int32
+}
+
+// Mesh is a mesh loaded by a Renderer.
+type Mesh struct {
+	ID int
+}
+
+// Material is how a mesh is drawn, the texture tinted with Color.
+type Material struct {
+	Texture Texture
+	Color   rl.Color
+}
+
+// Renderer draws the game. The RaylibRenderer draws into the window,
+// the SoftwareRenderer draws into an image on the CPU and the
+// RecordingRenderer keeps the calls so drawing code can be tested without
+// a window.
+//
+// A frame is drawn between BeginFrame and EndFrame. Meshes and cubes are
+// drawn between Begin3D and End3D, everything else is drawn on top in
+// screen coordinates.
+type Renderer interface {
+	// ScreenSize is the size of what is drawn to in pixels.
+	ScreenSize() (width, height int32)
+
+	LoadMesh(data *MeshData) (Mesh, error)
+	LoadTexture(img image.Image) Texture
+	// UpdateTexture replaces the pixels of a loaded texture, img has to
+	// be of the same size.
+	UpdateTexture(tex Texture, img image.Image)
+
+	BeginFrame(clear rl.Color)
+	EndFrame()
+	Begin3D(cam rl.Camera3D)
+	End3D()
+
+	// DrawMeshInstanced draws the mesh once for every transform.
+	DrawMeshInstanced(mesh Mesh, material Material, transforms []rl.Matrix)
+	// DrawCube draws a unit cube around the origin moved by transform.
+	DrawCube(transform rl.Matrix, color rl.Color)
+	// DrawGrid draws a grid of lines on the ground around the origin.
+	DrawGrid(slices int32, spacing float32)
+
+	DrawRectangle(rect rl.Rectangle, color rl.Color)
+	DrawRectangleLines(rect rl.Rectangle, thickness float32, color rl.Color)
+	// DrawRectangleGradient fades from top to bottom.
+	DrawRectangleGradient(rect rl.Rectangle, top, bottom rl.Color)
+	DrawLine(from, to rl.Vector2, color rl.Color)
+	DrawCircle(center rl.Vector2, radius float32, color rl.Color)
+	// DrawTexture draws the part src of the texture into dst.
+	DrawTexture(tex Texture, src, dst rl.Rectangle, tint rl.Color)
+	DrawText(text string, x, y, size int32, color rl.Color)
+	MeasureText(text string, size int32) int32
+}
+
+// recti is a rectangle in whole pixels.
+func recti(x, y, width, height int32) rl.Rectangle {
+	return rl.NewRectangle(float32(x), float32(y), float32(width), float32(height))
+}
+
+// screenRect covers the whole screen of r.
+func screenRect(r Renderer) rl.Rectangle {
+	w, h := r.ScreenSize()
+	return recti(0, 0, w, h)
+}
@@ -0,0 +1,241 @@
+package gocraft
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/png"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/tinogoehlert/gocraft/res"
+)
+
+var ErrResourcePack = errors.New("not a resource pack")
+
+// builtinResources is the name of the embedded resources in errors.
+const builtinResources = "builtin"
+
+// blockDefinitionsFile lists the block types with their textures.
+const blockDefinitionsFile = "blocks.json"
+
+type resourceLayer struct {
+	name string
+	fsys fs.FS
+	// closer closes the zip file of the layer
+	closer io.Closer
+}
+
+// Resources are the files the game loads, like textures and shaders.
+// They come from resource packs layered on top of the files embedded in
+// the binary, a file in a pack replaces the one with the same path in
+// the packs after it and in the embedded files.
+//
+// Resources is a fs.FS, listing a directory lists the files of all
+// layers.
+type Resources struct {
+	layers []resourceLayer
+}
+
+// NewResources opens the resource packs, which are directories or zip
+// files laid out like the res directory. The first pack has the highest
+// precedence.
+func NewResources(packs []string) (*Resources, error) {
+	r := &Resources{}
+	for _, pack := range packs {
+		layer, err := openResourcePack(pack)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.layers = append(r.layers, layer)
+	}
+	r.layers = append(r.layers, resourceLayer{name: builtinResources, fsys: res.FS})
+	return r, nil
+}
+
+func openResourcePack(pack string) (resourceLayer, error) {
+	info, err := os.Stat(pack)
+	if err != nil {
+		return resourceLayer{}, fmt.Errorf("resource pack: %w", err)
+	}
+	if info.IsDir() {
+		return resourceLayer{name: pack, fsys: os.DirFS(pack)}, nil
+	}
+	if !strings.EqualFold(path.Ext(pack), ".zip") {
+		return resourceLayer{}, fmt.Errorf("%s: %w, it has to be a directory or zip file", pack, ErrResourcePack)
+	}
+	z, err := zip.OpenReader(pack)
+	if err != nil {
+		return resourceLayer{}, fmt.Errorf("resource pack %s: %w", pack, err)
+	}
+	return resourceLayer{name: pack, fsys: z, closer: z}, nil
+}
+
+// Close closes the zip files of the packs.
+func (r *Resources) Close() error {
+	var first error
+	for _, l := range r.layers {
+		if l.closer == nil {
+			continue
+		}
+		if err := l.closer.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Packs are the names of the layers by precedence, the embedded files
+// are last.
+func (r *Resources) Packs() []string {
+	names := make([]string, len(r.layers))
+	for i, l := range r.layers {
+		names[i] = l.name
+	}
+	return names
+}
+
+// Open opens the file from the first layer that has it.
+func (r *Resources) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, l := range r.layers {
+		f, err := l.fsys.Open(name)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", l.name, err)
+		}
+	}
+	return nil, &fs.PathError{
+		Op:   "open",
+		Path: name,
+		Err:  fmt.Errorf("%w in %s", fs.ErrNotExist, strings.Join(r.Packs(), ", ")),
+	}
+}
+
+// ReadDir lists the directory in all layers, a file in more than one
+// layer is listed once.
+func (r *Resources) ReadDir(name string) ([]fs.DirEntry, error) {
+	var (
+		seen    = make(map[string]bool)
+		entries []fs.DirEntry
+		found   bool
+	)
+	for _, l := range r.layers {
+		list, err := fs.ReadDir(l.fsys, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", l.name, err)
+		}
+		found = true
+		for _, e := range list {
+			if !seen[e.Name()] {
+				seen[e.Name()] = true
+				entries = append(entries, e)
+			}
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// Origin is the name of the layer the file is loaded from, empty if no
+// layer has it.
+func (r *Resources) Origin(name string) string {
+	for _, l := range r.layers {
+		if _, err := fs.Stat(l.fsys, name); err == nil {
+			return l.name
+		}
+	}
+	return ""
+}
+
+// LoadImage decodes an image file.
+func (r *Resources) LoadImage(name string) (image.Image, error) {
+	f, err := r.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s (%s): %w", name, r.Origin(name), err)
+	}
+	return img, nil
+}
+
+// LoadTextureImage loads the image of a texture. If that fails the error
+// is returned together with a placeholder image, so a broken pack does
+// not stop the game.
+func (r *Resources) LoadTextureImage(name string) (image.Image, error) {
+	img, err := r.LoadImage(name)
+	if err != nil {
+		return placeholderImage(), err
+	}
+	return img, nil
+}
+
+// placeholderImage is a magenta checker board, easy to spot in the world.
+func placeholderImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			c := rl.Black
+			if (x/8+y/8)%2 == 0 {
+				c = rl.Magenta
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// LoadMesh reads an obj model.
+func (r *Resources) LoadMesh(name string) (*MeshData, error) {
+	f, err := r.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	mesh, err := ParseOBJ(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s (%s): %w", name, r.Origin(name), err)
+	}
+	if mesh.Triangles() == 0 {
+		return nil, fmt.Errorf("%s (%s): model has no faces", name, r.Origin(name))
+	}
+	return mesh, nil
+}
+
+// LoadShader loads a shader program, see LoadShaderProgram.
+func (r *Resources) LoadShader(vsPath, fsPath string) (*ShaderProgram, error) {
+	return LoadShaderProgram(r, vsPath, fsPath)
+}
+
+// LoadBlockDefinitions reads the block types and their textures.
+func (r *Resources) LoadBlockDefinitions() ([]BlockDefinition, error) {
+	data, err := fs.ReadFile(r, blockDefinitionsFile)
+	if err != nil {
+		return nil, err
+	}
+	defs, err := parseBlockDefinitions(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s (%s): %w", blockDefinitionsFile, r.Origin(blockDefinitionsFile), err)
+	}
+	return defs, nil
+}
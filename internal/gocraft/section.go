@@ -0,0 +1,279 @@
+package gocraft
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// chunkSectionHeight is the height of the slices chunks are culled in.
+const chunkSectionHeight = 16
+
+// the faces of a section, opposite faces differ in the lowest bit
+const (
+	faceWest  = iota // -x
+	faceEast         // +x
+	faceDown         // -y
+	faceUp           // +y
+	faceNorth        // -z
+	faceSouth        // +z
+	faceCount
+)
+
+func oppositeFace(face int) int {
+	return face ^ 1
+}
+
+// chunkSection is a horizontal slice of a chunk with its own instances,
+// so the parts of a chunk outside of the view are not drawn.
+type chunkSection struct {
+	chunk *Chunk
+	index int
+	// bounds covers the whole section, box only the blocks it draws
+	bounds AABB
+	box    AABB
+	// instances of the visible blocks by type
+	instances map[BlockType][]rl.Matrix
+	count     int
+	// connected has a bit for every pair of faces that air inside of
+	// the section connects, see connects.
+	connected uint64
+}
+
+// connects reports whether one can look into the section through face a
+// and out through face b.
+func (s *chunkSection) connects(a, b int) bool {
+	return s.connected&(1<<(a*faceCount+b)) != 0
+}
+
+func (s *chunkSection) render(r Renderer, block Mesh, stats *RenderStats) {
+	stats.Sections++
+	for typ, transforms := range s.instances {
+		stats.DrawCalls++
+		stats.Instances += len(transforms)
+		r.DrawMeshInstanced(block, blockMaterials[typ], transforms)
+	}
+}
+
+// buildSection collects the instances of the visible blocks of a
+// section and finds out which of its faces are connected through air.
+func (c *Chunk) buildSection(index int) {
+	s := c.sections[index]
+	s.instances = make(map[BlockType][]rl.Matrix)
+	s.count = 0
+
+	var (
+		minY = index * chunkSectionHeight
+		maxY = minInt(minY+chunkSectionHeight, len(c.blockList[0]))
+		half = rl.NewVector3(0.5, 0, 0.5)
+		top  = rl.NewVector3(0.5, 1, 0.5)
+	)
+	s.bounds = AABB{
+		Min: rl.Vector3Subtract(c.worldPosition(0, minY, 0), half),
+		Max: rl.Vector3Add(c.worldPosition(len(c.blockList)-1, maxY-1, len(c.blockList[0][0])-1), top),
+	}
+	for x := 0; x < len(c.blockList); x++ {
+		for y := minY; y < maxY; y++ {
+			for z := 0; z < len(c.blockList[x][y]); z++ {
+				b := c.blockList[x][y][z]
+				if b == nil || !b.enabled || b.carved {
+					continue
+				}
+				s.instances[b.blockType] = append(s.instances[b.blockType], rl.MatrixTranslate(
+					b.position.X,
+					b.position.Y,
+					b.position.Z,
+				))
+				box := blockAABB(blockCoord(b.position))
+				if s.count == 0 {
+					s.box = box
+				} else {
+					s.box = AABB{Min: rl.Vector3Min(s.box.Min, box.Min), Max: rl.Vector3Max(s.box.Max, box.Max)}
+				}
+				s.count++
+			}
+		}
+	}
+	s.connected = c.connectFaces(minY, maxY)
+}
+
+// connectFaces flood fills the air between minY and maxY and returns the
+// pairs of faces touched by the same air pocket.
+func (c *Chunk) connectFaces(minY, maxY int) uint64 {
+	var (
+		width     = len(c.blockList)
+		height    = maxY - minY
+		length    = len(c.blockList[0][0])
+		visited   = make([]bool, width*height*length)
+		stack     []int
+		connected uint64
+	)
+	// push visits a cell and queues it when it is air
+	push := func(x, y, z int) {
+		if x < 0 || y < 0 || z < 0 || x >= width || y >= height || z >= length {
+			return
+		}
+		i := (x*height+y)*length + z
+		if visited[i] {
+			return
+		}
+		visited[i] = true
+		if b := c.blockList[x][minY+y][z]; b == nil || b.carved {
+			stack = append(stack, i)
+		}
+	}
+	for start := range visited {
+		if visited[start] {
+			continue
+		}
+		push(start/(height*length), start/length%height, start%length)
+
+		var faces uint
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y, z := i/(height*length), i/length%height, i%length
+			for face, edge := range [faceCount]bool{x == 0, x == width-1, y == 0, y == height-1, z == 0, z == length-1} {
+				if edge {
+					faces |= 1 << face
+				}
+			}
+			push(x-1, y, z)
+			push(x+1, y, z)
+			push(x, y-1, z)
+			push(x, y+1, z)
+			push(x, y, z-1)
+			push(x, y, z+1)
+		}
+
+		for a := 0; a < faceCount; a++ {
+			for b := 0; b < faceCount; b++ {
+				if faces&(1<<a) != 0 && faces&(1<<b) != 0 {
+					connected |= 1 << (a*faceCount + b)
+				}
+			}
+		}
+	}
+	return connected
+}
+
+// section returns the section of the chunk at the given block height.
+func (c *Chunk) section(y int) *chunkSection {
+	i := y / chunkSectionHeight
+	if y < 0 || i >= len(c.sections) {
+		return nil
+	}
+	return c.sections[i]
+}
+
+// neighbourSection returns the section behind face of s, if its chunk
+// is in chunks.
+func (cm *ChunkManager) neighbourSection(s *chunkSection, face int, chunks map[*Chunk]bool) *chunkSection {
+	var (
+		chunk  = s.chunk
+		center = chunk.center
+	)
+	switch face {
+	case faceDown:
+		return chunk.section((s.index - 1) * chunkSectionHeight)
+	case faceUp:
+		return chunk.section((s.index + 1) * chunkSectionHeight)
+	// centers are stored negated, the chunk east of another has the
+	// smaller key
+	case faceWest:
+		center.X += cm.width
+	case faceEast:
+		center.X -= cm.width
+	case faceNorth:
+		center.Y += cm.length
+	case faceSouth:
+		center.Y -= cm.length
+	}
+	n, ok := cm.chunkMap[center]
+	if !ok || !chunks[n] || s.index >= len(n.sections) {
+		return nil
+	}
+	return n.sections[s.index]
+}
+
+// cullSections returns the sections of chunks that have to be drawn for
+// the camera. Sections outside of the frustum are skipped. With caves
+// set, sections that can not be seen through air from the section of the
+// camera are skipped too, like caves below the surface.
+func (cm *ChunkManager) cullSections(chunks []*Chunk, eye rl.Vector3, frustum Frustum, caves bool, stats *RenderStats) []*chunkSection {
+	var reached map[*chunkSection]bool
+	if caves {
+		reached = cm.reachableSections(chunks, eye, frustum)
+	}
+
+	var visible []*chunkSection
+	for _, chunk := range chunks {
+		drawn := false
+		for _, s := range chunk.sections {
+			switch {
+			case s.count == 0:
+			case !frustum.IntersectsBox(s.box):
+				stats.FrustumCulled++
+			case reached != nil && !reached[s]:
+				stats.CaveCulled++
+			default:
+				visible = append(visible, s)
+				drawn = true
+			}
+		}
+		if drawn {
+			stats.Chunks++
+		}
+	}
+	return visible
+}
+
+// reachableSections walks from the section of the camera to the sections
+// the view can reach through connected faces, never turning back along an
+// axis it already went. It returns nil if the camera is in no section of
+// chunks, then nothing can be culled that way.
+func (cm *ChunkManager) reachableSections(chunks []*Chunk, eye rl.Vector3, frustum Frustum) map[*chunkSection]bool {
+	loaded := make(map[*Chunk]bool, len(chunks))
+	for _, chunk := range chunks {
+		loaded[chunk] = true
+	}
+	x, y, z := blockCoord(eye)
+	chunk, ok := cm.chunkMap[cm.chunkCenter(x, z)]
+	if !ok || !loaded[chunk] {
+		return nil
+	}
+	start := chunk.section(y)
+	if start == nil {
+		return nil
+	}
+
+	type step struct {
+		section *chunkSection
+		// from is the face the section was entered through, -1 for the
+		// section of the camera
+		from int
+		// dirs are the faces left through on the way here
+		dirs uint
+	}
+	var (
+		reached = map[*chunkSection]bool{start: true}
+		queue   = []step{{section: start, from: -1}}
+	)
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for face := 0; face < faceCount; face++ {
+			if cur.dirs&(1<<oppositeFace(face)) != 0 {
+				continue
+			}
+			if cur.from >= 0 && !cur.section.connects(cur.from, face) {
+				continue
+			}
+			n := cm.neighbourSection(cur.section, face, loaded)
+			if n == nil || reached[n] || !frustum.IntersectsBox(n.bounds) {
+				continue
+			}
+			reached[n] = true
+			queue = append(queue, step{section: n, from: oppositeFace(face), dirs: cur.dirs | 1<<face})
+		}
+	}
+	return reached
+}
@@ -0,0 +1,131 @@
+package gocraft
+
+import "testing"
+
+var faceNames = [faceCount]string{"west", "east", "down", "up", "north", "south"}
+
+// solidChunk is a chunk of one section, completely filled with dirt.
+func solidChunk() *Chunk {
+	c := NewChunk(chunkSectionHeight, chunkSectionHeight, chunkSectionHeight)
+	for x := range c.blockList {
+		for y := range c.blockList[x] {
+			for z := range c.blockList[x][y] {
+				c.blockList[x][y][z] = &Block{blockType: BlockTypeDirt}
+			}
+		}
+	}
+	return c
+}
+
+func TestConnectFaces(t *testing.T) {
+	const n = chunkSectionHeight
+	tests := []struct {
+		name string
+		// dig turns blocks of a solid chunk into air
+		dig func(c *Chunk)
+		// pairs are the faces connected through air, besides every face
+		// with air being connected to itself
+		pairs [][2]int
+		air   []int
+	}{
+		{
+			name: "solid",
+			dig:  func(c *Chunk) {},
+		},
+		{
+			name: "empty",
+			dig: func(c *Chunk) {
+				*c = *NewChunk(n, n, n)
+			},
+			pairs: func() [][2]int {
+				var all [][2]int
+				for a := 0; a < faceCount; a++ {
+					for b := 0; b < faceCount; b++ {
+						all = append(all, [2]int{a, b})
+					}
+				}
+				return all
+			}(),
+			air: []int{faceWest, faceEast, faceDown, faceUp, faceNorth, faceSouth},
+		},
+		{
+			name: "tunnel from west to east",
+			dig: func(c *Chunk) {
+				for x := 0; x < n; x++ {
+					c.blockList[x][8][8] = nil
+				}
+			},
+			pairs: [][2]int{{faceWest, faceEast}},
+			air:   []int{faceWest, faceEast},
+		},
+		{
+			name: "carved shaft from down to up",
+			dig: func(c *Chunk) {
+				for y := 0; y < n; y++ {
+					c.blockList[8][y][8].carved = true
+				}
+			},
+			pairs: [][2]int{{faceDown, faceUp}},
+			air:   []int{faceDown, faceUp},
+		},
+		{
+			name: "two dead ends",
+			dig: func(c *Chunk) {
+				for x := 0; x < 6; x++ {
+					c.blockList[x][8][8] = nil
+					c.blockList[n-1-x][8][8] = nil
+				}
+			},
+			air: []int{faceWest, faceEast},
+		},
+		{
+			name: "cave inside",
+			dig: func(c *Chunk) {
+				for x := 4; x < 12; x++ {
+					c.blockList[x][8][8] = nil
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := solidChunk()
+			tt.dig(c)
+			s := &chunkSection{connected: c.connectFaces(0, n)}
+
+			want := map[[2]int]bool{}
+			for _, p := range tt.pairs {
+				want[p] = true
+				want[[2]int{p[1], p[0]}] = true
+			}
+			for _, face := range tt.air {
+				want[[2]int{face, face}] = true
+			}
+			for a := 0; a < faceCount; a++ {
+				for b := 0; b < faceCount; b++ {
+					if got := s.connects(a, b); got != want[[2]int{a, b}] {
+						t.Errorf("%s to %s connected %v, want %v", faceNames[a], faceNames[b], got, !got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestConnectFacesOfUpperSection(t *testing.T) {
+	c := NewChunk(chunkSectionHeight, 2*chunkSectionHeight, chunkSectionHeight)
+	// the lower section is solid, the upper one empty
+	for x := range c.blockList {
+		for y := 0; y < chunkSectionHeight; y++ {
+			for z := range c.blockList[x][y] {
+				c.blockList[x][y][z] = &Block{blockType: BlockTypeDirt}
+			}
+		}
+	}
+	if got := c.connectFaces(0, chunkSectionHeight); got != 0 {
+		t.Errorf("solid lower section connects %b", got)
+	}
+	if got, want := c.connectFaces(chunkSectionHeight, 2*chunkSectionHeight), uint64(1)<<(faceCount*faceCount)-1; got != want {
+		t.Errorf("empty upper section connects %b, want %b", got, want)
+	}
+}
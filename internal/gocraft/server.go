@@ -0,0 +1,569 @@
+package gocraft
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	ErrServerFull          = errors.New("server is full")
+	ErrInvalidServerConfig = errors.New("invalid server config")
+	ErrInvalidName         = errors.New("names may only contain letters, digits, - and _")
+	ErrNameTaken           = errors.New("a player of that name is already connected")
+	ErrShuttingDown        = errors.New("server is shutting down")
+)
+
+// ServerConfig is read from the json file given to the server command.
+type ServerConfig struct {
+	Address string `json:"address"`
+	Seed    int    `json:"seed"`
+	// WorldDir is where the world is saved, empty keeps it in memory only.
+	WorldDir     string `json:"world_dir"`
+	MaxPlayers   int    `json:"max_players"`
+	ViewDistance int    `json:"view_distance"`
+	TickRate     int    `json:"tick_rate"`
+	// ChunkSize and Terrain are used for new worlds, saved worlds keep
+	// the ones they were created with.
+	ChunkSize   float32       `json:"chunk_size"`
+	Terrain     TerrainConfig `json:"terrain"`
+	AllowFlight bool          `json:"allow_flight"`
+	// GameMode is survival or creative for every player.
+	GameMode string `json:"game_mode"`
+	// Autosave is the interval between saves in seconds.
+	Autosave int `json:"autosave"`
+	// ResourcePacks are layered over the embedded resources, the first
+	// one wins. The server only reads the recipes from them.
+	ResourcePacks []string `json:"resource_packs"`
+}
+
+func DefaultServerConfig() ServerConfig {
+	return ServerConfig{
+		Address:      ":25565",
+		Seed:         DefaultSeed,
+		WorldDir:     "world",
+		MaxPlayers:   8,
+		ViewDistance: DefaultViewDistance,
+		TickRate:     DefaultTickRate,
+		ChunkSize:    DefaultChunkSize,
+		Terrain:      DefaultTerrain(),
+		AllowFlight:  true,
+		GameMode:     GameModeSurvival.String(),
+		Autosave:     300,
+	}
+}
+
+// LoadServerConfig reads the config file over the defaults. A missing
+// file leaves the defaults untouched.
+func LoadServerConfig(path string) (ServerConfig, error) {
+	config := DefaultServerConfig()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, config.Validate()
+	}
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, config.Validate()
+}
+
+func (c ServerConfig) Validate() error {
+	switch {
+	case c.Address == "":
+		return fmt.Errorf("%w: address is empty", ErrInvalidServerConfig)
+	case c.MaxPlayers < 1:
+		return fmt.Errorf("%w: max_players must be at least 1", ErrInvalidServerConfig)
+	case c.ViewDistance < 0:
+		return fmt.Errorf("%w: view_distance must not be negative", ErrInvalidServerConfig)
+	case c.TickRate < 1:
+		return fmt.Errorf("%w: tick_rate must be at least 1", ErrInvalidServerConfig)
+	case !validGameMode(c.GameMode):
+		return fmt.Errorf("%w: game_mode must be survival or creative", ErrInvalidServerConfig)
+	case c.Autosave < 1:
+		return fmt.Errorf("%w: autosave must be at least 1 second", ErrInvalidServerConfig)
+	case !validChunkSize(c.ChunkSize):
+		return fmt.Errorf("%w: chunk_size must be a whole number from %d to %d", ErrInvalidServerConfig, minChunkSize, maxChunkSize)
+	}
+	if err := c.Terrain.Validate(c.ChunkSize); err != nil {
+		return fmt.Errorf("%w: terrain: %s", ErrInvalidServerConfig, err.Error())
+	}
+	return nil
+}
+
+func validGameMode(s string) bool {
+	_, ok := ParseGameMode(s)
+	return ok
+}
+
+const (
+	keepAliveInterval = 5 * time.Second
+	// clients that send nothing for this long are dropped, they have to
+	// answer keep alives so this only hits dead connections
+	clientTimeout    = 3 * keepAliveInterval
+	handshakeTimeout = 10 * time.Second
+	clientQueueSize  = 256
+	// chunks are large, each client gets at most this many per tick
+	chunksPerTick = 1
+)
+
+// Server runs a world without a window and lets clients connect over tcp.
+type Server struct {
+	config  ServerConfig
+	world   *World
+	ticker  *Ticker
+	recipes *RecipeBook
+
+	// mu guards the world and the clients, it is shared by the tick loop
+	// and the connections
+	mu            sync.Mutex
+	listener      net.Listener
+	clients       map[uint32]*serverClient
+	nextID        uint32
+	lastKeepAlive time.Time
+	// closing is set by Shutdown, no one may join after it
+	closing bool
+	wg      sync.WaitGroup
+}
+
+// serverClient is the server side of a connected player.
+type serverClient struct {
+	id         uint32
+	name       string
+	conn       net.Conn
+	player     *Player
+	yaw, pitch float32
+	sentChunks map[rl.Vector2]bool
+
+	// inputs wait here sorted by sequence until the tick applies them
+	inputs       []*PlayerInputPacket
+	inputBudget  int
+	lastSequence uint32
+
+	out       chan Packet
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// send queues a packet. A client that can not keep up is disconnected
+// instead of blocking the tick loop.
+func (c *serverClient) send(p Packet) {
+	select {
+	case c.out <- p:
+	case <-c.done:
+	default:
+		rl.TraceLog(rl.LogWarning, "SERVER: %s is too slow, disconnecting", c.name)
+		c.close()
+	}
+}
+
+func (c *serverClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+func (c *serverClient) writeLoop(pc packetLink) {
+	for {
+		select {
+		case p := <-c.out:
+			if err := pc.Write(p); err != nil {
+				c.close()
+				return
+			}
+			if _, ok := p.(*DisconnectPacket); ok {
+				c.close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func NewServer(config ServerConfig) (*Server, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	var world *World
+	if config.WorldDir == "" {
+		world = NewWorld(config.ChunkSize, config.Seed, nil, config.Terrain)
+	} else {
+		store, err := NewWorldStore(config.WorldDir)
+		if err != nil {
+			return nil, err
+		}
+		fresh := WorldMeta{Seed: config.Seed, ChunkSize: config.ChunkSize, Terrain: &config.Terrain}
+		if world, err = OpenWorld(store, fresh); err != nil {
+			return nil, err
+		}
+	}
+	world.ViewDistance = config.ViewDistance
+
+	resources, err := NewResources(config.ResourcePacks)
+	if err != nil {
+		return nil, err
+	}
+	defer resources.Close()
+	recipes, err := LoadRecipes(resources, "recipes")
+	if err != nil {
+		rl.TraceLog(rl.LogWarning, "recipes: %s", err.Error())
+		recipes = &RecipeBook{}
+	}
+
+	return &Server{
+		config:        config,
+		world:         world,
+		recipes:       recipes,
+		ticker:        NewTicker(config.TickRate),
+		clients:       make(map[uint32]*serverClient),
+		lastKeepAlive: time.Now(),
+	}, nil
+}
+
+// Run listens for clients and ticks the world until ctx is done, then
+// the world is saved a last time.
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.config.Address)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	rl.TraceLog(rl.LogInfo, "SERVER: listening on %s", listener.Addr())
+
+	s.wg.Add(1)
+	go s.acceptLoop()
+
+	err = s.tickLoop(ctx)
+
+	listener.Close()
+	s.Shutdown("server stopped")
+
+	if saveErr := s.save(); err == nil {
+		err = saveErr
+	}
+	return err
+}
+
+// Shutdown disconnects all clients and waits for their connections to
+// end. Connections still in the handshake are refused.
+func (s *Server) Shutdown(reason string) {
+	s.mu.Lock()
+	s.closing = true
+	for _, c := range s.clients {
+		c.send(&DisconnectPacket{Reason: reason})
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Addr is the address the server listens on, nil before Run.
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+func (s *Server) tickLoop(ctx context.Context) error {
+	var (
+		interval = time.Second / time.Duration(s.ticker.Rate())
+		ticks    = time.NewTicker(interval)
+		autosave = time.NewTicker(time.Duration(s.config.Autosave) * time.Second)
+		last     = time.Now()
+	)
+	defer ticks.Stop()
+	defer autosave.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticks.C:
+			for n := s.ticker.Advance(float32(now.Sub(last).Seconds())); n > 0; n-- {
+				s.Tick()
+			}
+			last = now
+		case <-autosave.C:
+			if err := s.save(); err != nil {
+				rl.TraceLog(rl.LogError, "SERVER: autosave failed: %s", err.Error())
+			}
+		}
+	}
+}
+
+// Tick advances the world by one tick and sends the changes to the clients.
+func (s *Server) Tick() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dt := s.ticker.Step()
+	s.world.Tick(dt)
+	for _, c := range s.clients {
+		s.simulate(c, dt)
+		if c.player.Inventory.takeChanged() {
+			c.send(&InventoryPacket{
+				GameMode: c.player.GameMode,
+				Slots:    append([]ItemStack(nil), c.player.Inventory.Slots[:]...),
+			})
+		}
+	}
+	for _, c := range s.clients {
+		s.streamChunks(c)
+		for _, other := range s.clients {
+			if other == c {
+				continue
+			}
+			c.send(&PlayerMovePacket{
+				PlayerID: other.id,
+				Position: other.player.Position,
+				Yaw:      other.yaw,
+				Pitch:    other.pitch,
+			})
+		}
+	}
+
+	if time.Since(s.lastKeepAlive) >= keepAliveInterval {
+		s.lastKeepAlive = time.Now()
+		for _, c := range s.clients {
+			c.send(&KeepAlivePacket{Nonce: s.world.Ticks})
+		}
+	}
+}
+
+// streamChunks sends the closest chunks around the client it does not have yet.
+func (s *Server) streamChunks(c *serverClient) {
+	sent := 0
+	for _, chunk := range s.world.Chunks.LoadAround(c.player.Position, s.world.ViewDistance) {
+		if sent >= chunksPerTick {
+			return
+		}
+		if c.sentChunks[chunk.center] {
+			continue
+		}
+		data, err := chunk.encodeBlocks()
+		if err != nil {
+			rl.TraceLog(rl.LogError, "SERVER: chunk %v: %s", chunk.center, err.Error())
+			return
+		}
+		c.send(&ChunkDataPacket{
+			X:      int32(chunk.center.X),
+			Z:      int32(chunk.center.Y),
+			Size:   uint16(len(chunk.blockList)),
+			Height: uint16(len(chunk.blockList[0])),
+			Data:   data,
+		})
+		c.sentChunks[chunk.center] = true
+		sent++
+	}
+}
+
+func (s *Server) broadcast(p Packet) {
+	for _, c := range s.clients {
+		c.send(p)
+	}
+}
+
+func (s *Server) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.config.WorldDir == "" {
+		return nil
+	}
+	if err := s.world.Save(); err != nil {
+		return err
+	}
+	for _, c := range s.clients {
+		if err := s.world.SavePlayer(c.name, c.player); err != nil {
+			return err
+		}
+	}
+	rl.TraceLog(rl.LogInfo, "SERVER: saved world to %s", s.config.WorldDir)
+	return nil
+}
+
+func (s *Server) acceptLoop() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.ServeConn(conn)
+	}
+}
+
+// ServeConn handles a client connection in the background. It is used
+// for accepted tcp connections as well as in-process pipes.
+func (s *Server) ServeConn(conn net.Conn) {
+	s.serve(conn, newPacketConn(conn))
+}
+
+func (s *Server) serve(conn net.Conn, link packetLink) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.handleConn(conn, link)
+	}()
+}
+
+// join spawns a player for the connection unless the server is full.
+func (s *Server) join(conn net.Conn, name string) (*serverClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closing {
+		return nil, ErrShuttingDown
+	}
+	if len(s.clients) >= s.config.MaxPlayers {
+		return nil, ErrServerFull
+	}
+	// the name is the file the player is saved in, two names must not end
+	// up in the same file
+	if safeFileName(name) != name {
+		return nil, ErrInvalidName
+	}
+	for _, other := range s.clients {
+		// not every file system tells upper and lower case apart
+		if strings.EqualFold(other.name, name) {
+			return nil, ErrNameTaken
+		}
+	}
+
+	s.nextID++
+	c := &serverClient{
+		id:         s.nextID,
+		name:       name,
+		conn:       conn,
+		player:     s.world.SpawnSaved(name),
+		sentChunks: make(map[rl.Vector2]bool),
+		out:        make(chan Packet, clientQueueSize),
+		done:       make(chan struct{}),
+	}
+	c.player.Remote = true
+	mode, _ := ParseGameMode(s.config.GameMode)
+	c.player.SetGameMode(mode)
+
+	c.send(&LoginPacket{
+		PlayerID:     c.id,
+		Seed:         int32(s.world.Chunks.Seed()),
+		ChunkSize:    uint16(s.world.Chunks.Size()),
+		TickRate:     uint16(s.ticker.Rate()),
+		ViewDistance: uint8(s.world.ViewDistance),
+		Position:     c.player.Position,
+		Terrain:      s.world.Chunks.Terrain(),
+	})
+	for _, other := range s.clients {
+		c.send(&PlayerJoinPacket{PlayerID: other.id, Name: other.name})
+		other.send(&PlayerJoinPacket{PlayerID: c.id, Name: c.name})
+	}
+	s.clients[c.id] = c
+	return c, nil
+}
+
+func (s *Server) leave(c *serverClient) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.world.SavePlayer(c.name, c.player); err != nil {
+		rl.TraceLog(rl.LogError, "SERVER: saving %s failed: %s", c.name, err.Error())
+	}
+	s.world.Despawn(c.player)
+	delete(s.clients, c.id)
+	s.broadcast(&PlayerLeavePacket{PlayerID: c.id})
+}
+
+func (s *Server) handleConn(conn net.Conn, pc packetLink) {
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	p, err := pc.Read()
+	if err != nil {
+		rl.TraceLog(rl.LogInfo, "SERVER: handshake with %s failed: %s", conn.RemoteAddr(), err.Error())
+		return
+	}
+	hello, ok := p.(*HandshakePacket)
+	if !ok {
+		return
+	}
+	if hello.Version != ProtocolVersion {
+		pc.Write(&DisconnectPacket{Reason: fmt.Sprintf(
+			"%s: server %d, client %d", ErrVersionMismatch, ProtocolVersion, hello.Version,
+		)})
+		return
+	}
+
+	c, err := s.join(conn, hello.Name)
+	if err != nil {
+		pc.Write(&DisconnectPacket{Reason: err.Error()})
+		rl.TraceLog(rl.LogInfo, "SERVER: rejected %s: %s", hello.Name, err.Error())
+		return
+	}
+	defer s.leave(c)
+	defer c.close()
+	go c.writeLoop(pc)
+
+	rl.TraceLog(rl.LogInfo, "SERVER: %s joined from %s", c.name, conn.RemoteAddr())
+	for {
+		conn.SetReadDeadline(time.Now().Add(clientTimeout))
+		p, err := pc.Read()
+		if err != nil {
+			break
+		}
+		if _, ok := p.(*DisconnectPacket); ok {
+			break
+		}
+		s.handlePacket(c, p)
+	}
+	rl.TraceLog(rl.LogInfo, "SERVER: %s left", c.name)
+}
+
+func (s *Server) handlePacket(c *serverClient, p Packet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch p := p.(type) {
+	case *PlayerInputPacket:
+		c.queueInput(p)
+	case *BlockActionPacket:
+		s.applyBlockAction(c, p)
+	case *CraftPacket:
+		s.applyCraft(c, p)
+	case *ChatPacket:
+		s.broadcast(&ChatPacket{Sender: c.name, Message: p.Message})
+	}
+}
+
+// RunServer is the action of the server command.
+func RunServer(ctx *cli.Context) error {
+	rl.SetTraceLog(loglevelFromString(ctx.String("loglevel")))
+
+	config, err := LoadServerConfig(ctx.String("config"))
+	if err != nil {
+		return err
+	}
+	server, err := NewServer(config)
+	if err != nil {
+		return err
+	}
+
+	sigCtx, stop := signal.NotifyContext(ctx.Context, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return server.Run(sigCtx)
+}
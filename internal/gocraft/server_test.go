@@ -0,0 +1,118 @@
+package gocraft
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+// testServerConfig keeps the world in memory with small chunks.
+func testServerConfig() ServerConfig {
+	config := DefaultServerConfig()
+	config.WorldDir = ""
+	config.Seed = 7
+	config.ChunkSize = testChunkSize
+	config.Terrain = testTerrain()
+	config.ViewDistance = 0
+	return config
+}
+
+func TestServerConfigValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		edit  func(c *ServerConfig)
+		valid bool
+	}{
+		{"defaults", func(c *ServerConfig) {}, true},
+		{"small chunks", func(c *ServerConfig) { c.ChunkSize = 32; c.Terrain.Height = 32 }, true},
+		{"chunks too small", func(c *ServerConfig) { c.ChunkSize = 8 }, false},
+		{"chunk size not whole", func(c *ServerConfig) { c.ChunkSize = 32.5 }, false},
+		{"terrain higher than chunks", func(c *ServerConfig) { c.ChunkSize = 32; c.Terrain.Height = 48 }, false},
+		{"no terrain frequency", func(c *ServerConfig) { c.Terrain.Frequency = 0 }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultServerConfig()
+			tt.edit(&config)
+			err := config.Validate()
+			if valid := err == nil; valid != tt.valid {
+				t.Fatalf("valid %v, want %v: %v", valid, tt.valid, err)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidServerConfig) {
+				t.Errorf("error %v is not ErrInvalidServerConfig", err)
+			}
+		})
+	}
+}
+
+func TestServerUsesConfiguredWorld(t *testing.T) {
+	config := testServerConfig()
+	s, err := NewServer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.world.Chunks.Size(); got != config.ChunkSize {
+		t.Errorf("chunk size %.0f, want %.0f", got, config.ChunkSize)
+	}
+	if got := s.world.Chunks.Terrain(); got != config.Terrain {
+		t.Errorf("terrain %+v, want %+v", got, config.Terrain)
+	}
+}
+
+func TestServerJoinNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		want  error
+	}{
+		{"different names", []string{"alice", "bob"}, nil},
+		{"same name", []string{"alice", "alice"}, ErrNameTaken},
+		{"same name in other case", []string{"alice", "Alice"}, ErrNameTaken},
+		{"dot", []string{"a.b"}, ErrInvalidName},
+		{"dot next to its file name", []string{"a_b", "a.b"}, ErrInvalidName},
+		{"path", []string{"../alice"}, ErrInvalidName},
+		{"empty", []string{""}, ErrInvalidName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewServer(testServerConfig())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Shutdown("done")
+
+			for i, name := range tt.names {
+				conn, _ := net.Pipe()
+				defer conn.Close()
+				_, err = s.join(conn, name)
+				if i < len(tt.names)-1 && err != nil {
+					t.Fatalf("%q was rejected: %v", name, err)
+				}
+			}
+			if err != tt.want {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+			if want := len(tt.names); tt.want != nil && len(s.clients) != want-1 {
+				t.Errorf("%d clients joined, want %d", len(s.clients), want-1)
+			}
+		})
+	}
+}
+
+func TestServerJoinAfterShutdown(t *testing.T) {
+	s, err := NewServer(testServerConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Shutdown("done")
+
+	// a connection that finished its handshake only now
+	conn, _ := net.Pipe()
+	defer conn.Close()
+	if _, err := s.join(conn, "late"); err != ErrShuttingDown {
+		t.Errorf("got %v, want %v", err, ErrShuttingDown)
+	}
+	if len(s.clients) != 0 {
+		t.Errorf("%d clients joined", len(s.clients))
+	}
+}
@@ -0,0 +1,136 @@
+package gocraft
+
+import (
+	"fmt"
+	"reflect"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const (
+	minRenderDistance = 1
+	maxRenderDistance = 4
+	// maxFarDistance keeps the far terrain inside of the far clip plane
+	maxFarDistance = 7
+	minFOV         = 30
+	maxFOV         = 110
+	minSensitivity = 0.05
+	maxSensitivity = 1.5
+	// maxSmoothing is the longest the view may lag behind the mouse
+	maxSmoothing = 0.3
+	// maxFPSCap is the highest frame cap, 0 means unlimited
+	maxFPSCap = 240
+)
+
+// Settings are the options of the settings screen. They are stored in
+// the config file, key bindings on their own, see BindingsPath.
+type Settings struct {
+	RenderDistance int     `json:"render_distance"`
+	FOV            float32 `json:"fov"`
+	Sensitivity    float32 `json:"sensitivity"`
+	// Smoothing is the time in seconds the view needs to catch up with
+	// the mouse, 0 turns it off.
+	Smoothing float32 `json:"smoothing"`
+	VSync     bool    `json:"vsync"`
+	// MaxFPS caps the frame rate, 0 is unlimited.
+	MaxFPS int `json:"max_fps"`
+	// CaveCulling skips chunk sections that can not be seen through air.
+	CaveCulling bool `json:"cave_culling"`
+	// FarDistance is how many chunks far the terrain is drawn without
+	// detail, 0 turns it off.
+	FarDistance int `json:"far_distance"`
+	// Minimap shows the map around the player, MinimapRotate turns it
+	// with the player instead of keeping north up.
+	Minimap       bool `json:"minimap"`
+	MinimapRotate bool `json:"minimap_rotate"`
+}
+
+func DefaultSettings() Settings {
+	return Settings{
+		RenderDistance: DefaultViewDistance,
+		FOV:            60,
+		Sensitivity:    0.3,
+		MaxFPS:         60,
+		CaveCulling:    true,
+		FarDistance:    4,
+		Minimap:        true,
+		MinimapRotate:  true,
+	}
+}
+
+// Clamp moves every value into its valid range.
+func (s *Settings) Clamp() {
+	s.RenderDistance = int(clamp32(float32(s.RenderDistance), minRenderDistance, maxRenderDistance))
+	s.FOV = clamp32(s.FOV, minFOV, maxFOV)
+	s.Sensitivity = clamp32(s.Sensitivity, minSensitivity, maxSensitivity)
+	s.Smoothing = clamp32(s.Smoothing, 0, maxSmoothing)
+	s.MaxFPS = int(clamp32(float32(s.MaxFPS), 0, maxFPSCap))
+	s.FarDistance = int(clamp32(float32(s.FarDistance), 0, maxFarDistance))
+}
+
+// Validate reports values out of range, the settings screen can not
+// produce them but the config file can.
+func (s Settings) Validate() error {
+	switch {
+	case s.RenderDistance < minRenderDistance || s.RenderDistance > maxRenderDistance:
+		return fmt.Errorf("render_distance must be %d to %d", minRenderDistance, maxRenderDistance)
+	case s.FOV < minFOV || s.FOV > maxFOV:
+		return fmt.Errorf("fov must be %d to %d", minFOV, maxFOV)
+	case s.Sensitivity < minSensitivity || s.Sensitivity > maxSensitivity:
+		return fmt.Errorf("sensitivity must be %.2f to %.2f", minSensitivity, maxSensitivity)
+	case s.Smoothing < 0 || s.Smoothing > maxSmoothing:
+		return fmt.Errorf("smoothing must be 0 (off) to %.2f seconds", maxSmoothing)
+	case s.MaxFPS < 0 || s.MaxFPS > maxFPSCap:
+		return fmt.Errorf("max_fps must be 0 (unlimited) to %d", maxFPSCap)
+	case s.FarDistance < 0 || s.FarDistance > maxFarDistance:
+		return fmt.Errorf("far_distance must be 0 (off) to %d", maxFarDistance)
+	}
+	return nil
+}
+
+// mergeSettings returns file with every setting that differs between
+// before and after taken from after.
+func mergeSettings(file, before, after Settings) Settings {
+	var (
+		f = reflect.ValueOf(&file).Elem()
+		b = reflect.ValueOf(before)
+		a = reflect.ValueOf(after)
+	)
+	for i := 0; i < f.NumField(); i++ {
+		if b.Field(i).Interface() != a.Field(i).Interface() {
+			f.Field(i).Set(a.Field(i))
+		}
+	}
+	return file
+}
+
+// applySettings hands the settings to the window, camera and world.
+func applySettings(s *engine) {
+	s.camera.FOV = s.settings.FOV
+	s.camera.Sensitivity = s.settings.Sensitivity
+	s.camera.Smoothing = s.settings.Smoothing
+	if s.world != nil && s.client == nil {
+		s.world.ViewDistance = s.settings.RenderDistance
+	}
+	if s.settings.VSync {
+		rl.SetWindowState(rl.FlagVsyncHint)
+	} else {
+		rl.ClearWindowState(rl.FlagVsyncHint)
+	}
+	rl.SetTargetFPS(int32(s.settings.MaxFPS))
+}
+
+// saveSettings writes the settings changed in the game to the config
+// file and the key bindings to their own file.
+func saveSettings(s *engine) {
+	if err := SaveConfigSettings(s.configPath, s.savedSettings, s.settings); err != nil {
+		rl.TraceLog(rl.LogWarning, "settings: %s", err.Error())
+	} else {
+		s.savedSettings = s.settings
+	}
+	if path, err := BindingsPath(); err == nil {
+		if err := s.input.SaveBindings(path); err != nil {
+			rl.TraceLog(rl.LogWarning, "bindings: %s", err.Error())
+		}
+	}
+}
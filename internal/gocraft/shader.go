@@ -0,0 +1,245 @@
+package gocraft
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"strings"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+var ErrShaderCompile = errors.New("shader does not compile")
+
+// shaderCheckInterval is how often the files of a shader are checked for
+// changes, see ShaderProgram.ReloadIfChanged.
+const shaderCheckInterval = 500 * time.Millisecond
+
+// ShaderProgram is a shader loaded from a vertex and a fragment shader
+// file. Uniforms are set by name, their locations are looked up once.
+// The sources may include other files with
+//
+//	#include "fog.glsl"
+//
+// relative to the including file. The program reloads itself when one of
+// its files changes and keeps the old program if the new one does not
+// compile.
+type ShaderProgram struct {
+	Shader rl.Shader
+	// OnLoad is called after the program was reloaded, for setting up
+	// what is not a uniform value like the locations raylib uses.
+	OnLoad func(p *ShaderProgram)
+
+	fsys           fs.FS
+	vsPath, fsPath string
+	locations      map[string]int32
+	// values sets the uniforms again after a reload
+	values map[string]func()
+	// files are the sources with includes and when they were changed
+	files   map[string]time.Time
+	checked time.Time
+}
+
+// LoadShaderProgram compiles the shader from the files in fsys.
+func LoadShaderProgram(fsys fs.FS, vsPath, fsPath string) (*ShaderProgram, error) {
+	p := &ShaderProgram{
+		fsys:   fsys,
+		vsPath: vsPath,
+		fsPath: fsPath,
+		values: make(map[string]func()),
+	}
+	shader, files, err := p.compile()
+	if err != nil {
+		return nil, err
+	}
+	p.Shader = shader
+	p.files = files
+	p.locations = make(map[string]int32)
+	p.checked = time.Now()
+	return p, nil
+}
+
+// compile preprocesses and compiles both sources.
+func (p *ShaderProgram) compile() (rl.Shader, map[string]time.Time, error) {
+	files := make(map[string]time.Time)
+	vsCode, err := preprocessShader(p.fsys, p.vsPath, files, nil)
+	if err != nil {
+		return rl.Shader{}, nil, err
+	}
+	fsCode, err := preprocessShader(p.fsys, p.fsPath, files, nil)
+	if err != nil {
+		return rl.Shader{}, nil, err
+	}
+	shader := rl.LoadShaderFromMemory(vsCode, fsCode)
+	// raylib falls back to its default shader, details are in its log
+	if shader.ID == rl.GetShaderIdDefault() {
+		return rl.Shader{}, nil, fmt.Errorf("%s, %s: %w", p.vsPath, p.fsPath, ErrShaderCompile)
+	}
+	return shader, files, nil
+}
+
+// preprocessShader returns the source of name with its includes resolved.
+// The files read are added to files, stack holds the including files.
+func preprocessShader(fsys fs.FS, name string, files map[string]time.Time, stack []string) (string, error) {
+	for _, s := range stack {
+		if s == name {
+			return "", fmt.Errorf("include cycle %s", strings.Join(append(stack, name), " -> "))
+		}
+	}
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return "", err
+	}
+	files[name] = modTime(fsys, name)
+
+	var (
+		out     strings.Builder
+		scanner = bufio.NewScanner(strings.NewReader(string(data)))
+		line    = 0
+	)
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+		if !strings.HasPrefix(trimmed, "#include") {
+			out.WriteString(text)
+			out.WriteByte('\n')
+			continue
+		}
+		arg := strings.TrimSpace(strings.TrimPrefix(trimmed, "#include"))
+		if len(arg) < 2 || arg[0] != '"' || arg[len(arg)-1] != '"' {
+			return "", fmt.Errorf("%s:%d: include needs a quoted file name", name, line)
+		}
+		inc, err := preprocessShader(fsys, path.Join(path.Dir(name), arg[1:len(arg)-1]), files, append(stack, name))
+		if err != nil {
+			return "", fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+		out.WriteString(inc)
+	}
+	return out.String(), nil
+}
+
+// modTime is the time the file was changed, zero if it is unknown like
+// for embedded files.
+func modTime(fsys fs.FS, name string) time.Time {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// Reload compiles the program again. If that fails the old program stays
+// in use and the error is returned.
+func (p *ShaderProgram) Reload() error {
+	shader, files, err := p.compile()
+	if err != nil {
+		// watch the files anyway, so the next save is tried again
+		for name := range p.files {
+			p.files[name] = modTime(p.fsys, name)
+		}
+		return err
+	}
+	rl.UnloadShader(p.Shader)
+	p.Shader = shader
+	p.files = files
+	p.locations = make(map[string]int32)
+	for _, set := range p.values {
+		set()
+	}
+	if p.OnLoad != nil {
+		p.OnLoad(p)
+	}
+	return nil
+}
+
+// ReloadIfChanged reloads the program when one of its files changed. It
+// is cheap to call every frame, the files are only checked now and then.
+func (p *ShaderProgram) ReloadIfChanged() error {
+	if time.Since(p.checked) < shaderCheckInterval {
+		return nil
+	}
+	p.checked = time.Now()
+	for name, t := range p.files {
+		if !modTime(p.fsys, name).Equal(t) {
+			return p.Reload()
+		}
+	}
+	return nil
+}
+
+// Unload frees the program.
+func (p *ShaderProgram) Unload() {
+	rl.UnloadShader(p.Shader)
+}
+
+// Location returns the location of the uniform, -1 if the program has
+// none of that name.
+func (p *ShaderProgram) Location(name string) int32 {
+	loc, ok := p.locations[name]
+	if !ok {
+		loc = rl.GetShaderLocation(p.Shader, name)
+		p.locations[name] = loc
+	}
+	return loc
+}
+
+// AttribLocation returns the location of the vertex attribute.
+func (p *ShaderProgram) AttribLocation(name string) int32 {
+	return rl.GetShaderLocationAttrib(p.Shader, name)
+}
+
+// set sets the uniform now and after every reload.
+func (p *ShaderProgram) set(name string, fn func(loc int32)) {
+	set := func() { fn(p.Location(name)) }
+	p.values[name] = set
+	set()
+}
+
+func (p *ShaderProgram) setValue(name string, value []float32, typ rl.ShaderUniformDataType) {
+	p.set(name, func(loc int32) {
+		rl.SetShaderValue(p.Shader, loc, value, typ)
+	})
+}
+
+func (p *ShaderProgram) SetInt(name string, v int32) {
+	// raylib only takes floats, the bits are passed as they are
+	p.setValue(name, []float32{math.Float32frombits(uint32(v))}, rl.ShaderUniformInt)
+}
+
+func (p *ShaderProgram) SetFloat(name string, v float32) {
+	p.setValue(name, []float32{v}, rl.ShaderUniformFloat)
+}
+
+func (p *ShaderProgram) SetVec2(name string, v rl.Vector2) {
+	p.setValue(name, []float32{v.X, v.Y}, rl.ShaderUniformVec2)
+}
+
+func (p *ShaderProgram) SetVec3(name string, v rl.Vector3) {
+	p.setValue(name, []float32{v.X, v.Y, v.Z}, rl.ShaderUniformVec3)
+}
+
+func (p *ShaderProgram) SetVec4(name string, v rl.Vector4) {
+	p.setValue(name, []float32{v.X, v.Y, v.Z, v.W}, rl.ShaderUniformVec4)
+}
+
+// SetColor sets a vec4 to the color with components from 0 to 1.
+func (p *ShaderProgram) SetColor(name string, c rl.Color) {
+	p.SetVec4(name, rl.ColorNormalize(c))
+}
+
+func (p *ShaderProgram) SetMat4(name string, m rl.Matrix) {
+	p.set(name, func(loc int32) {
+		rl.SetShaderValueMatrix(p.Shader, loc, m)
+	})
+}
+
+// SetTexture binds the texture to a sampler, raylib only keeps it for
+// the next draw so it is not set again after a reload.
+func (p *ShaderProgram) SetTexture(name string, t rl.Texture2D) {
+	rl.SetShaderValueTexture(p.Shader, p.Location(name), t)
+}
@@ -0,0 +1,318 @@
+package gocraft
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	fastnoise "github.com/tinogoehlert/gocraft/pkg/go-fastnoiselite"
+)
+
+const (
+	// skyBands is the number of gradient strips the sky is drawn with.
+	skyBands = 32
+	// the clouds are a grid of flat boxes, cloudCover is the noise level
+	// above which a cell has a cloud
+	cloudCell      float32 = 12
+	cloudThickness float32 = 4
+	cloudCover     float32 = 0.2
+	// exp(-(fogExpScale)²) is 1%, so exponential fog is nearly opaque at
+	// the end of the view just like linear fog
+	fogExpScale = 2.146
+)
+
+var (
+	sunsetColor = rl.NewColor(250, 140, 70, 255)
+	sunColor    = rl.NewColor(255, 245, 200, 255)
+	moonColor   = rl.NewColor(220, 225, 235, 255)
+)
+
+// SkyConfig configures the sky, fog and clouds.
+type SkyConfig struct {
+	// DayLength is a day and night in seconds, 0 keeps the sun at noon.
+	DayLength float32 `json:"day_length"`
+	// DayColor is the sky above at noon, HorizonColor the sky at the
+	// horizon and the fog at day. NightColor is the sky at midnight.
+	DayColor     string `json:"day_color"`
+	HorizonColor string `json:"horizon_color"`
+	NightColor   string `json:"night_color"`
+	// Fog is "linear", "exp" or "off". It ends where the world ends and
+	// starts at FogStart of that distance.
+	Fog      string  `json:"fog"`
+	FogStart float32 `json:"fog_start"`
+	Clouds   bool    `json:"clouds"`
+	// CloudHeight is the bottom of the clouds, CloudSpeed their drift
+	// in blocks per second.
+	CloudHeight float32 `json:"cloud_height"`
+	CloudSpeed  float32 `json:"cloud_speed"`
+}
+
+func DefaultSky() SkyConfig {
+	return SkyConfig{
+		DayLength:    1200,
+		DayColor:     "#3d7fd9",
+		HorizonColor: colorHex(rl.SkyBlue),
+		NightColor:   "#0b1024",
+		Fog:          "linear",
+		FogStart:     0.6,
+		Clouds:       true,
+		CloudHeight:  96,
+		CloudSpeed:   2,
+	}
+}
+
+func (c SkyConfig) Validate() error {
+	switch {
+	case c.DayLength < 0:
+		return errors.New("day_length must not be negative")
+	case c.Fog != "linear" && c.Fog != "exp" && c.Fog != "off":
+		return errors.New(`fog must be "linear", "exp" or "off"`)
+	case c.FogStart < 0 || c.FogStart >= 1:
+		return errors.New("fog_start must be in [0, 1)")
+	case c.CloudHeight <= 0:
+		return errors.New("cloud_height must be above 0")
+	case c.CloudSpeed < 0:
+		return errors.New("cloud_speed must not be negative")
+	}
+	for _, color := range []struct{ name, value string }{
+		{"day_color", c.DayColor},
+		{"horizon_color", c.HorizonColor},
+		{"night_color", c.NightColor},
+	} {
+		if _, err := parseColor(color.value); err != nil {
+			return fmt.Errorf("%s: %s", color.name, err.Error())
+		}
+	}
+	return nil
+}
+
+// Sky follows the time of day of the world. It draws the sky behind the
+// world and the clouds in it, and gives the light and fog to the world
+// shader.
+type Sky struct {
+	config              SkyConfig
+	day, horizon, night rl.Color
+
+	// the state of the current frame, see Update
+	time     float32
+	sun      rl.Vector3
+	daylight float32
+	zenith   rl.Color
+	fog      rl.Color
+	wind     float32
+
+	// shader is lit and fogged by Apply
+	shader *ShaderProgram
+
+	noise    *fastnoise.NoiseState
+	material Material
+	// cloudy cells around cloudOrigin, rebuilt when the player moves
+	// to another cell
+	cells       [][2]int
+	cloudOrigin [3]int
+	clouds      []rl.Matrix
+}
+
+// NewSky creates the sky, config has to be valid. Light and fog go to
+// the shader set with Bind.
+func NewSky(config SkyConfig, seed int) *Sky {
+	s := &Sky{config: config}
+	s.day, _ = parseColor(config.DayColor)
+	s.horizon, _ = parseColor(config.HorizonColor)
+	s.night, _ = parseColor(config.NightColor)
+
+	s.noise = fastnoise.NewDefaultNoise()
+	s.noise.SetSeed(seed + 1)
+	s.noise.SetType(fastnoise.FNL_NOISE_OPENSIMPLEX2)
+	s.noise.SetFractal(fastnoise.FNL_FRACTAL_FBM)
+	s.noise.SetOctaves(3)
+	s.noise.SetFrequency(0.08)
+	s.cloudOrigin = [3]int{math.MaxInt32}
+	s.Update(0, DefaultTickRate)
+	return s
+}
+
+// Bind sets the world shader the light and fog are applied to.
+func (s *Sky) Bind(shader *ShaderProgram) {
+	s.shader = shader
+}
+
+// Update sets the time of day from the ticks of the world.
+func (s *Sky) Update(ticks uint64, tickRate int) {
+	seconds := float64(ticks) / float64(tickRate)
+	s.time = 0.5
+	if s.config.DayLength > 0 {
+		// a new world starts in the morning
+		_, frac := math.Modf(seconds/float64(s.config.DayLength) + 0.3)
+		s.time = float32(frac)
+	}
+	s.wind = float32(seconds) * s.config.CloudSpeed
+
+	// the sun rises in the east at a quarter of the day
+	angle := 2 * math.Pi * (s.time - 0.25)
+	s.sun = rl.Vector3Normalize(rl.NewVector3(cos32(angle), sin32(angle), 0.3))
+	s.daylight = clamp32((s.sun.Y+0.1)/0.3, 0, 1)
+
+	nightHorizon := lerpColor(s.night, s.horizon, 0.15)
+	s.zenith = lerpColor(s.night, s.day, s.daylight)
+	s.fog = lerpColor(nightHorizon, s.horizon, s.daylight)
+	// sunrise and sunset tint the horizon
+	sunset := clamp32(1-float32(math.Abs(float64(s.sun.Y)))*4, 0, 1)
+	s.fog = lerpColor(s.fog, sunsetColor, sunset*0.6)
+}
+
+// TimeOfDay is the time in days, 0 is midnight and 0.5 noon.
+func (s *Sky) TimeOfDay() float32 {
+	return s.time
+}
+
+// Daylight is 1 at day, 0 at night and in between at dawn and dusk.
+func (s *Sky) Daylight() float32 {
+	return s.daylight
+}
+
+// FogColor is the color of the sky at the horizon.
+func (s *Sky) FogColor() rl.Color {
+	return s.fog
+}
+
+// Apply lights the world for the time of day. The sun, or the moon at
+// night, becomes the direction of light and color and ambient are dimmed
+// by night. The fog ends at distance.
+func (s *Sky) Apply(light *Light, color rl.Color, ambient, distance float32) {
+	level := s.LightLevel()
+	light.position = rl.Vector3Scale(s.LightDirection(), 50)
+	light.target = rl.Vector3Zero()
+	light.color = lerpColor(rl.Black, color, level)
+	light.UpdateValues()
+
+	ambient *= level
+	s.shader.SetVec4("ambient", rl.NewVector4(ambient, ambient, ambient, 1))
+	s.shader.SetColor("fogColor", s.fog)
+
+	// the shader skips fog ending at 0, linear fog has no density
+	var start, end, density float32
+	if s.config.Fog != "off" {
+		end = distance
+		start = distance * s.config.FogStart
+	}
+	if s.config.Fog == "exp" {
+		density = fogExpScale / (end - start)
+	}
+	s.shader.SetFloat("fogStart", start)
+	s.shader.SetFloat("fogEnd", end)
+	s.shader.SetFloat("fogDensity", density)
+}
+
+// LightDirection points towards the sun, or the moon at night.
+func (s *Sky) LightDirection() rl.Vector3 {
+	if s.sun.Y < 0 {
+		return rl.Vector3Negate(s.sun)
+	}
+	return s.sun
+}
+
+// LightLevel dims the light by night.
+func (s *Sky) LightLevel() float32 {
+	return 0.2 + 0.8*s.daylight
+}
+
+// colorAt is the color of the sky at the elevation in radians.
+func (s *Sky) colorAt(elevation float32) rl.Color {
+	if elevation <= 0 {
+		return s.fog
+	}
+	return lerpColor(s.fog, s.zenith, float32(math.Sqrt(float64(sin32(elevation)))))
+}
+
+// DrawBackground fills the screen with the sky as seen from cam, with
+// the sun and the moon. It is drawn before the world.
+func (s *Sky) DrawBackground(r Renderer, cam rl.Camera3D) {
+	var (
+		sw, sh  = r.ScreenSize()
+		w, h    = float32(sw), float32(sh)
+		forward = rl.Vector3Normalize(rl.Vector3Subtract(cam.Target, cam.Position))
+		pitch   = float32(math.Asin(float64(forward.Y)))
+		tanHalf = float32(math.Tan(float64(radians(cam.Fovy) / 2)))
+		band    = h / skyBands
+	)
+	elevation := func(y float32) float32 {
+		return pitch + float32(math.Atan(float64((h/2-y)/(h/2)*tanHalf)))
+	}
+	for i := 0; i < skyBands; i++ {
+		y := float32(i) * band
+		r.DrawRectangleGradient(rl.NewRectangle(0, float32(int32(y)), w, float32(int32(band)+1)), s.colorAt(elevation(y)), s.colorAt(elevation(y+band)))
+	}
+
+	for _, body := range []struct {
+		dir    rl.Vector3
+		radius float32
+		color  rl.Color
+	}{
+		{s.sun, 28, sunColor},
+		{rl.Vector3Negate(s.sun), 18, moonColor},
+	} {
+		if rl.Vector3DotProduct(body.dir, forward) <= 0 {
+			continue
+		}
+		pos, ok := worldToScreen(rl.Vector3Add(cam.Position, rl.Vector3Scale(body.dir, 100)), cam, sw, sh)
+		if ok {
+			r.DrawCircle(pos, body.radius, body.color)
+		}
+	}
+}
+
+// DrawClouds draws the clouds within radius around eye, it has to be
+// called in 3D mode.
+func (s *Sky) DrawClouds(r Renderer, block Mesh, eye rl.Vector3, radius float32, stats *RenderStats) {
+	if !s.config.Clouds {
+		return
+	}
+	var (
+		n      = int(radius / cloudCell)
+		ci     = int(math.Floor(float64((eye.X - s.wind) / cloudCell)))
+		cj     = int(math.Floor(float64(eye.Z / cloudCell)))
+		origin = [3]int{ci, cj, n}
+	)
+	if origin != s.cloudOrigin {
+		s.cloudOrigin = origin
+		s.cells = s.cells[:0]
+		for i := ci - n; i <= ci+n; i++ {
+			for j := cj - n; j <= cj+n; j++ {
+				if (i-ci)*(i-ci)+(j-cj)*(j-cj) > n*n {
+					continue
+				}
+				if s.noise.GetNoise2D(float32(i), float32(j)) > cloudCover {
+					s.cells = append(s.cells, [2]int{i, j})
+				}
+			}
+		}
+	}
+	if len(s.cells) == 0 {
+		return
+	}
+
+	// the cells stay on their grid, the wind moves the whole grid
+	s.clouds = s.clouds[:0]
+	scale := rl.MatrixScale(cloudCell, cloudThickness, cloudCell)
+	for _, c := range s.cells {
+		s.clouds = append(s.clouds, rl.MatrixMultiply(scale, rl.MatrixTranslate(
+			float32(c[0])*cloudCell+s.wind,
+			s.config.CloudHeight,
+			float32(c[1])*cloudCell,
+		)))
+	}
+	s.material.Color = lerpColor(rl.DarkGray, rl.White, s.daylight)
+	stats.DrawCalls++
+	stats.Instances += len(s.clouds)
+	r.DrawMeshInstanced(block, s.material, s.clouds)
+}
+
+// DebugLines shows the time of day for the debug screen.
+func (s *Sky) DebugLines() []string {
+	minutes := int(s.time * 24 * 60)
+	return []string{
+		fmt.Sprintf("time: %02d:%02d, daylight %.2f, %d clouds", minutes/60, minutes%60, s.daylight, len(s.cells)),
+	}
+}
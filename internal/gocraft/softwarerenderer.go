@@ -0,0 +1,441 @@
+package gocraft
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// SoftwareRenderer draws into an image on the CPU, so views of the world
+// can be rendered and compared without a GPU. Meshes are textured and
+// depth tested and every triangle is lit by its face normal, there is no
+// specular light, fog or gamma like in the lighting shader.
+type SoftwareRenderer struct {
+	Image *image.RGBA
+	// LightDirection points towards the light.
+	LightDirection rl.Vector3
+	// Ambient is the light level of faces turned away from the light.
+	Ambient float32
+
+	// depth holds 1/z of the nearest triangle per pixel, 0 is empty
+	depth    []float32
+	meshes   []*MeshData
+	textures []*image.RGBA
+	cube     *MeshData
+	view     softwareView
+}
+
+// softwareView is the camera of the 3D mode.
+type softwareView struct {
+	eye              rl.Vector3
+	front, right, up rl.Vector3
+	tanH, tanV       float32
+	width, height    float32
+	enabled          bool
+}
+
+func NewSoftwareRenderer(width, height int) *SoftwareRenderer {
+	return &SoftwareRenderer{
+		Image:          image.NewRGBA(image.Rect(0, 0, width, height)),
+		LightDirection: rl.Vector3Normalize(rl.NewVector3(0.5, 1, 0.3)),
+		Ambient:        0.4,
+		depth:          make([]float32, width*height),
+		cube:           unitCube(),
+	}
+}
+
+func (r *SoftwareRenderer) ScreenSize() (int32, int32) {
+	size := r.Image.Bounds().Size()
+	return int32(size.X), int32(size.Y)
+}
+
+func (r *SoftwareRenderer) LoadMesh(data *MeshData) (Mesh, error) {
+	r.meshes = append(r.meshes, data)
+	return Mesh{ID: len(r.meshes) - 1}, nil
+}
+
+func (r *SoftwareRenderer) LoadTexture(img image.Image) Texture {
+	rgba := image.NewRGBA(img.Bounds().Sub(img.Bounds().Min))
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	r.textures = append(r.textures, rgba)
+	size := rgba.Bounds().Size()
+	return Texture{ID: len(r.textures), Width: int32(size.X), Height: int32(size.Y)}
+}
+
+func (r *SoftwareRenderer) UpdateTexture(tex Texture, img image.Image) {
+	if tex.ID == 0 {
+		return
+	}
+	dst := r.textures[tex.ID-1]
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Src)
+}
+
+func (r *SoftwareRenderer) BeginFrame(clear rl.Color) {
+	draw.Draw(r.Image, r.Image.Bounds(), image.NewUniform(clear), image.Point{}, draw.Src)
+	for i := range r.depth {
+		r.depth[i] = 0
+	}
+}
+
+func (r *SoftwareRenderer) EndFrame() {}
+
+func (r *SoftwareRenderer) Begin3D(cam rl.Camera3D) {
+	w, h := r.ScreenSize()
+	front := rl.Vector3Normalize(rl.Vector3Subtract(cam.Target, cam.Position))
+	right := rl.Vector3Normalize(rl.Vector3CrossProduct(front, cam.Up))
+	tanV := float32(math.Tan(float64(radians(cam.Fovy) / 2)))
+	r.view = softwareView{
+		eye:     cam.Position,
+		front:   front,
+		right:   right,
+		up:      rl.Vector3CrossProduct(right, front),
+		tanV:    tanV,
+		tanH:    tanV * float32(w) / float32(h),
+		width:   float32(w),
+		height:  float32(h),
+		enabled: true,
+	}
+}
+
+func (r *SoftwareRenderer) End3D() {
+	r.view.enabled = false
+}
+
+func (r *SoftwareRenderer) DrawMeshInstanced(mesh Mesh, material Material, transforms []rl.Matrix) {
+	var tex *image.RGBA
+	if material.Texture.ID > 0 {
+		tex = r.textures[material.Texture.ID-1]
+	}
+	for _, m := range transforms {
+		r.drawMesh(r.meshes[mesh.ID], m, tex, material.Color)
+	}
+}
+
+func (r *SoftwareRenderer) DrawCube(transform rl.Matrix, color rl.Color) {
+	r.drawMesh(r.cube, transform, nil, color)
+}
+
+// DrawGrid is not drawn, the grid is only a debug aid.
+func (r *SoftwareRenderer) DrawGrid(slices int32, spacing float32) {}
+
+// clipVertex is a vertex in view space.
+type clipVertex struct {
+	pos rl.Vector3
+	uv  rl.Vector2
+}
+
+func (r *SoftwareRenderer) drawMesh(mesh *MeshData, m rl.Matrix, tex *image.RGBA, tint rl.Color) {
+	if !r.view.enabled {
+		return
+	}
+	v := &r.view
+	for i := 0; i+2 < len(mesh.Positions); i += 3 {
+		var (
+			p0 = rl.Vector3Transform(mesh.Positions[i], m)
+			p1 = rl.Vector3Transform(mesh.Positions[i+1], m)
+			p2 = rl.Vector3Transform(mesh.Positions[i+2], m)
+			n  = rl.Vector3CrossProduct(rl.Vector3Subtract(p1, p0), rl.Vector3Subtract(p2, p0))
+		)
+		// counter clockwise triangles face the viewer
+		if rl.Vector3DotProduct(n, rl.Vector3Subtract(p0, v.eye)) >= 0 {
+			continue
+		}
+		n = rl.Vector3Normalize(n)
+		shade := r.Ambient + (1-r.Ambient)*clamp32(rl.Vector3DotProduct(n, r.LightDirection), 0, 1)
+
+		var poly [4]clipVertex
+		for j, p := range [3]rl.Vector3{p0, p1, p2} {
+			d := rl.Vector3Subtract(p, v.eye)
+			poly[j] = clipVertex{
+				pos: rl.NewVector3(rl.Vector3DotProduct(d, v.right), rl.Vector3DotProduct(d, v.up), rl.Vector3DotProduct(d, v.front)),
+				uv:  mesh.Texcoords[i+j],
+			}
+		}
+		clipped := clipNear(poly[:3], poly[:0:4])
+		for j := 1; j+1 < len(clipped); j++ {
+			r.rasterize([3]clipVertex{clipped[0], clipped[j], clipped[j+1]}, tex, tint, shade)
+		}
+	}
+}
+
+// clipNear cuts off the part of the triangle in front of the near plane,
+// which leaves up to four vertices in out.
+func clipNear(in []clipVertex, out []clipVertex) []clipVertex {
+	var poly [4]clipVertex
+	n := 0
+	for i := range in {
+		a, b := in[i], in[(i+1)%len(in)]
+		if a.pos.Z >= cameraNear {
+			poly[n] = a
+			n++
+		}
+		if (a.pos.Z >= cameraNear) != (b.pos.Z >= cameraNear) {
+			t := (cameraNear - a.pos.Z) / (b.pos.Z - a.pos.Z)
+			poly[n] = clipVertex{
+				pos: rl.Vector3Lerp(a.pos, b.pos, t),
+				uv:  rl.Vector2Lerp(a.uv, b.uv, t),
+			}
+			n++
+		}
+	}
+	return append(out, poly[:n]...)
+}
+
+// rasterize fills a triangle in view space with perspective correct
+// texture coordinates.
+func (r *SoftwareRenderer) rasterize(tri [3]clipVertex, tex *image.RGBA, tint rl.Color, shade float32) {
+	var (
+		v          = &r.view
+		sx, sy, iz [3]float32
+		// texture coordinates divided by z
+		u, w [3]float32
+	)
+	for i, c := range tri {
+		iz[i] = 1 / c.pos.Z
+		sx[i] = (c.pos.X*iz[i]/v.tanH + 1) / 2 * v.width
+		sy[i] = (1 - c.pos.Y*iz[i]/v.tanV) / 2 * v.height
+		u[i] = c.uv.X * iz[i]
+		w[i] = c.uv.Y * iz[i]
+	}
+	area := (sx[1]-sx[0])*(sy[2]-sy[0]) - (sx[2]-sx[0])*(sy[1]-sy[0])
+	if area == 0 {
+		return
+	}
+	var (
+		bounds = r.Image.Bounds()
+		minX   = maxInt(int(floor32(minf(sx[0], minf(sx[1], sx[2])))), 0)
+		maxX   = minInt(int(floor32(maxf(sx[0], maxf(sx[1], sx[2])))), bounds.Dx()-1)
+		minY   = maxInt(int(floor32(minf(sy[0], minf(sy[1], sy[2])))), 0)
+		maxY   = minInt(int(floor32(maxf(sy[0], maxf(sy[1], sy[2])))), bounds.Dy()-1)
+	)
+	for y := minY; y <= maxY; y++ {
+		py := float32(y) + 0.5
+		for x := minX; x <= maxX; x++ {
+			px := float32(x) + 0.5
+			b0 := ((sx[1]-px)*(sy[2]-py) - (sx[2]-px)*(sy[1]-py)) / area
+			b1 := ((sx[2]-px)*(sy[0]-py) - (sx[0]-px)*(sy[2]-py)) / area
+			b2 := 1 - b0 - b1
+			if b0 < 0 || b1 < 0 || b2 < 0 {
+				continue
+			}
+			z := b0*iz[0] + b1*iz[1] + b2*iz[2]
+			i := y*bounds.Dx() + x
+			if z <= r.depth[i] {
+				continue
+			}
+
+			c := tint
+			if tex != nil {
+				t := sampleTexture(tex, (b0*u[0]+b1*u[1]+b2*u[2])/z, (b0*w[0]+b1*w[1]+b2*w[2])/z)
+				if t.A < 128 {
+					continue
+				}
+				c = rl.NewColor(
+					uint8(uint16(t.R)*uint16(tint.R)/255),
+					uint8(uint16(t.G)*uint16(tint.G)/255),
+					uint8(uint16(t.B)*uint16(tint.B)/255),
+					255,
+				)
+			}
+			r.depth[i] = z
+			r.Image.SetRGBA(x, y, color.RGBA{
+				R: uint8(float32(c.R) * shade),
+				G: uint8(float32(c.G) * shade),
+				B: uint8(float32(c.B) * shade),
+				A: 255,
+			})
+		}
+	}
+}
+
+// sampleTexture returns the nearest texel, coordinates repeat.
+func sampleTexture(tex *image.RGBA, u, v float32) color.RGBA {
+	size := tex.Bounds().Size()
+	x := int(floor32(u*float32(size.X))) % size.X
+	y := int(floor32(v*float32(size.Y))) % size.Y
+	if x < 0 {
+		x += size.X
+	}
+	if y < 0 {
+		y += size.Y
+	}
+	return tex.RGBAAt(x, y)
+}
+
+// blend draws a pixel over the image by its alpha.
+func (r *SoftwareRenderer) blend(x, y int, c rl.Color) {
+	if !(image.Point{X: x, Y: y}.In(r.Image.Bounds())) || c.A == 0 {
+		return
+	}
+	if c.A == 255 {
+		r.Image.SetRGBA(x, y, c)
+		return
+	}
+	var (
+		dst = r.Image.RGBAAt(x, y)
+		a   = uint16(c.A)
+		mix = func(s, d uint8) uint8 {
+			return uint8((uint16(s)*a + uint16(d)*(255-a)) / 255)
+		}
+	)
+	r.Image.SetRGBA(x, y, color.RGBA{R: mix(c.R, dst.R), G: mix(c.G, dst.G), B: mix(c.B, dst.B), A: 255})
+}
+
+// pixels returns the pixel range covered by the rectangle.
+func (r *SoftwareRenderer) pixels(rect rl.Rectangle) image.Rectangle {
+	return image.Rect(
+		int(floor32(rect.X+0.5)),
+		int(floor32(rect.Y+0.5)),
+		int(floor32(rect.X+rect.Width+0.5)),
+		int(floor32(rect.Y+rect.Height+0.5)),
+	).Intersect(r.Image.Bounds())
+}
+
+func (r *SoftwareRenderer) DrawRectangle(rect rl.Rectangle, c rl.Color) {
+	p := r.pixels(rect)
+	for y := p.Min.Y; y < p.Max.Y; y++ {
+		for x := p.Min.X; x < p.Max.X; x++ {
+			r.blend(x, y, c)
+		}
+	}
+}
+
+func (r *SoftwareRenderer) DrawRectangleLines(rect rl.Rectangle, thickness float32, c rl.Color) {
+	t := thickness
+	r.DrawRectangle(rl.NewRectangle(rect.X, rect.Y, rect.Width, t), c)
+	r.DrawRectangle(rl.NewRectangle(rect.X, rect.Y+rect.Height-t, rect.Width, t), c)
+	r.DrawRectangle(rl.NewRectangle(rect.X, rect.Y+t, t, rect.Height-2*t), c)
+	r.DrawRectangle(rl.NewRectangle(rect.X+rect.Width-t, rect.Y+t, t, rect.Height-2*t), c)
+}
+
+func (r *SoftwareRenderer) DrawRectangleGradient(rect rl.Rectangle, top, bottom rl.Color) {
+	p := r.pixels(rect)
+	for y := p.Min.Y; y < p.Max.Y; y++ {
+		c := lerpColor(top, bottom, (float32(y)+0.5-rect.Y)/rect.Height)
+		for x := p.Min.X; x < p.Max.X; x++ {
+			r.blend(x, y, c)
+		}
+	}
+}
+
+func (r *SoftwareRenderer) DrawLine(from, to rl.Vector2, c rl.Color) {
+	d := rl.Vector2Subtract(to, from)
+	steps := int(maxf(float32(math.Abs(float64(d.X))), float32(math.Abs(float64(d.Y))))) + 1
+	for i := 0; i <= steps; i++ {
+		p := rl.Vector2Add(from, rl.Vector2Scale(d, float32(i)/float32(steps)))
+		r.blend(int(floor32(p.X)), int(floor32(p.Y)), c)
+	}
+}
+
+func (r *SoftwareRenderer) DrawCircle(center rl.Vector2, radius float32, c rl.Color) {
+	p := r.pixels(rl.NewRectangle(center.X-radius, center.Y-radius, 2*radius, 2*radius))
+	for y := p.Min.Y; y < p.Max.Y; y++ {
+		for x := p.Min.X; x < p.Max.X; x++ {
+			dx, dy := float32(x)+0.5-center.X, float32(y)+0.5-center.Y
+			if dx*dx+dy*dy <= radius*radius {
+				r.blend(x, y, c)
+			}
+		}
+	}
+}
+
+func (r *SoftwareRenderer) DrawTexture(tex Texture, src, dst rl.Rectangle, tint rl.Color) {
+	if tex.ID == 0 {
+		r.DrawRectangle(dst, tint)
+		return
+	}
+	var (
+		img = r.textures[tex.ID-1]
+		p   = r.pixels(dst)
+	)
+	for y := p.Min.Y; y < p.Max.Y; y++ {
+		for x := p.Min.X; x < p.Max.X; x++ {
+			sx := int(src.X + (float32(x)+0.5-dst.X)/dst.Width*src.Width)
+			sy := int(src.Y + (float32(y)+0.5-dst.Y)/dst.Height*src.Height)
+			t := img.RGBAAt(sx, sy)
+			r.blend(x, y, rl.NewColor(
+				uint8(uint16(t.R)*uint16(tint.R)/255),
+				uint8(uint16(t.G)*uint16(tint.G)/255),
+				uint8(uint16(t.B)*uint16(tint.B)/255),
+				uint8(uint16(t.A)*uint16(tint.A)/255),
+			))
+		}
+	}
+}
+
+func (r *SoftwareRenderer) DrawText(text string, x, y, size int32, c rl.Color) {
+	scale := glyphScale(size)
+	for _, ch := range text {
+		glyph := glyphFor(ch)
+		for row := 0; row < glyphHeight; row++ {
+			for col := 0; col < glyphWidth; col++ {
+				if glyph[row*glyphWidth+col] != '#' {
+					continue
+				}
+				r.DrawRectangle(recti(x+int32(col)*scale, y+int32(row)*scale, scale, scale), c)
+			}
+		}
+		x += (glyphWidth + 1) * scale
+	}
+}
+
+func (r *SoftwareRenderer) MeasureText(text string, size int32) int32 {
+	n := int32(len([]rune(text)))
+	if n == 0 {
+		return 0
+	}
+	scale := glyphScale(size)
+	return n*(glyphWidth+1)*scale - scale
+}
+
+// unitCube is a cube of size 1 around the origin, for DrawCube.
+func unitCube() *MeshData {
+	mesh := &MeshData{}
+	// u × v is the normal, so the corners go counter clockwise
+	for _, f := range [][3]rl.Vector3{
+		{{X: 1}, {Z: -1}, {Y: 1}},
+		{{X: -1}, {Z: 1}, {Y: 1}},
+		{{Y: 1}, {X: 1}, {Z: -1}},
+		{{Y: -1}, {X: 1}, {Z: 1}},
+		{{Z: 1}, {X: 1}, {Y: 1}},
+		{{Z: -1}, {X: -1}, {Y: 1}},
+	} {
+		var (
+			n, u, v = rl.Vector3Scale(f[0], 0.5), rl.Vector3Scale(f[1], 0.5), rl.Vector3Scale(f[2], 0.5)
+			corners = [4]rl.Vector3{
+				rl.Vector3Subtract(rl.Vector3Subtract(n, u), v),
+				rl.Vector3Subtract(rl.Vector3Add(n, u), v),
+				rl.Vector3Add(rl.Vector3Add(n, u), v),
+				rl.Vector3Add(rl.Vector3Subtract(n, u), v),
+			}
+			uvs = [4]rl.Vector2{{X: 0, Y: 1}, {X: 1, Y: 1}, {X: 1, Y: 0}, {X: 0, Y: 0}}
+		)
+		for _, i := range []int{0, 1, 2, 0, 2, 3} {
+			mesh.Positions = append(mesh.Positions, corners[i])
+			mesh.Normals = append(mesh.Normals, f[0])
+			mesh.Texcoords = append(mesh.Texcoords, uvs[i])
+		}
+	}
+	return mesh
+}
+
+func floor32(v float32) float32 {
+	return float32(math.Floor(float64(v)))
+}
+
+func minf(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxf(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,45 @@
+package gocraft
+
+// maxTicksPerFrame caps the catch up after a long frame, so a slow
+// machine drops simulation time instead of falling further behind.
+const maxTicksPerFrame = 10
+
+// Ticker turns variable frame times into a fixed number of simulation
+// ticks and remembers the remainder for interpolation.
+type Ticker struct {
+	rate        int
+	accumulator float32
+}
+
+func NewTicker(rate int) *Ticker {
+	if rate <= 0 {
+		rate = DefaultTickRate
+	}
+	return &Ticker{rate: rate}
+}
+
+func (t *Ticker) Rate() int {
+	return t.rate
+}
+
+// Step is the duration of one tick in seconds.
+func (t *Ticker) Step() float32 {
+	return 1 / float32(t.rate)
+}
+
+// Advance adds the frame time and returns how many ticks are due.
+func (t *Ticker) Advance(frame float32) int {
+	t.accumulator += frame
+	ticks := int(t.accumulator / t.Step())
+	t.accumulator -= float32(ticks) * t.Step()
+	if ticks > maxTicksPerFrame {
+		ticks = maxTicksPerFrame
+	}
+	return ticks
+}
+
+// Alpha is how far the current frame is between the last and the next
+// tick, in the range 0..1.
+func (t *Ticker) Alpha() float32 {
+	return clamp32(t.accumulator/t.Step(), 0, 1)
+}
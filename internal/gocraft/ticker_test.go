@@ -0,0 +1,47 @@
+package gocraft
+
+import "testing"
+
+func TestTickerAdvance(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames []float32
+		ticks  []int
+		alpha  float32
+	}{
+		{"short frames carry over", []float32{0.03, 0.03, 0.03}, []int{0, 1, 0}, 0.8},
+		{"one frame many ticks", []float32{0.125}, []int{2}, 0.5},
+		{"exact ticks", []float32{0.05, 0.1}, []int{1, 2}, 0},
+		{"long frame is capped", []float32{2, 0}, []int{maxTicksPerFrame, 0}, 0},
+		{"cap drops time", []float32{1.01, 0.045}, []int{maxTicksPerFrame, 1}, 0.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticker := NewTicker(20)
+			for i, frame := range tt.frames {
+				if got := ticker.Advance(frame); got != tt.ticks[i] {
+					t.Errorf("frame %d of %.3fs: %d ticks, want %d", i, frame, got, tt.ticks[i])
+				}
+			}
+			if got := ticker.Alpha(); !near(got, tt.alpha, 1e-3) {
+				t.Errorf("alpha %.3f, want %.3f", got, tt.alpha)
+			}
+		})
+	}
+}
+
+func TestTickerAlphaStaysInRange(t *testing.T) {
+	ticker := NewTicker(60)
+	for i := 0; i < 1000; i++ {
+		ticker.Advance(float32(i%7) * 0.004)
+		if a := ticker.Alpha(); a < 0 || a > 1 {
+			t.Fatalf("frame %d: alpha %.3f out of 0..1", i, a)
+		}
+	}
+}
+
+func TestTickerDefaultRate(t *testing.T) {
+	if got := NewTicker(0).Rate(); got != DefaultTickRate {
+		t.Errorf("rate %d, want %d", got, DefaultTickRate)
+	}
+}
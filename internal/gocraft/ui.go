@@ -0,0 +1,194 @@
+package gocraft
+
+import (
+	"fmt"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const (
+	uiWidgetWidth  float32 = 300
+	uiWidgetHeight float32 = 30
+	uiSpacing      float32 = 8
+	uiFontSize     int32   = 20
+	uiTitleSize    int32   = 40
+)
+
+var (
+	uiBackground = rl.Fade(rl.DarkGray, 0.85)
+	uiHover      = rl.Gray
+	uiActive     = rl.NewColor(90, 130, 200, 255)
+	uiText       = rl.RayWhite
+)
+
+// UI draws immediate mode widgets: every call draws the widget and
+// reports what the user did with it this frame. Widgets are laid out in
+// a centered column from top to bottom.
+type UI struct {
+	r       Renderer
+	mouse   rl.Vector2
+	clicked bool
+	down    bool
+	wheel   float32
+	x, y    float32
+	// focus is the id of the text field that gets the typed characters.
+	focus string
+}
+
+// Begin starts a frame of widgets drawn with r, the column starts at y.
+func (ui *UI) Begin(r Renderer, y float32) {
+	w, _ := r.ScreenSize()
+	ui.r = r
+	ui.mouse = rl.GetMousePosition()
+	ui.clicked = rl.IsMouseButtonPressed(rl.MouseLeftButton)
+	ui.down = rl.IsMouseButtonDown(rl.MouseLeftButton)
+	ui.wheel = rl.GetMouseWheelMove()
+	ui.x = (float32(w) - uiWidgetWidth) / 2
+	ui.y = y
+}
+
+// next returns the rectangle of the next widget and moves down.
+func (ui *UI) next(height float32) rl.Rectangle {
+	r := rl.NewRectangle(ui.x, ui.y, uiWidgetWidth, height)
+	ui.y += height + uiSpacing
+	return r
+}
+
+// Space leaves a gap in the column.
+func (ui *UI) Space(height float32) {
+	ui.y += height
+}
+
+// Wheel is the mouse wheel movement of this frame, for scrolling lists.
+func (ui *UI) Wheel() float32 {
+	return ui.wheel
+}
+
+func (ui *UI) hovered(r rl.Rectangle) bool {
+	return rl.CheckCollisionPointRec(ui.mouse, r)
+}
+
+// Title draws a centered heading.
+func (ui *UI) Title(text string) {
+	r := ui.next(float32(uiTitleSize))
+	drawCentered(ui.r, text, r, uiTitleSize, uiText)
+	ui.Space(uiSpacing)
+}
+
+// Label draws a line of centered text.
+func (ui *UI) Label(text string) {
+	drawCentered(ui.r, text, ui.next(float32(uiFontSize)), uiFontSize, uiText)
+}
+
+// Button reports whether the button was clicked.
+func (ui *UI) Button(label string) bool {
+	return ui.button(ui.next(uiWidgetHeight), label, false)
+}
+
+func (ui *UI) button(r rl.Rectangle, label string, active bool) bool {
+	color := uiBackground
+	switch {
+	case active:
+		color = uiActive
+	case ui.hovered(r):
+		color = uiHover
+	}
+	ui.r.DrawRectangle(r, color)
+	ui.r.DrawRectangleLines(r, 1, rl.Black)
+	drawCentered(ui.r, label, r, uiFontSize, uiText)
+	return ui.clicked && ui.hovered(r)
+}
+
+// Buttons draws a row of buttons sharing the width of one widget and
+// returns the index of the clicked one or -1.
+func (ui *UI) Buttons(labels ...string) int {
+	var (
+		r       = ui.next(uiWidgetHeight)
+		w       = (r.Width - uiSpacing*float32(len(labels)-1)) / float32(len(labels))
+		clicked = -1
+	)
+	for i, label := range labels {
+		b := rl.NewRectangle(r.X+float32(i)*(w+uiSpacing), r.Y, w, r.Height)
+		if ui.button(b, label, false) {
+			clicked = i
+		}
+	}
+	return clicked
+}
+
+// Toggle is a button showing an on/off value, it flips value when clicked.
+func (ui *UI) Toggle(label string, value *bool) bool {
+	state := "off"
+	if *value {
+		state = "on"
+	}
+	if ui.button(ui.next(uiWidgetHeight), fmt.Sprintf("%s: %s", label, state), *value) {
+		*value = !*value
+		return true
+	}
+	return false
+}
+
+// Slider lets the user drag value between min and max. step rounds the
+// value, 0 keeps it continuous. It reports whether the value changed.
+func (ui *UI) Slider(label string, value *float32, min, max, step float32, format string) bool {
+	r := ui.next(uiWidgetHeight)
+	ui.r.DrawRectangle(r, uiBackground)
+
+	old := *value
+	if ui.down && ui.hovered(r) {
+		v := min + (ui.mouse.X-r.X)/r.Width*(max-min)
+		if step > 0 {
+			v = float32(int((v-min)/step+0.5))*step + min
+		}
+		*value = clamp32(v, min, max)
+	}
+
+	fill := r
+	fill.Width = r.Width * (*value - min) / (max - min)
+	ui.r.DrawRectangle(fill, uiActive)
+	ui.r.DrawRectangleLines(r, 1, rl.Black)
+	drawCentered(ui.r, fmt.Sprintf("%s: "+format, label, *value), r, uiFontSize, uiText)
+	return *value != old
+}
+
+// TextField edits text, clicking it takes the keyboard focus. Only
+// printable ASCII is accepted up to limit characters.
+func (ui *UI) TextField(id, label string, text *string, limit int) {
+	drawCentered(ui.r, label, ui.next(float32(uiFontSize)), uiFontSize, uiText)
+	r := ui.next(uiWidgetHeight)
+	if ui.clicked {
+		if ui.hovered(r) {
+			ui.focus = id
+		} else if ui.focus == id {
+			ui.focus = ""
+		}
+	}
+
+	focused := ui.focus == id
+	if focused {
+		for c := rl.GetCharPressed(); c != 0; c = rl.GetCharPressed() {
+			if c >= 32 && c < 127 && len(*text) < limit {
+				*text += string(rune(c))
+			}
+		}
+		if rl.IsKeyPressed(rl.KeyBackspace) && len(*text) > 0 {
+			*text = (*text)[:len(*text)-1]
+		}
+	}
+
+	ui.r.DrawRectangle(r, rl.Fade(rl.Black, 0.7))
+	border := rl.Gray
+	shown := *text
+	if focused {
+		border = rl.White
+		shown += "_"
+	}
+	ui.r.DrawRectangleLines(r, 1, border)
+	ui.r.DrawText(shown, int32(r.X)+6, int32(r.Y+(r.Height-float32(uiFontSize))/2), uiFontSize, uiText)
+}
+
+func drawCentered(renderer Renderer, text string, r rl.Rectangle, size int32, color rl.Color) {
+	w := renderer.MeasureText(text, size)
+	renderer.DrawText(text, int32(r.X+(r.Width-float32(w))/2), int32(r.Y+(r.Height-float32(size))/2), size, color)
+}
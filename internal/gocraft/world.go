@@ -0,0 +1,237 @@
+package gocraft
+
+import (
+	"fmt"
+	"math/rand"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	fastnoise "github.com/tinogoehlert/gocraft/pkg/go-fastnoiselite"
+)
+
+const (
+	DefaultTickRate             = 20
+	DefaultChunkSize    float32 = 128
+	DefaultSeed                 = fastnoise.DefaultSeed
+	DefaultViewDistance         = 1
+
+	fallOutOfWorld float32 = -64
+	// unloadInterval is the number of ticks between unloading the chunks
+	// the players left behind.
+	unloadInterval = 10 * DefaultTickRate
+)
+
+// World is the simulated part of the game. It does not depend on a
+// window, so it can be ticked by the client, a server or a test.
+type World struct {
+	Chunks  *ChunkManager
+	Players []*Player
+	Ticks   uint64
+	// ViewDistance is the radius in chunks kept loaded around every player.
+	ViewDistance int
+
+	store        *WorldStore
+	visible      []*Chunk
+	current      *Chunk
+	rng          *rand.Rand
+	lastEntityID EntityID
+	// pathBudget is what is left of pathNodesPerTick in the current tick
+	pathBudget int
+}
+
+// NewWorld creates a world from the seed. If store is not nil, edits of
+// earlier sessions are loaded from it.
+func NewWorld(chunkSize float32, seed int, store *WorldStore, terrain TerrainConfig) *World {
+	w := &World{
+		Chunks:       NewChunkManager(chunkSize),
+		ViewDistance: DefaultViewDistance,
+		store:        store,
+		rng:          rand.New(rand.NewSource(int64(seed))),
+	}
+	w.Chunks.SetSeed(seed)
+	w.Chunks.SetTerrain(terrain)
+	w.Chunks.SetStore(store)
+	w.current = w.Chunks.GetChunk(rl.Vector2Zero(), rl.White)
+	return w
+}
+
+// NewRemoteWorld creates a world that shows the chunks a server sends
+// instead of generating them.
+func NewRemoteWorld(chunkSize float32, viewDistance int) *World {
+	return &World{
+		Chunks:       NewRemoteChunkManager(chunkSize),
+		ViewDistance: viewDistance,
+		rng:          rand.New(rand.NewSource(1)),
+	}
+}
+
+// OpenWorld loads the world saved in store or creates a new one as
+// described by fresh. Saved worlds keep their seed, chunk size and
+// terrain, otherwise the unmodified chunks would change.
+func OpenWorld(store *WorldStore, fresh WorldMeta) (*World, error) {
+	meta, ok, err := store.LoadMeta()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		meta = fresh
+	}
+	terrain := DefaultTerrain()
+	if meta.Terrain != nil {
+		terrain = *meta.Terrain
+	}
+
+	w := NewWorld(meta.ChunkSize, meta.Seed, store, terrain)
+	w.Ticks = meta.Ticks
+	return w, nil
+}
+
+// Save writes modified chunks and the world metadata to the store.
+func (w *World) Save() error {
+	if w.store == nil {
+		return nil
+	}
+	if err := w.Chunks.Save(); err != nil {
+		return err
+	}
+	terrain := w.Chunks.Terrain()
+	return w.store.SaveMeta(WorldMeta{
+		Seed:      w.Chunks.Seed(),
+		ChunkSize: w.Chunks.Size(),
+		Ticks:     w.Ticks,
+		Terrain:   &terrain,
+	})
+}
+
+// Spawn adds a new player standing on the terrain at the world origin.
+func (w *World) Spawn() *Player {
+	p := NewPlayer(w.spawnPoint())
+	w.Players = append(w.Players, p)
+	return p
+}
+
+// SpawnSaved adds the player saved under name, or a new one if there is
+// no save.
+func (w *World) SpawnSaved(name string) *Player {
+	p := w.Spawn()
+	if w.store == nil {
+		return p
+	}
+	save, ok, err := w.store.LoadPlayer(name)
+	if err != nil {
+		rl.TraceLog(rl.LogWarning, "player %s: %s", name, err.Error())
+	}
+	if ok {
+		p.restore(save)
+	}
+	return p
+}
+
+// SavePlayer stores the player under name.
+func (w *World) SavePlayer(name string, p *Player) error {
+	if w.store == nil {
+		return nil
+	}
+	return w.store.SavePlayer(name, p.save())
+}
+
+// Despawn removes a player from the world.
+func (w *World) Despawn(p *Player) {
+	for i, other := range w.Players {
+		if other == p {
+			w.Players = append(w.Players[:i], w.Players[i+1:]...)
+			return
+		}
+	}
+}
+
+func (w *World) spawnPoint() rl.Vector3 {
+	return rl.NewVector3(0, float32(w.Chunks.SurfaceHeight(0, 0)), 0)
+}
+
+// Tick advances the simulation by dt seconds. Every player is moved with
+// the input it holds, entities are ticked and the chunks around the
+// players are loaded. Now and then the chunks far from every player are
+// saved and unloaded together with their entities.
+func (w *World) Tick(dt float32) {
+	w.Ticks++
+	for _, p := range w.Players {
+		p.prevPosition = p.Position
+		// without the ground loaded the player would fall through it
+		if p.Remote || !w.Chunks.Loaded(p.Position) {
+			continue
+		}
+		w.StepPlayer(p, p.Input, dt)
+	}
+	w.pathBudget = pathNodesPerTick
+	w.collectItems()
+	w.tickEntities(dt)
+
+	for i, p := range w.Players {
+		chunks := w.Chunks.LoadAround(p.Position, w.ViewDistance)
+		if i == 0 {
+			w.visible = chunks
+			if len(chunks) > 0 {
+				w.current = chunks[0]
+			}
+		}
+	}
+	// a remote world only holds what the server sent, it would not send
+	// an unloaded chunk again
+	if !w.Chunks.remote && w.Ticks%unloadInterval == 0 {
+		if err := w.UnloadDistantChunks(); err != nil {
+			rl.TraceLog(rl.LogWarning, "world: %s", err.Error())
+		}
+	}
+}
+
+// StepPlayer moves a single player by one input, players falling out of
+// the world are put back to the spawn point.
+func (w *World) StepPlayer(p *Player, in PlayerInput, dt float32) {
+	p.Step(w.Chunks, in, dt)
+	if p.Position.Y < fallOutOfWorld {
+		p.Position = w.spawnPoint()
+		p.prevPosition = p.Position
+		p.Velocity = rl.Vector3Zero()
+	}
+}
+
+// UnloadDistantChunks drops chunks that are farther than one chunk
+// beyond the view distance of every player, saving them if modified.
+func (w *World) UnloadDistantChunks() error {
+	keep := map[rl.Vector2]bool{}
+	if w.current != nil {
+		keep[w.current.center] = true
+	}
+	for _, p := range w.Players {
+		for _, center := range w.Chunks.centersAround(p.Position, w.ViewDistance+1) {
+			keep[center] = true
+		}
+	}
+	return w.Chunks.UnloadExcept(keep)
+}
+
+// VisibleChunks returns the chunks loaded around the first player.
+func (w *World) VisibleChunks() []*Chunk {
+	if w.visible == nil && w.current != nil {
+		return []*Chunk{w.current}
+	}
+	return w.visible
+}
+
+// CurrentChunk is the chunk the first player stands in, it is nil in a
+// remote world until the first chunk arrived.
+func (w *World) CurrentChunk() *Chunk {
+	return w.current
+}
+
+// DebugLines describes the loaded part of the world for the debug screen.
+func (w *World) DebugLines() []string {
+	pending := 0
+	for _, p := range w.Players {
+		pending += w.Chunks.Pending(p.Position, w.ViewDistance)
+	}
+	return []string{
+		fmt.Sprintf("chunks: %d loaded, %d pending, %d visible", len(w.Chunks.chunkMap), pending, len(w.visible)),
+		fmt.Sprintf("entities: %d, players: %d, tick: %d", len(w.Entities()), len(w.Players), w.Ticks),
+	}
+}
@@ -0,0 +1,119 @@
+package gocraft
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// worldState describes the players and entities of a world.
+func worldState(w *World) string {
+	var lines []string
+	for _, p := range w.Players {
+		lines = append(lines, fmt.Sprintf("player %v %v %v", p.Position, p.Velocity, p.OnGround))
+	}
+	for _, e := range w.Entities() {
+		lines = append(lines, fmt.Sprintf("entity %d %s %v %v %.2f", e.ID, e.Kind, e.Position, e.Velocity, e.Yaw))
+	}
+	sort.Strings(lines)
+	return fmt.Sprint(w.Ticks, lines)
+}
+
+func TestWorldTickIsDeterministic(t *testing.T) {
+	run := func(seed int) string {
+		w := NewWorld(testChunkSize, seed, nil, testTerrain())
+		p := w.Spawn()
+		for i := 0; i < 200; i++ {
+			p.Input = PlayerInput{
+				Forward: 1,
+				Yaw:     float32(i * 3),
+				Jump:    i%40 == 0,
+				Sprint:  i > 100,
+			}
+			w.Tick(testDT)
+		}
+		return worldState(w)
+	}
+
+	first := run(7)
+	if second := run(7); second != first {
+		t.Errorf("two runs of the same seed differ:\n%s\n%s", first, second)
+	}
+	if other := run(8); other == first {
+		t.Error("another seed gave the same world")
+	}
+}
+
+func TestWorldTickMovesPlayers(t *testing.T) {
+	w := NewWorld(testChunkSize, 7, nil, testTerrain())
+	p := w.Spawn()
+	start := p.Position
+	p.Input = PlayerInput{Forward: 1}
+	for i := 0; i < 20; i++ {
+		w.Tick(testDT)
+	}
+	if w.Ticks != 20 {
+		t.Errorf("%d ticks counted, want 20", w.Ticks)
+	}
+	if p.Position.X <= start.X {
+		t.Errorf("player did not walk forward from %v to %v", start, p.Position)
+	}
+	// interpolation runs from the previous tick to the current one
+	if eye := p.InterpolatedEye(1); eye != p.EyePosition() {
+		t.Errorf("interpolated eye at alpha 1 is %v, want %v", eye, p.EyePosition())
+	}
+}
+
+func TestWorldTickUnloadsDistantChunks(t *testing.T) {
+	for _, stored := range []bool{true, false} {
+		t.Run(fmt.Sprintf("stored %v", stored), func(t *testing.T) {
+			var store *WorldStore
+			if stored {
+				var err error
+				if store, err = NewWorldStore(t.TempDir()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			w := NewWorld(testChunkSize, 7, store, testTerrain())
+			p := w.Spawn()
+			w.Tick(testDT)
+			origin := w.CurrentChunk()
+			if len(origin.entities) == 0 {
+				t.Fatal("the chunk has no mobs")
+			}
+			if err := w.Chunks.SetBlock(0, 1, 0, BlockTypeRock); err != nil {
+				t.Fatal(err)
+			}
+
+			// the player walks away, its old chunk is far behind
+			p.Position.X = 10 * testChunkSize
+			for i := 0; i < unloadInterval; i++ {
+				w.Tick(testDT)
+			}
+			_, loaded := w.Chunks.chunkMap[origin.center]
+			if !stored {
+				if !loaded {
+					t.Error("the edited chunk was dropped without a store to save it")
+				}
+				return
+			}
+			if loaded {
+				t.Fatal("the chunk left behind is still loaded")
+			}
+			for _, e := range w.Entities() {
+				for _, old := range origin.entities {
+					if e == old {
+						t.Errorf("entity %d of the unloaded chunk is still ticked", e.ID)
+					}
+				}
+			}
+			save, err := store.LoadChunk(origin.center)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if save == nil || len(save.edits) != 1 {
+				t.Errorf("saved chunk %+v, want the edit", save)
+			}
+		})
+	}
+}
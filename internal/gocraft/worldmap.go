@@ -0,0 +1,242 @@
+package gocraft
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	mapWaterShallow = rl.NewColor(63, 118, 228, 255)
+	mapWaterDeep    = rl.NewColor(18, 38, 104, 255)
+	// mapWaterDepth is the depth at which the water is darkest.
+	mapWaterDepth float32 = 16
+)
+
+// MapRenderer draws the world from above with one pixel per column, in
+// the color of the highest solid block. Slopes facing north are lit and
+// the ones facing south are shaded, higher ground is brighter. Map images
+// have x to the right and z down, so north (-z) is up.
+type MapRenderer struct {
+	// WaterLevel floods the columns below it. The world has no water
+	// blocks, 0 turns it off.
+	WaterLevel int
+
+	chunks *ChunkManager
+	tiles  map[rl.Vector2]*mapTile
+}
+
+// mapTile is the image of one chunk, it is redrawn when the chunk is
+// replaced or changed.
+type mapTile struct {
+	chunk    *Chunk
+	revision int
+	image    *image.RGBA
+}
+
+func NewMapRenderer(chunks *ChunkManager) *MapRenderer {
+	return &MapRenderer{chunks: chunks, tiles: make(map[rl.Vector2]*mapTile)}
+}
+
+// ChunkImage returns the map of a loaded chunk.
+func (m *MapRenderer) ChunkImage(chunk *Chunk) *image.RGBA {
+	tile, ok := m.tiles[chunk.center]
+	if !ok || tile.chunk != chunk || tile.revision != chunk.revision {
+		tile = &mapTile{chunk: chunk, revision: chunk.revision, image: m.drawChunk(chunk)}
+		m.tiles[chunk.center] = tile
+	}
+	return tile.image
+}
+
+// ColumnColor is the map color at world x, z, ok is false if its chunk
+// is not loaded.
+func (m *MapRenderer) ColumnColor(x, z int) (c color.RGBA, ok bool) {
+	chunk, lx, _, lz, err := m.chunks.localBlock(x, 0, z)
+	if err != nil {
+		return c, false
+	}
+	return m.ChunkImage(chunk).RGBAAt(lx, lz), true
+}
+
+// Prune forgets the images of chunks that are no longer loaded.
+func (m *MapRenderer) Prune() {
+	for center, tile := range m.tiles {
+		if m.chunks.chunkMap[center] != tile.chunk {
+			delete(m.tiles, center)
+		}
+	}
+}
+
+// RenderArea draws the columns of area, which is in world x and z.
+// Chunks that are not loaded are generated for it and dropped again, so
+// large areas fit into memory. Remote chunks that did not arrive stay
+// transparent.
+func (m *MapRenderer) RenderArea(area image.Rectangle) *image.RGBA {
+	var (
+		img     = image.NewRGBA(image.Rect(0, 0, area.Dx(), area.Dy()))
+		size    = int(m.chunks.Size())
+		first   = m.chunks.chunkCenter(area.Min.X, area.Min.Y)
+		last    = m.chunks.chunkCenter(area.Max.X-1, area.Max.Y-1)
+		centers []rl.Vector2
+	)
+	// centers are negated, so the last chunk has the lowest one
+	for cx := first.X; cx >= last.X; cx -= float32(size) {
+		for cz := first.Y; cz >= last.Y; cz -= float32(size) {
+			centers = append(centers, rl.NewVector2(cx, cz))
+		}
+	}
+	for _, center := range centers {
+		_, loaded := m.chunks.chunkMap[center]
+		chunk := m.chunks.GetChunk(center, rl.White)
+		if chunk == nil {
+			continue
+		}
+		tile := m.ChunkImage(chunk)
+		// the world position of the first column of the chunk
+		x0, z0 := -int(center.X)-size/2, -int(center.Y)-size/2
+		for lz := 0; lz < size; lz++ {
+			for lx := 0; lx < size; lx++ {
+				p := image.Pt(x0+lx, z0+lz)
+				if p.In(area) {
+					img.SetRGBA(p.X-area.Min.X, p.Y-area.Min.Y, tile.RGBAAt(lx, lz))
+				}
+			}
+		}
+		if !loaded {
+			delete(m.chunks.chunkMap, center)
+			delete(m.tiles, center)
+		}
+	}
+	return img
+}
+
+// drawChunk draws the map of a chunk. The slope is taken from the column
+// to the north inside of the chunk, so tiles do not depend on their
+// neighbours.
+func (m *MapRenderer) drawChunk(chunk *Chunk) *image.RGBA {
+	var (
+		width  = len(chunk.blockList)
+		length = len(chunk.blockList[0][0])
+		img    = image.NewRGBA(image.Rect(0, 0, width, length))
+		tops   = make([]int, width)
+	)
+	for lz := 0; lz < length; lz++ {
+		for lx := 0; lx < width; lx++ {
+			y, typ := chunk.top(lx, lz)
+			north := tops[lx]
+			if lz == 0 {
+				north = y
+			}
+			tops[lx] = y
+			img.SetRGBA(lx, lz, m.columnColor(typ, y, north, len(chunk.blockList[0])))
+		}
+	}
+	return img
+}
+
+// columnColor is the map color of a column whose highest solid block of
+// typ is at y, north is the height of the column to the north.
+func (m *MapRenderer) columnColor(typ BlockType, y, north, height int) color.RGBA {
+	if y < 0 {
+		return color.RGBA{}
+	}
+	c, ok := blockColors[typ]
+	if !ok {
+		c = rl.Magenta
+	}
+	// a step up towards the south is lit like a slope facing the sun
+	light := 1 + clamp32(float32(y-north)*0.12, -0.3, 0.3)
+	light *= 0.75 + 0.4*float32(y)/float32(height)
+	c = rl.NewColor(
+		uint8(clamp32(float32(c.R)*light, 0, 255)),
+		uint8(clamp32(float32(c.G)*light, 0, 255)),
+		uint8(clamp32(float32(c.B)*light, 0, 255)),
+		255,
+	)
+
+	if depth := m.WaterLevel - y - 1; depth > 0 {
+		water := lerpColor(mapWaterShallow, mapWaterDeep, clamp32(float32(depth)/mapWaterDepth, 0, 1))
+		// the ground shines through shallow water
+		c = lerpColor(c, water, clamp32(0.5+float32(depth)*0.15, 0, 1))
+	}
+	return c
+}
+
+// top returns the height and type of the highest solid block in the
+// local column, y is -1 if there is none.
+func (c *Chunk) top(x, z int) (int, BlockType) {
+	column := c.blockList[x]
+	for y := len(column) - 1; y >= 0; y-- {
+		if b := column[y][z]; b != nil && !b.carved {
+			return y, b.blockType
+		}
+	}
+	return -1, 0
+}
+
+// mapArea is the square of blocks with the given size around x, z.
+func mapArea(x, z, size int) image.Rectangle {
+	half := size / 2
+	return image.Rect(x-half, z-half, x-half+size, z-half+size)
+}
+
+// openWorldForExport opens the saved world given with --world, or a new
+// world of the seed of config. Saved worlds are only read, the store is
+// not created.
+func openWorldForExport(ctx *cli.Context, config Config) (*World, error) {
+	dir := ctx.String("world")
+	if dir == "" {
+		return NewWorld(config.ChunkSize, config.Seed, nil, config.Terrain), nil
+	}
+	store := &WorldStore{dir: dir}
+	if _, ok, err := store.LoadMeta(); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, fmt.Errorf("%s: no saved world", dir)
+	}
+	return OpenWorld(store, WorldMeta{})
+}
+
+// RunMap is the action of the map command. It draws the map of an area
+// of a saved world, or of a new world of the seed, into a png without a
+// window.
+func RunMap(ctx *cli.Context) error {
+	config, _, err := ResolveConfig(ctx)
+	if err != nil {
+		return err
+	}
+	size := ctx.Int("size")
+	if size < 1 {
+		return fmt.Errorf("map size %d has to be positive", size)
+	}
+
+	// the block colors can come from resource packs
+	resources, err := NewResources(config.ResourcePacks)
+	if err != nil {
+		return err
+	}
+	defer resources.Close()
+	if err := loadBlockColors(resources); err != nil {
+		return err
+	}
+	world, err := openWorldForExport(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	m := NewMapRenderer(world.Chunks)
+	m.WaterLevel = ctx.Int("water-level")
+	area := mapArea(ctx.Int("x"), ctx.Int("z"), size)
+	img := m.RenderArea(area)
+
+	out := ctx.String("out")
+	if err := writePNG(out, img); err != nil {
+		return err
+	}
+	fmt.Fprintf(ctx.App.Writer, "wrote %s (seed %d, x %d..%d, z %d..%d)\n",
+		out, world.Chunks.Seed(), area.Min.X, area.Max.X-1, area.Min.Y, area.Max.Y-1)
+	return nil
+}
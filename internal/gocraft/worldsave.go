@@ -0,0 +1,297 @@
+package gocraft
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+var (
+	ErrInvalidChunkFile = errors.New("invalid chunk file")
+)
+
+const (
+	chunkFileMagic   = "GCCH"
+	chunkFileVersion = 1
+	worldMetaFile    = "world.json"
+)
+
+// blockEdit is a change of a single block after generation.
+type blockEdit struct {
+	Type    BlockType
+	Removed bool
+}
+
+// WorldMeta is stored next to the chunks and describes how to
+// regenerate the unmodified terrain.
+type WorldMeta struct {
+	Seed      int     `json:"seed"`
+	ChunkSize float32 `json:"chunk_size"`
+	Ticks     uint64  `json:"ticks"`
+	// Terrain is missing in worlds saved before it was configurable,
+	// they use the default terrain.
+	Terrain *TerrainConfig `json:"terrain,omitempty"`
+}
+
+// WorldStore saves chunk edits and world metadata in a directory.
+// Chunks are regenerated from the seed, only their edits and entities
+// are stored.
+type WorldStore struct {
+	dir string
+}
+
+func NewWorldStore(dir string) (*WorldStore, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "chunks"), 0o755); err != nil {
+		return nil, err
+	}
+	return &WorldStore{dir: dir}, nil
+}
+
+func (s *WorldStore) Dir() string {
+	return s.dir
+}
+
+// LoadMeta reads the world metadata, ok is false for a new world.
+func (s *WorldStore) LoadMeta() (meta WorldMeta, ok bool, err error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, worldMetaFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return meta, false, nil
+	}
+	if err != nil {
+		return meta, false, err
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, false, fmt.Errorf("%s: %w", worldMetaFile, err)
+	}
+	return meta, true, nil
+}
+
+func (s *WorldStore) SaveMeta(meta WorldMeta) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(s.dir, worldMetaFile), data)
+}
+
+func (s *WorldStore) chunkPath(center rl.Vector2) string {
+	return filepath.Join(s.dir, "chunks", fmt.Sprintf("c.%d.%d.bin", int(center.X), int(center.Y)))
+}
+
+// chunkSave is what is stored of a chunk.
+type chunkSave struct {
+	edits    map[[3]int]blockEdit
+	entities []*Entity
+}
+
+// LoadChunk reads the edits and entities of a chunk. It returns nil for
+// chunks that were never saved.
+func (s *WorldStore) LoadChunk(center rl.Vector2) (*chunkSave, error) {
+	f, err := os.Open(s.chunkPath(center))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var header struct {
+		Magic   [4]byte
+		Version uint16
+		Count   uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidChunkFile, err)
+	}
+	if string(header.Magic[:]) != chunkFileMagic || header.Version != chunkFileVersion {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidChunkFile, s.chunkPath(center))
+	}
+
+	save := &chunkSave{edits: make(map[[3]int]blockEdit, header.Count)}
+	for i := uint32(0); i < header.Count; i++ {
+		var e struct {
+			X, Y, Z uint16
+			Type    int16
+			Removed bool
+		}
+		if err := binary.Read(r, binary.LittleEndian, &e); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidChunkFile, err)
+		}
+		save.edits[[3]int{int(e.X), int(e.Y), int(e.Z)}] = blockEdit{
+			Type:    BlockType(e.Type),
+			Removed: e.Removed,
+		}
+	}
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidChunkFile, err)
+	}
+	for i := uint32(0); i < count; i++ {
+		var e struct {
+			Kind     uint8
+			Position [3]float32
+			Yaw      float32
+			Item     int16
+			Count    uint16
+			Age      float32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &e); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidChunkFile, err)
+		}
+		entity := NewEntity(EntityKind(e.Kind), rl.NewVector3(e.Position[0], e.Position[1], e.Position[2]))
+		entity.Yaw = e.Yaw
+		entity.Item = BlockType(e.Item)
+		entity.Count = int(e.Count)
+		entity.Age = e.Age
+		save.entities = append(save.entities, entity)
+	}
+	return save, nil
+}
+
+func (s *WorldStore) SaveChunk(center rl.Vector2, edits map[[3]int]blockEdit, entities []*Entity) error {
+	var buf []byte
+	buf = append(buf, chunkFileMagic...)
+	buf = binary.LittleEndian.AppendUint16(buf, chunkFileVersion)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(edits)))
+	for pos, e := range edits {
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(pos[0]))
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(pos[1]))
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(pos[2]))
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(e.Type))
+		if e.Removed {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+	}
+
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(entities)))
+	for _, e := range entities {
+		buf = append(buf, uint8(e.Kind))
+		for _, v := range []float32{e.Position.X, e.Position.Y, e.Position.Z, e.Yaw} {
+			buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(v))
+		}
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(e.Item))
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(e.Count))
+		buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(e.Age))
+	}
+	return writeFileAtomic(s.chunkPath(center), buf)
+}
+
+// PlayerSave is the state of a player kept between sessions.
+type PlayerSave struct {
+	Position  [3]float32  `json:"position"`
+	Yaw       float32     `json:"yaw"`
+	Mode      string      `json:"mode"`
+	GameMode  string      `json:"game_mode"`
+	Selected  int         `json:"selected"`
+	Inventory []ItemStack `json:"inventory"`
+}
+
+func (s *WorldStore) playerPath(name string) string {
+	return filepath.Join(s.dir, "players", safeFileName(name)+".json")
+}
+
+// safeFileName keeps player and world names from escaping the directory.
+func safeFileName(name string) string {
+	clean := []rune(name)
+	for i, r := range clean {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			clean[i] = '_'
+		}
+	}
+	if len(clean) == 0 {
+		return "player"
+	}
+	return string(clean)
+}
+
+// LoadPlayer reads a saved player, ok is false if it was never saved.
+func (s *WorldStore) LoadPlayer(name string) (save PlayerSave, ok bool, err error) {
+	data, err := os.ReadFile(s.playerPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return save, false, nil
+	}
+	if err != nil {
+		return save, false, err
+	}
+	if err := json.Unmarshal(data, &save); err != nil {
+		return save, false, fmt.Errorf("%s: %w", s.playerPath(name), err)
+	}
+	return save, true, nil
+}
+
+func (s *WorldStore) SavePlayer(name string, save PlayerSave) error {
+	if err := os.MkdirAll(filepath.Join(s.dir, "players"), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(save, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.playerPath(name), data)
+}
+
+// WorldInfo describes a saved world for the world selection.
+type WorldInfo struct {
+	Name string
+	Dir  string
+	Meta WorldMeta
+}
+
+// SavesDir is where the worlds of single player games are stored.
+func SavesDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gocraft", "saves"), nil
+}
+
+// ListWorlds returns the worlds saved in dir sorted by name. A missing
+// dir has no worlds.
+func ListWorlds(dir string) ([]WorldInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var worlds []WorldInfo
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		store := &WorldStore{dir: filepath.Join(dir, e.Name())}
+		meta, ok, err := store.LoadMeta()
+		if err != nil || !ok {
+			continue
+		}
+		worlds = append(worlds, WorldInfo{Name: e.Name(), Dir: store.dir, Meta: meta})
+	}
+	// ReadDir already sorts by file name
+	return worlds, nil
+}
+
+// writeFileAtomic writes to a temporary file first, so a crash during
+// an autosave does not leave a truncated file behind.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
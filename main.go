@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/tinogoehlert/gocraft/internal/gocraft"
@@ -8,7 +9,10 @@ import (
 )
 
 func main() {
-	(&cli.App{
+	// the flag defaults are only shown in the help, flags that are not
+	// given do not override the config file
+	defaults := gocraft.DefaultConfig()
+	err := (&cli.App{
 		Name:        "GoCraft",
 		Description: "a minecraft clone written in go ^^",
 		Authors: []*cli.Author{{
@@ -18,34 +22,249 @@ func main() {
 		Flags: []cli.Flag{
 			&cli.IntFlag{
 				Name:    "width",
-				Value:   800,
+				Value:   defaults.Width,
 				Aliases: []string{"sw"},
 			},
 			&cli.IntFlag{
 				Name:    "height",
-				Value:   450,
+				Value:   defaults.Height,
 				Aliases: []string{"sh"},
 			},
 			&cli.StringFlag{
 				Name:    "title",
-				Value:   "GoCraft",
+				Value:   defaults.Title,
 				Aliases: []string{"t"},
 			},
 			&cli.StringFlag{
 				Name:    "loglevel",
-				Value:   "info",
+				Value:   defaults.LogLevel,
 				Aliases: []string{"v"},
 			},
 			&cli.IntFlag{
 				Name:  "fps",
-				Value: 60,
+				Usage: "frame rate cap, 0 is unlimited",
+				Value: defaults.Settings.MaxFPS,
+			},
+			&cli.IntFlag{
+				Name:  "seed",
+				Value: defaults.Seed,
+			},
+			&cli.IntFlag{
+				Name:  "tps",
+				Usage: "simulation ticks per second",
+				Value: defaults.TickRate,
+			},
+			&cli.Float64Flag{
+				Name:  "fov",
+				Value: float64(defaults.Settings.FOV),
+			},
+			&cli.Float64Flag{
+				Name:  "sensitivity",
+				Value: float64(defaults.Settings.Sensitivity),
+			},
+			&cli.StringSliceFlag{
+				Name:  "resource-pack",
+				Usage: "directory or zip file overriding the embedded resources, the first one wins",
+			},
+			&cli.StringFlag{
+				Name:  "config-file",
+				Usage: "config file to use instead of the one in the user config dir",
 			},
 		},
 		Commands: []*cli.Command{
 			{
 				Name:   "start",
 				Action: gocraft.RunEngine,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "connect",
+						Usage: "address of a server to join, e.g. localhost:25565",
+					},
+					&cli.StringFlag{
+						Name:  "name",
+						Usage: "player name shown to others on a server, letters, digits, - and _",
+						Value: "player",
+					},
+				},
+			},
+			{
+				Name:   "config",
+				Usage:  "print the effective configuration",
+				Action: gocraft.RunConfig,
+			},
+			{
+				Name:   "render",
+				Usage:  "render a view of the seed into a png without a window",
+				Action: gocraft.RunRender,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "out",
+						Value:   "render.png",
+						Aliases: []string{"o"},
+					},
+					&cli.Float64Flag{Name: "x"},
+					&cli.Float64Flag{
+						Name:  "y",
+						Usage: "eye height, by default a bit above the surface",
+					},
+					&cli.Float64Flag{Name: "z"},
+					&cli.Float64Flag{
+						Name:  "yaw",
+						Usage: "heading in degrees, 0 looks along +x",
+					},
+					&cli.Float64Flag{
+						Name:  "pitch",
+						Value: -20,
+					},
+					&cli.Float64Flag{
+						Name:  "time",
+						Usage: "time of day in days, 0 is midnight and 0.5 noon",
+						Value: 0.5,
+					},
+					&cli.IntFlag{
+						Name:  "view-distance",
+						Usage: "chunks loaded around the position",
+						Value: 1,
+					},
+					&cli.StringFlag{
+						Name:  "compare",
+						Usage: "reference png to compare with instead of writing the image",
+					},
+					&cli.UintFlag{
+						Name:  "threshold",
+						Usage: "channel difference a pixel may have and still match",
+						Value: 8,
+					},
+					&cli.Float64Flag{
+						Name:  "tolerance",
+						Usage: "fraction of pixels that may differ",
+						Value: 0.001,
+					},
+					&cli.StringFlag{
+						Name:  "diff",
+						Usage: "where to write the difference image when the comparison fails",
+					},
+				},
+			},
+			{
+				Name:   "map",
+				Usage:  "draw a map of a world or seed into a png without a window",
+				Action: gocraft.RunMap,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "out",
+						Value:   "map.png",
+						Aliases: []string{"o"},
+					},
+					&cli.StringFlag{
+						Name:  "world",
+						Usage: "directory of a saved world, by default a new world of the seed is drawn",
+					},
+					&cli.IntFlag{
+						Name:  "x",
+						Usage: "center of the map",
+					},
+					&cli.IntFlag{
+						Name:  "z",
+						Usage: "center of the map",
+					},
+					&cli.IntFlag{
+						Name:  "size",
+						Usage: "width and height of the map in blocks",
+						Value: 512,
+					},
+					&cli.IntFlag{
+						Name:  "water-level",
+						Usage: "columns below it are drawn as water, 0 is off",
+					},
+				},
+			},
+			{
+				Name:   "preview",
+				Usage:  "write height, biome and cave maps and histograms of the terrain noise to png and csv",
+				Action: gocraft.RunPreview,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "out",
+						Usage:   "directory the files are written to",
+						Value:   "preview",
+						Aliases: []string{"o"},
+					},
+					&cli.StringFlag{
+						Name:  "terrain",
+						Usage: "json file with terrain settings replacing the ones of the config",
+					},
+					&cli.StringSliceFlag{
+						Name:  "set",
+						Usage: "terrain setting as key=value, e.g. frequency=0.02",
+					},
+					&cli.IntFlag{
+						Name:  "x",
+						Usage: "center of the area",
+					},
+					&cli.IntFlag{
+						Name:  "z",
+						Usage: "center of the area",
+					},
+					&cli.IntFlag{
+						Name:  "size",
+						Usage: "width and length of the area in blocks",
+						Value: 512,
+					},
+					&cli.IntFlag{
+						Name:  "scale",
+						Usage: "blocks per pixel",
+						Value: 1,
+					},
+					&cli.IntSliceFlag{
+						Name:  "cave-y",
+						Usage: "heights to cut the caves at",
+					},
+				},
+			},
+			{
+				Name:   "export",
+				Usage:  "export a box of a world or seed as obj with mtl and textures, or as glb",
+				Action: gocraft.RunExport,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "out",
+						Usage:   "file to write, .obj or .glb",
+						Value:   "region.obj",
+						Aliases: []string{"o"},
+					},
+					&cli.StringFlag{
+						Name:  "world",
+						Usage: "directory of a saved world, by default a new world of the seed is exported",
+					},
+					&cli.StringFlag{
+						Name:  "from",
+						Usage: "corner of the box as x,y,z",
+						Value: "-16,0,-16",
+					},
+					&cli.StringFlag{
+						Name:  "to",
+						Usage: "opposite corner of the box as x,y,z, not part of it",
+						Value: "16,64,16",
+					},
+				},
+			},
+			{
+				Name:   "server",
+				Usage:  "run a headless dedicated server",
+				Action: gocraft.RunServer,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "config",
+						Value:   "server.json",
+						Aliases: []string{"c"},
+					},
+				},
 			},
 		},
 	}).Run(os.Args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
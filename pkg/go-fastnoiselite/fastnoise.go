@@ -61,6 +61,9 @@ const (
 	FNL_DOMAIN_WARP_BASICGRID            FNL_DOMAIN_WARP = 2
 )
 
+// DefaultSeed is the seed of a state created by NewDefaultNoise.
+const DefaultSeed = 1337
+
 type NoiseState struct {
 	cstate C.struct_fnl_state
 	warpX  C.float
@@ -0,0 +1,11 @@
+// Package res holds the default resources of the game, resource packs
+// are layered on top of them.
+package res
+
+import "embed"
+
+// FS contains the files the game loads, paths are relative to res, like
+// textures/dirt.png.
+//
+//go:embed blocks.json models recipes shaders textures
+var FS embed.FS
@@ -0,0 +1,14 @@
+// Fog, it is off when fogEnd is 0 and linear when fogDensity is 0
+uniform vec4 fogColor;
+uniform float fogStart;
+uniform float fogEnd;
+uniform float fogDensity;
+
+vec4 applyFog(vec4 color, float dist)
+{
+    if (fogEnd <= 0.0) return color;
+
+    float fog = clamp((fogEnd - dist)/(fogEnd - fogStart), 0.0, 1.0);
+    if (fogDensity > 0.0) fog = exp(-pow(max(dist - fogStart, 0.0)*fogDensity, 2.0));
+    return vec4(mix(fogColor.rgb, color.rgb, fog), color.a);
+}
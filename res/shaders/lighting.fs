@@ -38,6 +38,8 @@ uniform Light lights[MAX_LIGHTS];
 uniform vec4 ambient;
 uniform vec3 viewPos;
 
+#include "fog.glsl"
+
 void main()
 {
     // Texel color fetching from texture sampler
@@ -79,4 +81,7 @@ void main()
 
     // Gamma correction
     finalColor = pow(finalColor, vec4(1.0/2.2));
+
+    // Fog towards the sky color, which is already gamma corrected
+    finalColor = applyFog(finalColor, length(viewPos - fragPosition));
 }
\ No newline at end of file